@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/format"
+	"log"
+	"os"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The enumgen app writes the methods of the enum types and flag sets of the message models, which are implemented with
+// the functions of pkg/message. It is run by go generate in the package of the types, e.g.
+//
+//	//go:generate go run ../../../../cmd/enumgen -output enum_gen.go Scope Status
+//	//go:generate go run ../../../../cmd/enumgen -flags -output flags_gen.go ContactTypes
+//
+// The names of the values of each type are declared in the package in a map named after the type, e.g. scopeNames.
+func main() {
+	flags := flag.Bool("flags", false, "whether the types are flag sets")
+	output := flag.String("output", "", "file of the generated methods")
+
+	flag.Parse()
+
+	packageName := os.Getenv("GOPACKAGE")
+
+	if packageName == "" || *output == "" || flag.NArg() == 0 {
+		log.Fatal("usage: go generate with enumgen [-flags] -output <file> <type>...")
+	}
+
+	types := make([]*enumType, 0, flag.NArg())
+
+	for _, name := range flag.Args() {
+		types = append(types, newEnumType(name))
+	}
+
+	fileTemplate := enumTemplate
+
+	if *flags {
+		fileTemplate = flagsTemplate
+	}
+
+	buffer := &bytes.Buffer{}
+
+	err := fileTemplate.Execute(buffer, map[string]any{
+		"Package": packageName,
+		"Types":   types,
+	})
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	source, err := format.Source(buffer.Bytes())
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(*output, source, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+type enumType struct {
+	Name     string
+	Receiver string
+	Names    string
+}
+
+func newEnumType(name string) *enumType {
+	r, size := utf8.DecodeRuneInString(name)
+
+	receiver := string(unicode.ToLower(r)) + name[size:]
+
+	return &enumType{
+		Name:     name,
+		Receiver: receiver,
+		Names:    receiver + "Names",
+	}
+}
+
+const header = `// Code generated by enumgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"encoding/json"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/message"
+)
+`
+
+var enumTemplate = template.Must(template.New("enum").Parse(header + `
+{{range .Types}}
+func ({{.Receiver}} {{.Name}}) String() string {
+	return message.EnumString({{.Receiver}}, {{.Names}})
+}
+
+func ({{.Receiver}} {{.Name}}) Valid() bool {
+	_, ok := {{.Names}}[{{.Receiver}}]
+
+	return ok
+}
+
+func ({{.Receiver}} {{.Name}}) EnumValues() []any {
+	return message.EnumValues({{.Names}})
+}
+
+func ({{.Receiver}} {{.Name}}) EnumNames() []string {
+	return message.EnumNames({{.Names}})
+}
+
+func ({{.Receiver}} {{.Name}}) MarshalText() ([]byte, error) {
+	return []byte({{.Receiver}}.String()), nil
+}
+
+func ({{.Receiver}} *{{.Name}}) UnmarshalText(data []byte) error {
+	value, err := message.ParseEnum(string(data), {{.Names}})
+
+	if err != nil {
+		return err
+	}
+
+	*{{.Receiver}} = value
+
+	return nil
+}
+
+func ({{.Receiver}} {{.Name}}) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int({{.Receiver}}))
+}
+
+func ({{.Receiver}} *{{.Name}}) UnmarshalJSON(data []byte) error {
+	value, err := message.UnmarshalEnumJSON(data, {{.Names}})
+
+	if err != nil {
+		return err
+	}
+
+	*{{.Receiver}} = value
+
+	return nil
+}
+{{end}}`))
+
+var flagsTemplate = template.Must(template.New("flags").Parse(header + `
+{{range .Types}}
+// Has reports whether all the given flags are set.
+func ({{.Receiver}} {{.Name}}) Has(flags {{.Name}}) bool {
+	return {{.Receiver}}&flags == flags
+}
+
+func ({{.Receiver}} *{{.Name}}) Set(flags {{.Name}}) {
+	*{{.Receiver}} |= flags
+}
+
+func ({{.Receiver}} *{{.Name}}) Clear(flags {{.Name}}) {
+	*{{.Receiver}} &^= flags
+}
+
+func ({{.Receiver}} {{.Name}}) List() []{{.Name}} {
+	return message.ListFlags({{.Receiver}})
+}
+
+func ({{.Receiver}} {{.Name}}) String() string {
+	return message.FlagsString({{.Receiver}}, {{.Names}})
+}
+
+func ({{.Receiver}} {{.Name}}) Valid() bool {
+	return message.ValidFlags({{.Receiver}}, {{.Names}})
+}
+
+func ({{.Receiver}} {{.Name}}) EnumValues() []any {
+	return message.FlagsValues({{.Names}})
+}
+
+func ({{.Receiver}} {{.Name}}) FlagNames() []string {
+	return message.FlagNames({{.Names}})
+}
+
+func ({{.Receiver}} {{.Name}}) MarshalText() ([]byte, error) {
+	return []byte({{.Receiver}}.String()), nil
+}
+
+func ({{.Receiver}} *{{.Name}}) UnmarshalText(data []byte) error {
+	value, err := message.ParseFlags(string(data), {{.Names}})
+
+	if err != nil {
+		return err
+	}
+
+	*{{.Receiver}} = value
+
+	return nil
+}
+
+func ({{.Receiver}} {{.Name}}) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int({{.Receiver}}))
+}
+
+func ({{.Receiver}} *{{.Name}}) UnmarshalJSON(data []byte) error {
+	value, err := message.UnmarshalFlagsJSON(data, {{.Names}})
+
+	if err != nil {
+		return err
+	}
+
+	*{{.Receiver}} = value
+
+	return nil
+}
+{{end}}`))
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"log/slog"
 	"os"
@@ -10,11 +11,17 @@ import (
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus/admin"
 	"github.com/scaleforce/synchronization-for-go/internal/azure/servicebus/util"
 	envelopemessage "github.com/scaleforce/synchronization-for-go/internal/message/envelope"
 	"github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus"
+	asyncservicebus "github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus/async"
+	"github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus/claimcheck"
+	sessionservicebus "github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus/session"
 	"github.com/scaleforce/synchronization-for-go/pkg/message/event"
 	"github.com/scaleforce/synchronization-for-go/pkg/message/event/xnms"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+	"github.com/scaleforce/synchronization-for-go/pkg/spool"
 	"github.com/spf13/viper"
 )
 
@@ -83,29 +90,185 @@ func main() {
 
 	defer sender.Close(ctx)
 
-	publisher := servicebus.NewPublisher(sender, util.NewMarshalEnvelopeFunc(util.NewMarshalMessageFunc()), logger, nil)
+	marshalMessageFunc := servicebus.NewMarshalApplicationPropertiesFunc(util.NewMarshalEnvelopeFunc(util.NewMarshalMessageFunc()), util.GetApplicationProperties)
+
+	if viper.GetBool("AZURE_SERVICEBUS_SESSIONS") {
+		marshalMessageFunc = sessionservicebus.NewMarshalMessageFunc(marshalMessageFunc, util.GetSessionID)
+	}
+
+	var getMessageIDFunc servicebus.GetMessageIDFunc
+
+	switch viper.GetString("AZURE_SERVICEBUS_MESSAGE_ID") {
+	case "hash":
+		getMessageIDFunc = servicebus.NewHashMessageIDFunc(util.GetMessageKey)
+	case "uuid":
+		getMessageIDFunc = servicebus.NewUUIDMessageIDFunc(util.MessageIDNamespace, util.GetMessageKey)
+	}
+
+	if getMessageIDFunc != nil {
+		marshalMessageFunc = servicebus.NewMarshalMessageIDFunc(marshalMessageFunc, getMessageIDFunc)
+
+		adminClient, err := admin.NewClientFromConnectionString(viper.GetString("AZURE_SERVICEBUS_CONNECTION_STRING"), nil)
+
+		if err != nil {
+			log.Panic(err)
+		}
+
+		// The check is only a warning, the publisher app can still publish without the Manage claim.
+		if _, err := servicebus.CheckDuplicateDetection(ctx, adminClient, viper.GetString("AZURE_SERVICEBUS_TOPIC"), logger); err != nil {
+			logger.Warn("duplicate detection of the topic was not checked", "error", err)
+		}
+	}
+
+	// The claim check is applied last, because it replaces the body of the messages.
+	if claimCheckDirectory := viper.GetString("CLAIM_CHECK_DIRECTORY"); claimCheckDirectory != "" {
+		marshalOptions := &claimcheck.MarshalOptions{
+			Threshold: viper.GetInt("CLAIM_CHECK_THRESHOLD"),
+		}
+
+		marshalMessageFunc = claimcheck.NewMarshalMessageFunc(marshalMessageFunc, claimcheck.NewFileBlobStore(claimCheckDirectory), marshalOptions)
+	}
+
+	publisherOptions := &servicebus.PublisherOptions{
+		BatchMaxBytes:       viper.GetUint64("AZURE_SERVICEBUS_BATCH_MAX_BYTES"),
+		GetPartitionKeyFunc: util.GetPartitionKey,
+	}
+
+	publisher := servicebus.NewPublisher(servicebus.NewSender(sender), marshalMessageFunc, logger, publisherOptions)
+
+	publishFunc := pubsub.PublishFunc(publisher.Publish)
+
+	if spoolDirectory := viper.GetString("SPOOL_DIRECTORY"); spoolDirectory != "" {
+		messageSpool, err := newSpool(spoolDirectory, publisher)
+
+		if err != nil {
+			log.Panic(err)
+		}
+
+		go func() {
+			if err := messageSpool.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				logger.Error("spool was stopped", "error", err)
+			}
+		}()
+
+		defer func() {
+			metrics := messageSpool.Metrics()
+
+			logger.Info("spool metrics", "published", metrics.Published, "retried", metrics.Retried, "spooled", metrics.Spooled, "replayed", metrics.Replayed,
+				"rejected", metrics.Rejected, "failed", metrics.Failed, "pendingMessages", metrics.PendingMessages, "pendingBytes", metrics.PendingBytes)
+		}()
+
+		publishFunc = messageSpool.Publish
+	} else if linger := viper.GetDuration("AZURE_SERVICEBUS_LINGER"); linger > 0 {
+		asyncPublisherOptions := &asyncservicebus.PublisherOptions{
+			Linger: linger,
+		}
+
+		asyncPublisher := asyncservicebus.NewPublisher(publisher, logger, asyncPublisherOptions)
+
+		// The buffered messages are published on close, so the async publisher must keep running after the signal.
+		go asyncPublisher.Run(context.WithoutCancel(ctx))
+
+		defer func() {
+			closeCtx, cancelCloseCtx := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+
+			defer cancelCloseCtx()
+
+			if err := asyncPublisher.Close(closeCtx); err != nil {
+				logger.Error("async publisher was not closed", "error", err)
+			}
+		}()
+
+		publishFunc = func(ctx context.Context, message pubsub.Message) error {
+			return asyncPublisher.PublishCallback(ctx, message, func(message pubsub.Message, err error) {
+				if err != nil {
+					logger.Error("message was not published", "discriminator", message.Discriminator(), "error", err)
+				}
+			})
+		}
+	}
+
+	batchSize := viper.GetInt("AZURE_SERVICEBUS_BATCH_SIZE")
+
+	// The publisher schedules natively, the scheduler only falls back to in-memory timers for publishers that do not.
+	scheduler := pubsub.NewScheduler(publisher, publisher.Publish, logger)
+
+	defer func() {
+		if count := scheduler.Close(); count > 0 {
+			logger.Warn("scheduled messages were dropped", "count", count)
+		}
+	}()
+
+	scheduleDelay := viper.GetDuration("AZURE_SERVICEBUS_SCHEDULE_DELAY")
 
 	for done := false; !done; {
 		select {
 		case <-ctx.Done():
 			done = true
 		case <-time.Tick(10 * time.Second):
-			message := xnms.NewDeviceEvent(event.Version1, event.OperationAddOrSet, time.Now().UTC().Format(time.RFC3339), TenantGroupNameExcitel,
-				&xnms.DeviceData{
-					Code:         "1234",
-					SerialNumber: "1234",
-					TenantName:   TenantNameDelhi,
-					Status:       xnms.StatusOnline,
-				},
-			)
+			if batchSize > 1 {
+				envelopes := make([]pubsub.Message, 0, batchSize)
 
-			envelope := envelopemessage.NewEnvelope(message)
+				for range batchSize {
+					envelopes = append(envelopes, newDeviceEnvelope())
+				}
 
-			envelope.ApplicationProperties = map[string]any{"Event": true, "Type": "XNMS_Device", "TenantGroupName": TenantGroupNameExcitel, TenantNameDelhi: "TenantName"}
+				// The error reports each message that failed. They are only logged here, a real publisher app would retry them.
+				if err := publisher.PublishBatch(ctx, envelopes); err != nil {
+					logger.Error("message batch was not fully published", "error", err)
+				}
 
-			if err := publisher.Publish(ctx, envelope); err != nil {
+				continue
+			}
+
+			// E.g. partner activation events are scheduled at the MigrationStartDate of the partner.
+			if scheduleDelay > 0 {
+				sequenceNumber, err := scheduler.Schedule(ctx, newDeviceEnvelope(), time.Now().Add(scheduleDelay))
+
+				if err != nil {
+					log.Panic(err)
+				}
+
+				logger.Info("message was scheduled", "sequenceNumber", sequenceNumber)
+
+				continue
+			}
+
+			if err := publishFunc(ctx, newDeviceEnvelope()); err != nil {
 				log.Panic(err)
 			}
 		}
 	}
 }
+
+// newSpool spools the envelopes with their message ID, session ID and application properties, as the outbox stores them.
+// The application properties derived from the message are derived again when they are replayed.
+func newSpool(directory string, publisher *servicebus.Publisher) (*spool.Spool, error) {
+	unmarshalFunc := util.NewUnmarshalEnvelopeJSONFunc(util.CreateMessage)
+
+	spoolOptions := &spool.SpoolOptions{
+		MaxBytes:      viper.GetInt64("SPOOL_MAX_BYTES"),
+		RetryableFunc: servicebus.IsRetryable,
+	}
+
+	return spool.NewSpool(directory, util.MarshalEnvelopeJSON, unmarshalFunc, publisher.Publish, logger, spoolOptions)
+}
+
+func newDeviceEnvelope() *envelopemessage.Envelope {
+	// The version and timestamp default to the latest version and the current time.
+	message := xnms.NewDeviceEventWithOptions(
+		event.OperationAddOrSet,
+		&xnms.DeviceData{
+			Code:         "1234",
+			SerialNumber: "1234",
+			TenantName:   TenantNameDelhi,
+			Status:       xnms.StatusOnline,
+		},
+		event.WithTenantGroupName(TenantGroupNameExcitel),
+	)
+
+	// The application properties are derived from the content of the message when it is marshaled.
+	envelope := envelopemessage.NewEnvelope(message)
+
+	return envelope
+}
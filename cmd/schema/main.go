@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/scaleforce/synchronization-for-go/internal/azure/servicebus/util"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/schema"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+var (
+	logger *slog.Logger
+)
+
+func init() {
+	logger = slog.Default()
+}
+
+// The schema app writes the JSON Schema of the current version of each message to <directory>/<Type>.<Version>.json, to be shared
+// with the publishers of other stacks. The schemas that already exist are compared with the generated schemas: the additive changes
+// are written, and the app fails on the breaking changes, which require a new version of the message.
+func main() {
+	directory := flag.String("directory", "schemas", "directory of the schemas")
+
+	flag.Parse()
+
+	if err := os.MkdirAll(*directory, 0o755); err != nil {
+		log.Panic(err)
+	}
+
+	registry := util.NewRegistry()
+
+	breaking := false
+
+	for _, discriminator := range registry.Discriminators() {
+		version, _ := registry.CurrentVersion(discriminator)
+
+		messageSchema, err := schema.GenerateMessage(registry.CreateMessage(discriminator))
+
+		if err != nil {
+			log.Panic(err)
+		}
+
+		path := filepath.Join(*directory, fmt.Sprintf("%s.%s.json", discriminator, version))
+
+		ok, err := check(path, discriminator, version, messageSchema)
+
+		if err != nil {
+			log.Panic(err)
+		}
+
+		if !ok {
+			breaking = true
+
+			continue
+		}
+
+		data, err := json.MarshalIndent(messageSchema, "", "  ")
+
+		if err != nil {
+			log.Panic(err)
+		}
+
+		if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+			log.Panic(err)
+		}
+	}
+
+	if breaking {
+		os.Exit(1)
+	}
+}
+
+// check compares the existing schema of the version with the generated schema and reports whether it can be written.
+func check(path string, discriminator pubsub.Discriminator, version string, messageSchema *schema.Schema) (bool, error) {
+	data, err := os.ReadFile(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("schema was added", "discriminator", discriminator, "version", version)
+
+		return true, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	existingSchema := &schema.Schema{}
+
+	if err := json.Unmarshal(data, existingSchema); err != nil {
+		return false, err
+	}
+
+	changes := schema.Compare(existingSchema, messageSchema)
+
+	for _, change := range changes {
+		logger.Info("schema was changed", "discriminator", discriminator, "version", version, "path", change.Path, "kind", change.Kind, "description", change.Description)
+	}
+
+	if changes.Breaking() {
+		logger.Error("schema has breaking changes, the version of the message must be incremented", "discriminator", discriminator, "version", version)
+
+		return false, nil
+	}
+
+	return true, nil
+}
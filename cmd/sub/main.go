@@ -2,22 +2,31 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus/admin"
 	"github.com/scaleforce/synchronization-for-go/internal/azure/servicebus/util"
 	"github.com/scaleforce/synchronization-for-go/internal/handler/event/hr"
 	"github.com/scaleforce/synchronization-for-go/internal/handler/event/masterdata"
 	"github.com/scaleforce/synchronization-for-go/internal/handler/event/partner"
 
 	// "github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus/claimcheck"
 	partitionedservicebus "github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus/partitioned"
+	"github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus/rules"
+	sessionservicebus "github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus/session"
+	"github.com/scaleforce/synchronization-for-go/pkg/filter"
 	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+	"github.com/scaleforce/synchronization-for-go/pkg/ratelimit"
 	"github.com/spf13/viper"
 )
 
@@ -45,6 +54,9 @@ func init() {
 
 	viper.SetDefault("AZURE_SERVICEBUS_INTERVAL", 10*time.Second)
 	viper.SetDefault("AZURE_SERVICEBUS_MESSAGES_LIMIT", 10)
+	viper.SetDefault("HEALTH_MAX_RECEIVE_AGE", 5*time.Minute)
+	viper.SetDefault("HEALTH_MAX_CONSECUTIVE_FAILURES", 10)
+	viper.SetDefault("CLAIM_CHECK_RETENTION", 14*24*time.Hour)
 
 	if err := viper.ReadInConfig(); err != nil {
 		log.Panic(err)
@@ -86,29 +98,225 @@ func main() {
 
 	defer cancelCtx()
 
-	receiverOptions := &azservicebus.ReceiverOptions{
-		ReceiveMode: azservicebus.ReceiveModePeekLock,
+	var limiter *ratelimit.Limiter
+
+	if viper.GetFloat64("RATE_LIMIT") > 0 {
+		limiterOptions := &ratelimit.LimiterOptions{
+			Global: &ratelimit.Limit{
+				Rate:  viper.GetFloat64("RATE_LIMIT"),
+				Burst: viper.GetInt("RATE_LIMIT_BURST"),
+			},
+			// Discriminators: map[pubsub.Discriminator]*ratelimit.Limit{"Partner_Partner": {Rate: 1, Burst: 1}},
+			// TenantGroups:   map[string]*ratelimit.Limit{"excitel": {Rate: 5, Burst: 5}},
+		}
+
+		limiter = ratelimit.NewLimiter(util.GetTenantGroupName, limiterOptions)
 	}
 
-	receiver, err := client.NewReceiverForSubscription(viper.GetString("AZURE_SERVICEBUS_TOPIC"), viper.GetString("AZURE_SERVICEBUS_SUBSCRIPTION"), receiverOptions)
+	filterOptions := &filter.FilterOptions{
+		TenantGroupNames: getList("FILTER_TENANT_GROUP_NAMES"),
+		TenantNames:      getList("FILTER_TENANT_NAMES"),
+		Operations:       getList("FILTER_OPERATIONS"),
+	}
 
-	if err != nil {
-		log.Panic(err)
+	messageFilter := filter.NewFilter(util.GetAttributes, filterOptions)
+
+	if viper.GetBool("AZURE_SERVICEBUS_RULES_RECONCILE") {
+		adminClient, err := admin.NewClientFromConnectionString(viper.GetString("AZURE_SERVICEBUS_CONNECTION_STRING"), nil)
+
+		if err != nil {
+			log.Panic(err)
+		}
+
+		reconcilerOptions := &rules.ReconcilerOptions{
+			DryRun: viper.GetBool("AZURE_SERVICEBUS_RULES_DRY_RUN"),
+		}
+
+		reconciler := rules.NewReconciler(adminClient, viper.GetString("AZURE_SERVICEBUS_TOPIC"), viper.GetString("AZURE_SERVICEBUS_SUBSCRIPTION"), logger, reconcilerOptions)
+
+		if err := reconciler.Reconcile(ctx, rules.NewRules("Synchronization", dispatcher, filterOptions)); err != nil {
+			log.Panic(err)
+		}
+	}
+
+	hooks := &pubsub.Hooks{
+		OnStarted: func() {
+			logger.Info("subscriber was started")
+		},
+		OnStopped: func(err error) {
+			logger.Info("subscriber was stopped", "error", err)
+		},
+	}
+
+	receiveMode := azservicebus.ReceiveModePeekLock
+
+	// Lower latency and cost, at the price of losing the messages that fail or that are received when the app crashes.
+	if viper.GetBool("AZURE_SERVICEBUS_RECEIVE_AND_DELETE") {
+		receiveMode = azservicebus.ReceiveModeReceiveAndDelete
+	}
+
+	unmarshalMessageFunc := util.NewUnmarshalVersionedMessageFunc(util.NewRegistry(), util.CreateMessage)
+
+	if claimCheckDirectory := viper.GetString("CLAIM_CHECK_DIRECTORY"); claimCheckDirectory != "" {
+		blobStore := claimcheck.NewFileBlobStore(claimCheckDirectory)
+
+		unmarshalMessageFunc = claimcheck.NewUnmarshalMessageFunc(unmarshalMessageFunc, blobStore)
+
+		cleanerOptions := &claimcheck.CleanerOptions{
+			Retention: viper.GetDuration("CLAIM_CHECK_RETENTION"),
+		}
+
+		go claimcheck.NewCleaner(blobStore, logger, cleanerOptions).Run(ctx)
 	}
 
-	defer receiver.Close(ctx)
+	unmarshalMessageFunc = util.NewUnmarshalReceivedEnvelopeFunc(unmarshalMessageFunc)
+
+	var subscriber pubsub.Subscriber
+
+	if viper.GetBool("AZURE_SERVICEBUS_SESSIONS") {
+		subscriberOptions := &sessionservicebus.SubscriberOptions{
+			Interval:             viper.GetDuration("AZURE_SERVICEBUS_INTERVAL"),
+			MessagesLimit:        viper.GetInt("AZURE_SERVICEBUS_MESSAGES_LIMIT"),
+			SessionsCount:        viper.GetInt("AZURE_SERVICEBUS_SESSIONS_COUNT"),
+			SessionMessagesLimit: viper.GetInt("AZURE_SERVICEBUS_SESSION_MESSAGES_LIMIT"),
+			SessionIdleTimeout:   viper.GetDuration("AZURE_SERVICEBUS_SESSION_IDLE_TIMEOUT"),
+			SessionStateTracking: viper.GetBool("AZURE_SERVICEBUS_SESSION_STATE_TRACKING"),
+			ReceiveMode:          receiveMode,
+			Limiter:              limiter,
+			Hooks:                hooks,
+			Filter:               messageFilter,
+		}
+
+		subscriber = sessionservicebus.NewSubscriberForSubscription(client, viper.GetString("AZURE_SERVICEBUS_TOPIC"), viper.GetString("AZURE_SERVICEBUS_SUBSCRIPTION"), dispatcher, unmarshalMessageFunc, logger, subscriberOptions)
+	} else {
+		subscriberOptions := &partitionedservicebus.SubscriberOptions{
+			Interval:        viper.GetDuration("AZURE_SERVICEBUS_INTERVAL"),
+			MessagesLimit:   viper.GetInt("AZURE_SERVICEBUS_MESSAGES_LIMIT"),
+			PartitionsCount: viper.GetInt("AZURE_SERVICEBUS_PARTITIONS_COUNT"),
+			PartitionsLimit: viper.GetInt("AZURE_SERVICEBUS_PARTITIONS_LIMIT"),
+			PartitionsDrain: viper.GetBool("AZURE_SERVICEBUS_PARTITIONS_DRAIN"),
+			ReceiveMode:     receiveMode,
+
+			LockRenewalInterval: viper.GetDuration("AZURE_SERVICEBUS_LOCK_RENEWAL_INTERVAL"),
+
+			InFlightMessagesLimit: viper.GetInt("AZURE_SERVICEBUS_INFLIGHT_MESSAGES_LIMIT"),
+			InFlightBytesLimit:    viper.GetInt64("AZURE_SERVICEBUS_INFLIGHT_BYTES_LIMIT"),
+
+			Limiter: limiter,
+			Hooks:   hooks,
+			Filter:  messageFilter,
+
+			// Process HR events in a lane with a higher weight, so that they are not stuck behind large master data imports.
+			// Lanes:       []*partitionedservicebus.Lane{{Weight: 1}, {Weight: 4}},
+			// GetLaneFunc: util.NewGetLaneFunc(nil, map[pubsub.Discriminator]int{"HR_Employee": 1, "HR_Position": 1, "HR_Role": 1}, 0),
+		}
+
+		partitionedSubscriber, err := partitionedservicebus.NewSubscriberForSubscription(client, viper.GetString("AZURE_SERVICEBUS_TOPIC"), viper.GetString("AZURE_SERVICEBUS_SUBSCRIPTION"), dispatcher, unmarshalMessageFunc, util.GetPartitionName, logger, subscriberOptions)
+
+		if err != nil {
+			log.Panic(err)
+		}
 
-	subscriberOptions := &partitionedservicebus.SubscriberOptions{
-		Interval:        viper.GetDuration("AZURE_SERVICEBUS_INTERVAL"),
-		MessagesLimit:   viper.GetInt("AZURE_SERVICEBUS_MESSAGES_LIMIT"),
-		PartitionsCount: viper.GetInt("AZURE_SERVICEBUS_PARTITIONS_COUNT"),
-		PartitionsLimit: viper.GetInt("AZURE_SERVICEBUS_PARTITIONS_LIMIT"),
-		PartitionsDrain: viper.GetBool("AZURE_SERVICEBUS_PARTITIONS_DRAIN"),
+		defer partitionedSubscriber.Close(ctx)
+
+		subscriber = partitionedSubscriber
 	}
 
-	subscriber := partitionedservicebus.NewSubscriber(receiver, dispatcher, util.NewUnmarshalReceivedEnvelopeFunc(util.NewUnmarshalMessageFunc(util.CreateMessage)), util.GetPartitionName, logger, subscriberOptions)
+	if adminAddress := viper.GetString("ADMIN_ADDRESS"); adminAddress != "" {
+		go serveAdmin(adminAddress, subscriber)
+	}
 
 	if err := subscriber.Run(ctx); err != nil {
 		log.Panic(err)
 	}
 }
+
+// getList returns the comma separated values of the configuration option, or nil when it is empty.
+func getList(key string) []string {
+	value := viper.GetString(key)
+
+	if value == "" {
+		return nil
+	}
+
+	values := strings.Split(value, ",")
+
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+
+	return values
+}
+
+// serveAdmin serves liveness and readiness endpoints for the orchestrator, and endpoints to pause and resume the subscriber.
+func serveAdmin(address string, subscriber pubsub.Subscriber) {
+	maxReceiveAge := viper.GetDuration("HEALTH_MAX_RECEIVE_AGE")
+	maxConsecutiveFailures := viper.GetInt("HEALTH_MAX_CONSECUTIVE_FAILURES")
+
+	serveMux := http.NewServeMux()
+
+	if pauser, ok := subscriber.(pubsub.Pauser); ok {
+		serveMux.HandleFunc("POST /pause", func(writer http.ResponseWriter, request *http.Request) {
+			pauser.Pause()
+
+			writer.WriteHeader(http.StatusNoContent)
+		})
+
+		serveMux.HandleFunc("POST /resume", func(writer http.ResponseWriter, request *http.Request) {
+			pauser.Resume()
+
+			writer.WriteHeader(http.StatusNoContent)
+		})
+	}
+
+	healthReporter, ok := subscriber.(pubsub.HealthReporter)
+
+	if !ok {
+		logger.Warn("subscriber does not report its health")
+	}
+
+	serveMux.HandleFunc("GET /livez", func(writer http.ResponseWriter, request *http.Request) {
+		if healthReporter != nil && !healthReporter.Health().Live(maxReceiveAge) {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		writer.WriteHeader(http.StatusOK)
+	})
+
+	serveMux.HandleFunc("GET /readyz", func(writer http.ResponseWriter, request *http.Request) {
+		if healthReporter != nil && !healthReporter.Health().Ready(maxConsecutiveFailures) {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		writer.WriteHeader(http.StatusOK)
+	})
+
+	serveMux.HandleFunc("GET /healthz", func(writer http.ResponseWriter, request *http.Request) {
+		if healthReporter == nil {
+			writer.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		healthReport := healthReporter.Health()
+
+		writer.Header().Set("Content-Type", "application/json")
+
+		json.NewEncoder(writer).Encode(map[string]any{
+			"Running":             healthReport.Running,
+			"Paused":              healthReport.Paused,
+			"LastReceiveTime":     healthReport.LastReceiveTime,
+			"LastMessageTime":     healthReport.LastMessageTime,
+			"ConsecutiveFailures": healthReport.ConsecutiveFailures,
+			"PartitionSaturation": healthReport.PartitionSaturation,
+		})
+	})
+
+	if err := http.ListenAndServe(address, serveMux); err != nil {
+		logger.Error("admin endpoints could not be served", "error", err)
+	}
+}
@@ -1,17 +1,26 @@
 package util
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"slices"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/google/uuid"
 	emptymessage "github.com/scaleforce/synchronization-for-go/internal/message/empty"
 	envelopemessage "github.com/scaleforce/synchronization-for-go/internal/message/envelope"
 	"github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus"
+	partitionedservicebus "github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus/partitioned"
+	"github.com/scaleforce/synchronization-for-go/pkg/filter"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event"
 	"github.com/scaleforce/synchronization-for-go/pkg/message/event/hr"
 	"github.com/scaleforce/synchronization-for-go/pkg/message/event/masterdata"
 	"github.com/scaleforce/synchronization-for-go/pkg/message/event/partner"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event/xnms"
 	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+	"github.com/scaleforce/synchronization-for-go/pkg/validation"
 )
 
 func NewMarshalMessageFunc() servicebus.MarshalMessageFunc {
@@ -30,31 +39,33 @@ func NewMarshalMessageFunc() servicebus.MarshalMessageFunc {
 	}
 }
 
-func CreateMessage(discriminator pubsub.Discriminator) pubsub.Message {
-	var message pubsub.Message
+// NewRegistry registers the types of the current version of the messages. The types of the older versions are registered
+// here with their upcasters, once the messages have a newer version.
+func NewRegistry() *event.Registry {
+	registry := event.NewRegistry()
+
+	registry.Register(masterdata.DiscriminatorCity, event.Version1, func() pubsub.Message { return &masterdata.CityEvent{} })
+	registry.Register(masterdata.DiscriminatorCircle, event.Version1, func() pubsub.Message { return &masterdata.CircleEvent{} })
+	registry.Register(masterdata.DiscriminatorZone, event.Version1, func() pubsub.Message { return &masterdata.ZoneEvent{} })
+	registry.Register(partner.DiscriminatorPartnerGroup, event.Version1, func() pubsub.Message { return &partner.PartnerGroupEvent{} })
+	registry.Register(partner.DiscriminatorPartner, event.Version1, func() pubsub.Message { return &partner.PartnerEvent{} })
+	registry.Register(hr.DiscriminatorEmployee, event.Version1, func() pubsub.Message { return &hr.EmployeeEvent{} })
+	registry.Register(hr.DiscriminatorPosition, event.Version1, func() pubsub.Message { return &hr.PositionEvent{} })
+	registry.Register(hr.DiscriminatorRole, event.Version1, func() pubsub.Message { return &hr.RoleEvent{} })
+	registry.Register(xnms.DiscriminatorDevice, event.Version1, func() pubsub.Message { return &xnms.DeviceEvent{} })
+
+	return registry
+}
 
-	switch discriminator {
-	case masterdata.DiscriminatorCity:
-		message = &masterdata.CityEvent{}
-	case masterdata.DiscriminatorCircle:
-		message = &masterdata.CircleEvent{}
-	case masterdata.DiscriminatorZone:
-		message = &masterdata.ZoneEvent{}
-	case partner.DiscriminatorPartnerGroup:
-		message = &partner.PartnerGroupEvent{}
-	case partner.DiscriminatorPartner:
-		message = &partner.PartnerEvent{}
-	case hr.DiscriminatorEmployee:
-		message = &hr.EmployeeEvent{}
-	case hr.DiscriminatorPosition:
-		message = &hr.PositionEvent{}
-	case hr.DiscriminatorRole:
-		message = &hr.RoleEvent{}
-	default:
-		message = &emptymessage.Empty{}
+var registry = NewRegistry()
+
+// CreateMessage returns a message of the current version of discriminator.
+func CreateMessage(discriminator pubsub.Discriminator) pubsub.Message {
+	if message := registry.CreateMessage(discriminator); message != nil {
+		return message
 	}
 
-	return message
+	return &emptymessage.Empty{}
 }
 
 type CreateMessageFunc func(discriminator pubsub.Discriminator) pubsub.Message
@@ -83,6 +94,35 @@ func NewUnmarshalMessageFunc(createMessageFunc CreateMessageFunc) servicebus.Unm
 	}
 }
 
+// NewUnmarshalVersionedMessageFunc unmarshals the messages registered in registry according to their version, upcasting
+// the older versions into the current version. The other messages are unmarshaled into the message of createMessageFunc.
+func NewUnmarshalVersionedMessageFunc(registry *event.Registry, createMessageFunc CreateMessageFunc) servicebus.UnmarshalMessageFunc {
+	return func(serviceBusReceivedMessage *azservicebus.ReceivedMessage) (pubsub.Message, error) {
+		partialMessage := &struct {
+			Type    string `json:"Type"`
+			Version string `json:"Version"`
+		}{}
+
+		if err := json.Unmarshal(serviceBusReceivedMessage.Body, &partialMessage); err != nil {
+			return nil, err
+		}
+
+		discriminator := pubsub.Discriminator(partialMessage.Type)
+
+		if registry.Registered(discriminator) {
+			return registry.Unmarshal(discriminator, partialMessage.Version, serviceBusReceivedMessage.Body)
+		}
+
+		message := createMessageFunc(discriminator)
+
+		if err := json.Unmarshal(serviceBusReceivedMessage.Body, &message); err != nil {
+			return nil, err
+		}
+
+		return message, nil
+	}
+}
+
 func NewMarshalEnvelopeFunc(marshalMessageFunc servicebus.MarshalMessageFunc) servicebus.MarshalMessageFunc {
 	return func(message pubsub.Message) (*azservicebus.Message, error) {
 		envelope, ok := message.(*envelopemessage.Envelope)
@@ -123,121 +163,384 @@ func NewUnmarshalReceivedEnvelopeFunc(unmarshalMessageFunc servicebus.UnmarshalM
 	}
 }
 
+// storedEnvelope is the JSON of an envelope that is stored before it is published, e.g. in the outbox.
+type storedEnvelope struct {
+	ApplicationProperties map[string]any `json:",omitempty"`
+	SessionID             *string        `json:",omitempty"`
+	MessageID             *string        `json:",omitempty"`
+	Message               json.RawMessage
+}
+
+// MarshalEnvelopeJSON marshals the envelope with its message, so that it is published later with the same message ID,
+// session ID and application properties, e.g. by the outbox relay. The application properties are stored as JSON values,
+// so the numbers are restored as float64. The application properties derived from the message are derived again when it is published.
+func MarshalEnvelopeJSON(message pubsub.Message) ([]byte, error) {
+	envelope, ok := message.(*envelopemessage.Envelope)
+
+	if !ok {
+		return nil, envelopemessage.ErrInvalidEnvelope
+	}
+
+	data, err := json.Marshal(envelope.Message)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&storedEnvelope{
+		ApplicationProperties: envelope.ApplicationProperties,
+		SessionID:             envelope.SessionID,
+		MessageID:             envelope.MessageID,
+		Message:               data,
+	})
+}
+
+// NewUnmarshalEnvelopeJSONFunc returns the function that restores the envelopes marshaled by MarshalEnvelopeJSON.
+func NewUnmarshalEnvelopeJSONFunc(createMessageFunc CreateMessageFunc) func(discriminator pubsub.Discriminator, payload []byte) (pubsub.Message, error) {
+	return func(discriminator pubsub.Discriminator, payload []byte) (pubsub.Message, error) {
+		stored := &storedEnvelope{}
+
+		if err := json.Unmarshal(payload, stored); err != nil {
+			return nil, err
+		}
+
+		message := createMessageFunc(discriminator)
+
+		if err := json.Unmarshal(stored.Message, message); err != nil {
+			return nil, err
+		}
+
+		envelope := envelopemessage.NewEnvelope(message)
+
+		envelope.ApplicationProperties = stored.ApplicationProperties
+		envelope.SessionID = stored.SessionID
+		envelope.MessageID = stored.MessageID
+
+		return envelope, nil
+	}
+}
+
+func GetTenantGroupName(message pubsub.Message) (string, error) {
+	switch envelope := message.(type) {
+	case *envelopemessage.ReceivedEnvelope:
+		message = envelope.Message
+	case *envelopemessage.Envelope:
+		message = envelope.Message
+	}
+
+	tenantGroupEventMessage, ok := message.(event.TenantGroupEventMessage)
+
+	if !ok {
+		return "", nil
+	}
+
+	return tenantGroupEventMessage.GetTenantGroupEvent().TenantGroupName, nil
+}
+
+// NewGetLaneFunc returns the lane of the operation of the message, or else the lane of its discriminator, or else the default lane.
+func NewGetLaneFunc(operationLanes map[event.Operation]int, discriminatorLanes map[pubsub.Discriminator]int, defaultLaneIndex int) partitionedservicebus.GetLaneFunc {
+	return func(message pubsub.Message) (int, error) {
+		switch envelope := message.(type) {
+		case *envelopemessage.ReceivedEnvelope:
+			message = envelope.Message
+		case *envelopemessage.Envelope:
+			message = envelope.Message
+		}
+
+		if eventMessage, ok := message.(event.EventMessage); ok {
+			if laneIndex, ok := operationLanes[eventMessage.GetEvent().Operation]; ok {
+				return laneIndex, nil
+			}
+		}
+
+		if laneIndex, ok := discriminatorLanes[message.Discriminator()]; ok {
+			return laneIndex, nil
+		}
+
+		return defaultLaneIndex, nil
+	}
+}
+
+// GetAttributes derives the filter attributes from the content of the message. The empty tenant names are skipped, so that
+// the messages without tenant apply to all tenants.
+func GetAttributes(message pubsub.Message) (*filter.Attributes, error) {
+	switch envelope := message.(type) {
+	case *envelopemessage.ReceivedEnvelope:
+		message = envelope.Message
+	case *envelopemessage.Envelope:
+		message = envelope.Message
+	}
+
+	attributes := &filter.Attributes{
+		Discriminator: message.Discriminator(),
+	}
+
+	if eventMessage, ok := message.(event.EventMessage); ok {
+		attributes.Version = eventMessage.GetEvent().Version
+		attributes.Operation = eventMessage.GetEvent().Operation.String()
+	}
+
+	if tenantGroupEventMessage, ok := message.(event.TenantGroupEventMessage); ok {
+		attributes.TenantGroupName = tenantGroupEventMessage.GetTenantGroupEvent().TenantGroupName
+	}
+
+	if tenantEventMessage, ok := message.(event.TenantEventMessage); ok {
+		attributes.TenantNames = append(attributes.TenantNames, tenantEventMessage.GetTenantEvent().TenantName)
+	}
+
+	switch message := message.(type) {
+	case *masterdata.CityEvent:
+		if message.Data != nil {
+			attributes.TenantNames = append(attributes.TenantNames, message.Data.TenantName)
+		}
+	case *masterdata.CircleEvent:
+		if message.Data != nil {
+			attributes.TenantNames = append(attributes.TenantNames, message.Data.TenantName)
+		}
+	case *masterdata.ZoneEvent:
+		if message.Data != nil {
+			attributes.TenantNames = append(attributes.TenantNames, message.Data.TenantName)
+		}
+	case *partner.PartnerGroupEvent:
+		if message.Data != nil {
+			attributes.TenantNames = append(attributes.TenantNames, message.Data.TenantName)
+		}
+	case *partner.PartnerEvent:
+		if message.Data != nil {
+			attributes.TenantNames = append(attributes.TenantNames, message.Data.TenantName)
+		}
+	case *xnms.DeviceEvent:
+		if message.Data != nil {
+			attributes.TenantNames = append(attributes.TenantNames, message.Data.TenantName)
+		}
+	case *hr.EmployeeEvent:
+		if message.Data != nil && message.Data.TenantGroup != nil {
+			for _, tenant := range message.Data.TenantGroup.Tenants {
+				attributes.TenantNames = append(attributes.TenantNames, tenant.Name)
+			}
+		}
+
+		if message.Data != nil && message.Data.PartnerGroup != nil {
+			attributes.TenantNames = append(attributes.TenantNames, message.Data.PartnerGroup.TenantName)
+		}
+	}
+
+	attributes.TenantNames = slices.DeleteFunc(attributes.TenantNames, func(tenantName string) bool {
+		return tenantName == ""
+	})
+
+	return attributes, nil
+}
+
+// MessageIDNamespace is the namespace of the UUIDv5 message IDs.
+var MessageIDNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/scaleforce/synchronization-for-go"))
+
+// GetMessageKey identifies an event by its tenant group, type and code (the partition key), operation, timestamp and
+// the hash of its JSON. The timestamps have a precision of one second, so the hash tells apart the different events of
+// the same entity and operation within the same second. Events without a partition key cannot be identified.
+func GetMessageKey(message pubsub.Message) ([]string, error) {
+	partitionKey, err := GetPartitionKey(message)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if partitionKey == "" {
+		return nil, nil
+	}
+
+	envelope, ok := message.(*envelopemessage.Envelope)
+
+	if !ok {
+		return nil, envelopemessage.ErrInvalidEnvelope
+	}
+
+	eventMessage, ok := envelope.Message.(event.EventMessage)
+
+	if !ok {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(envelope.Message)
+
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(data)
+
+	return []string{partitionKey, eventMessage.GetEvent().Operation.String(), eventMessage.GetEvent().Timestamp.String(), hex.EncodeToString(hash[:])}, nil
+}
+
+// GetApplicationProperties derives the application properties documented in the servicebus package from the content
+// of the message.
+func GetApplicationProperties(message pubsub.Message) (map[string]any, error) {
+	attributes, err := GetAttributes(message)
+
+	if err != nil {
+		return nil, err
+	}
+
+	applicationProperties := map[string]any{
+		servicebus.ApplicationPropertyType: string(attributes.Discriminator),
+	}
+
+	switch envelope := message.(type) {
+	case *envelopemessage.ReceivedEnvelope:
+		message = envelope.Message
+	case *envelopemessage.Envelope:
+		message = envelope.Message
+	}
+
+	if _, ok := message.(event.EventMessage); ok {
+		applicationProperties[servicebus.ApplicationPropertyEvent] = true
+		applicationProperties[servicebus.ApplicationPropertyVersion] = attributes.Version
+		applicationProperties[servicebus.ApplicationPropertyOperation] = attributes.Operation
+	}
+
+	if attributes.TenantGroupName != "" {
+		applicationProperties[servicebus.ApplicationPropertyTenantGroupName] = attributes.TenantGroupName
+	}
+
+	// The application properties cannot hold lists, so the tenant is only set when there is exactly one.
+	tenantNames := slices.Compact(slices.DeleteFunc(slices.Sorted(slices.Values(attributes.TenantNames)), func(tenantName string) bool {
+		return tenantName == ""
+	}))
+
+	if len(tenantNames) == 1 {
+		applicationProperties[servicebus.ApplicationPropertyTenantName] = tenantNames[0]
+	}
+
+	return applicationProperties, nil
+}
+
 func GetPartitionName(message pubsub.Message) (string, error) {
+	receivedEnvelope, ok := message.(*envelopemessage.ReceivedEnvelope)
+
+	if !ok {
+		return "", envelopemessage.ErrInvalidReceivedEnvelope
+	}
+
+	return getPartitionKey(receivedEnvelope.Message)
+}
+
+// GetSessionID uses the same key as GetPartitionName, so that the messages ordered by the partitioned subscriber
+// within one process are ordered by the session subscriber across processes.
+func GetSessionID(message pubsub.Message) (string, error) {
+	return GetPartitionKey(message)
+}
+
+// GetPartitionKey uses the same key as GetPartitionName on the publisher side, e.g. to preserve the order of the messages
+// published in batches.
+func GetPartitionKey(message pubsub.Message) (string, error) {
+	envelope, ok := message.(*envelopemessage.Envelope)
+
+	if !ok {
+		return "", envelopemessage.ErrInvalidEnvelope
+	}
+
+	return getPartitionKey(envelope.Message)
+}
+
+func getPartitionKey(message pubsub.Message) (string, error) {
 	var partitionName string
 
 	discriminator := message.Discriminator()
 
 	switch discriminator {
 	case masterdata.DiscriminatorCity:
-		receivedEnvelope, ok := message.(*envelopemessage.ReceivedEnvelope)
+		cityEvent, ok := message.(*masterdata.CityEvent)
 
 		if !ok {
-			return "", envelopemessage.ErrInvalidReceivedEnvelope
+			return "", pubsub.ErrInvalidDiscriminator
 		}
 
-		cityEvent, ok := receivedEnvelope.Message.(*masterdata.CityEvent)
-
-		if !ok {
-			return "", pubsub.ErrInvalidDiscriminator
+		if cityEvent.Data == nil {
+			return "", newMissingDataError(discriminator)
 		}
 
 		partitionName = fmt.Sprintf("%s~%s~%s", cityEvent.TenantGroupName, cityEvent.Type, cityEvent.Data.Code)
 	case masterdata.DiscriminatorCircle:
-		receivedEnvelope, ok := message.(*envelopemessage.ReceivedEnvelope)
+		circleEvent, ok := message.(*masterdata.CircleEvent)
 
 		if !ok {
-			return "", envelopemessage.ErrInvalidReceivedEnvelope
+			return "", pubsub.ErrInvalidDiscriminator
 		}
 
-		circleEvent, ok := receivedEnvelope.Message.(*masterdata.CircleEvent)
-
-		if !ok {
-			return "", pubsub.ErrInvalidDiscriminator
+		if circleEvent.Data == nil {
+			return "", newMissingDataError(discriminator)
 		}
 
 		partitionName = fmt.Sprintf("%s~%s~%s", circleEvent.TenantGroupName, circleEvent.Type, circleEvent.Data.Code)
 	case masterdata.DiscriminatorZone:
-		receivedEnvelope, ok := message.(*envelopemessage.ReceivedEnvelope)
+		zoneEvent, ok := message.(*masterdata.ZoneEvent)
 
 		if !ok {
-			return "", envelopemessage.ErrInvalidReceivedEnvelope
+			return "", pubsub.ErrInvalidDiscriminator
 		}
 
-		zoneEvent, ok := receivedEnvelope.Message.(*masterdata.ZoneEvent)
-
-		if !ok {
-			return "", pubsub.ErrInvalidDiscriminator
+		if zoneEvent.Data == nil {
+			return "", newMissingDataError(discriminator)
 		}
 
 		partitionName = fmt.Sprintf("%s~%s~%s", zoneEvent.TenantGroupName, zoneEvent.Type, zoneEvent.Data.Code)
 	case partner.DiscriminatorPartnerGroup:
-		receivedEnvelope, ok := message.(*envelopemessage.ReceivedEnvelope)
+		partnerGroupEvent, ok := message.(*partner.PartnerGroupEvent)
 
 		if !ok {
-			return "", envelopemessage.ErrInvalidReceivedEnvelope
+			return "", pubsub.ErrInvalidDiscriminator
 		}
 
-		partnerGroupEvent, ok := receivedEnvelope.Message.(*partner.PartnerGroupEvent)
-
-		if !ok {
-			return "", pubsub.ErrInvalidDiscriminator
+		if partnerGroupEvent.Data == nil {
+			return "", newMissingDataError(discriminator)
 		}
 
 		partitionName = fmt.Sprintf("%s~%s~%s", partnerGroupEvent.TenantGroupName, partnerGroupEvent.Type, partnerGroupEvent.Data.Code)
 	case partner.DiscriminatorPartner:
-		receivedEnvelope, ok := message.(*envelopemessage.ReceivedEnvelope)
+		partnerEvent, ok := message.(*partner.PartnerEvent)
 
 		if !ok {
-			return "", envelopemessage.ErrInvalidReceivedEnvelope
+			return "", pubsub.ErrInvalidDiscriminator
 		}
 
-		partnerEvent, ok := receivedEnvelope.Message.(*partner.PartnerEvent)
-
-		if !ok {
-			return "", pubsub.ErrInvalidDiscriminator
+		if partnerEvent.Data == nil {
+			return "", newMissingDataError(discriminator)
 		}
 
 		partitionName = fmt.Sprintf("%s~%s~%s", partnerEvent.TenantGroupName, partnerEvent.Type, partnerEvent.Data.Code)
 	case hr.DiscriminatorEmployee:
-		receivedEnvelope, ok := message.(*envelopemessage.ReceivedEnvelope)
+		employeeEvent, ok := message.(*hr.EmployeeEvent)
 
 		if !ok {
-			return "", envelopemessage.ErrInvalidReceivedEnvelope
+			return "", pubsub.ErrInvalidDiscriminator
 		}
 
-		employeeEvent, ok := receivedEnvelope.Message.(*hr.EmployeeEvent)
-
-		if !ok {
-			return "", pubsub.ErrInvalidDiscriminator
+		if employeeEvent.Data == nil {
+			return "", newMissingDataError(discriminator)
 		}
 
 		partitionName = fmt.Sprintf("%s~%s~%s", employeeEvent.TenantGroupName, employeeEvent.Type, employeeEvent.Data.Code)
 	case hr.DiscriminatorPosition:
-		receivedEnvelope, ok := message.(*envelopemessage.ReceivedEnvelope)
+		positionEvent, ok := message.(*hr.PositionEvent)
 
 		if !ok {
-			return "", envelopemessage.ErrInvalidReceivedEnvelope
+			return "", pubsub.ErrInvalidDiscriminator
 		}
 
-		positionEvent, ok := receivedEnvelope.Message.(*hr.PositionEvent)
-
-		if !ok {
-			return "", pubsub.ErrInvalidDiscriminator
+		if positionEvent.Data == nil {
+			return "", newMissingDataError(discriminator)
 		}
 
 		partitionName = fmt.Sprintf("%s~%s~%s", positionEvent.TenantGroupName, positionEvent.Type, positionEvent.Data.Code)
 	case hr.DiscriminatorRole:
-		receivedEnvelope, ok := message.(*envelopemessage.ReceivedEnvelope)
+		roleEvent, ok := message.(*hr.RoleEvent)
 
 		if !ok {
-			return "", envelopemessage.ErrInvalidReceivedEnvelope
+			return "", pubsub.ErrInvalidDiscriminator
 		}
 
-		roleEvent, ok := receivedEnvelope.Message.(*hr.RoleEvent)
-
-		if !ok {
-			return "", pubsub.ErrInvalidDiscriminator
+		if roleEvent.Data == nil {
+			return "", newMissingDataError(discriminator)
 		}
 
 		partitionName = fmt.Sprintf("%s~%s~%s", roleEvent.TenantGroupName, roleEvent.Type, roleEvent.Data.Code)
@@ -247,3 +550,8 @@ func GetPartitionName(message pubsub.Message) (string, error) {
 
 	return partitionName, nil
 }
+
+// newMissingDataError returns the error of the messages without data, which have no partition key.
+func newMissingDataError(discriminator pubsub.Discriminator) error {
+	return fmt.Errorf("%w: %s has no data", validation.ErrInvalidMessage, discriminator)
+}
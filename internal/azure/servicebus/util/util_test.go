@@ -0,0 +1,141 @@
+package util
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	envelopemessage "github.com/scaleforce/synchronization-for-go/internal/message/envelope"
+	"github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event/hr"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event/masterdata"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event/xnms"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+func TestEnvelopeJSONKeepsTheMessageIDAndSessionID(t *testing.T) {
+	envelope := envelopemessage.NewEnvelope(xnms.NewDeviceEventWithOptions(event.OperationAddOrSet, &xnms.DeviceData{Code: "1"}, event.WithTenantGroupName("group")))
+
+	envelope.MessageID = to.Ptr("message")
+	envelope.SessionID = to.Ptr("session")
+	envelope.ApplicationProperties = map[string]any{"Custom": "value"}
+
+	payload, err := MarshalEnvelopeJSON(envelope)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message, err := NewUnmarshalEnvelopeJSONFunc(CreateMessage)(xnms.DiscriminatorDevice, payload)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restoredEnvelope, ok := message.(*envelopemessage.Envelope)
+
+	if !ok {
+		t.Fatalf("message is %T, expected *envelope.Envelope", message)
+	}
+
+	if _, ok := restoredEnvelope.Message.(*xnms.DeviceEvent); !ok {
+		t.Fatalf("message is %T, expected *xnms.DeviceEvent", restoredEnvelope.Message)
+	}
+
+	if *restoredEnvelope.MessageID != "message" || *restoredEnvelope.SessionID != "session" || restoredEnvelope.ApplicationProperties["Custom"] != "value" {
+		t.Fatalf("envelope is %+v", restoredEnvelope)
+	}
+
+	if _, err := MarshalEnvelopeJSON(envelope.Message); err == nil {
+		t.Fatal("message that is not an envelope was marshaled")
+	}
+}
+
+func TestGetAttributesSkipsTheEmptyTenantNames(t *testing.T) {
+	device := xnms.NewDeviceEventWithOptions(event.OperationAddOrSet, &xnms.DeviceData{Code: "1"}, event.WithTenantGroupName("group"))
+
+	attributes, err := GetAttributes(envelopemessage.NewEnvelope(device))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(attributes.TenantNames) != 0 {
+		t.Fatalf("tenant names are %q, expected none", attributes.TenantNames)
+	}
+
+	device.Data.TenantName = "tenant"
+
+	attributes, err = GetAttributes(envelopemessage.NewEnvelope(device))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(attributes.TenantNames, []string{"tenant"}) {
+		t.Fatalf("tenant names are %q, expected [tenant]", attributes.TenantNames)
+	}
+}
+
+func TestGetApplicationPropertiesDropsTheEmptyValues(t *testing.T) {
+	group := event.WithTenantGroupName("group")
+
+	tests := []struct {
+		name     string
+		message  pubsub.Message
+		expected map[string]any
+	}{
+		{"event with tenant", xnms.NewDeviceEventWithOptions(event.OperationAddOrSet, &xnms.DeviceData{Code: "1", TenantName: "delhi"}, group), map[string]any{
+			servicebus.ApplicationPropertyType:            string(xnms.DiscriminatorDevice),
+			servicebus.ApplicationPropertyEvent:           true,
+			servicebus.ApplicationPropertyVersion:         event.LatestVersion,
+			servicebus.ApplicationPropertyOperation:       "AddOrSet",
+			servicebus.ApplicationPropertyTenantGroupName: "group",
+			servicebus.ApplicationPropertyTenantName:      "delhi",
+		}},
+		{"event without tenant group and tenant", masterdata.NewCityEventWithOptions(event.OperationRemove, &masterdata.CityData{Code: "1"}), map[string]any{
+			servicebus.ApplicationPropertyType:      string(masterdata.DiscriminatorCity),
+			servicebus.ApplicationPropertyEvent:     true,
+			servicebus.ApplicationPropertyVersion:   event.LatestVersion,
+			servicebus.ApplicationPropertyOperation: "Remove",
+		}},
+		{"event with the same tenant twice", hr.NewEmployeeEventWithOptions(event.OperationAddOrSet, &hr.EmployeeData{
+			Code:         "1",
+			TenantGroup:  &hr.EmployeeTenantGroupModel{Tenants: []*hr.TenantModel{{Name: "delhi"}, {Name: ""}}},
+			PartnerGroup: &hr.EmployeePartnerGroupModel{PartnerGroupCode: "1", TenantName: "delhi"},
+		}, group), map[string]any{
+			servicebus.ApplicationPropertyType:            string(hr.DiscriminatorEmployee),
+			servicebus.ApplicationPropertyEvent:           true,
+			servicebus.ApplicationPropertyVersion:         event.LatestVersion,
+			servicebus.ApplicationPropertyOperation:       "AddOrSet",
+			servicebus.ApplicationPropertyTenantGroupName: "group",
+			servicebus.ApplicationPropertyTenantName:      "delhi",
+		}},
+		{"event with several tenants", hr.NewEmployeeEventWithOptions(event.OperationAddOrSet, &hr.EmployeeData{
+			Code:        "1",
+			TenantGroup: &hr.EmployeeTenantGroupModel{Tenants: []*hr.TenantModel{{Name: "delhi"}, {Name: "mumbai"}}},
+		}, group), map[string]any{
+			servicebus.ApplicationPropertyType:            string(hr.DiscriminatorEmployee),
+			servicebus.ApplicationPropertyEvent:           true,
+			servicebus.ApplicationPropertyVersion:         event.LatestVersion,
+			servicebus.ApplicationPropertyOperation:       "AddOrSet",
+			servicebus.ApplicationPropertyTenantGroupName: "group",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			applicationProperties, err := GetApplicationProperties(envelopemessage.NewEnvelope(test.message))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !maps.Equal(applicationProperties, test.expected) {
+				t.Fatalf("application properties are %v, expected %v", applicationProperties, test.expected)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+	"github.com/scaleforce/synchronization-for-go/pkg/validation"
 )
 
 var (
@@ -29,6 +30,10 @@ func (message *Envelope) Discriminator() pubsub.Discriminator {
 	return message.Message.Discriminator()
 }
 
+func (message *Envelope) Validate() error {
+	return validation.Validate(message.Message)
+}
+
 type ReceivedEnvelope struct {
 	ApplicationProperties  map[string]any
 	EnqueuedSequenceNumber *int64
@@ -45,3 +50,7 @@ func NewReceivedEnvelope(message pubsub.Message) *ReceivedEnvelope {
 func (message *ReceivedEnvelope) Discriminator() pubsub.Discriminator {
 	return message.Message.Discriminator()
 }
+
+func (message *ReceivedEnvelope) Validate() error {
+	return validation.Validate(message.Message)
+}
@@ -0,0 +1,293 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+var (
+	ErrPublisherClosed  = errors.New("publisher closed")
+	ErrPublisherStopped = errors.New("publisher stopped")
+)
+
+// BatchPublisher is implemented by servicebus.Publisher.
+type BatchPublisher interface {
+	PublishBatch(ctx context.Context, messages []pubsub.Message) error
+}
+
+// CallbackFunc is called once the message is published or failed. It is called by Run, so it must not block.
+type CallbackFunc func(message pubsub.Message, err error)
+
+// Future completes once the message is published or failed.
+type Future struct {
+	done chan struct{}
+	err  error
+}
+
+func newFuture() *Future {
+	return &Future{
+		done: make(chan struct{}),
+	}
+}
+
+func (future *Future) Done() <-chan struct{} {
+	return future.done
+}
+
+// Err returns the error of the message once Done is closed, and nil before.
+func (future *Future) Err() error {
+	select {
+	case <-future.done:
+		return future.err
+	default:
+		return nil
+	}
+}
+
+func (future *Future) Wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-future.done:
+		return future.err
+	}
+}
+
+type PublisherOptions struct {
+	// Number of messages accepted but not yet published, defaults to 1000. Publish blocks while the buffer is full.
+	BufferSize int
+	// Maximum number of messages published at once, defaults to 100. The batches are also split on the size limit by
+	// servicebus.Publisher.
+	BatchSize int
+	// Time the first message of a batch waits for more messages, defaults to 100 milliseconds.
+	Linger time.Duration
+}
+
+type request struct {
+	message      pubsub.Message
+	future       *Future
+	callbackFunc CallbackFunc
+}
+
+// Publisher accepts messages into a bounded buffer and publishes them in batches, once the batch is full or once its
+// first message has lingered long enough. Close stops accepting messages and publishes the buffered ones.
+type Publisher struct {
+	batchPublisher BatchPublisher
+	logger         *slog.Logger
+	options        *PublisherOptions
+	requests       chan *request
+	mutex          sync.RWMutex
+	closed         bool
+	stopped        chan struct{}
+	stopOnce       sync.Once
+}
+
+func NewPublisher(batchPublisher BatchPublisher, logger *slog.Logger, options *PublisherOptions) *Publisher {
+	bufferSize := 1000
+
+	if options != nil && options.BufferSize > 0 {
+		bufferSize = options.BufferSize
+	}
+
+	return &Publisher{
+		batchPublisher: batchPublisher,
+		logger:         logger,
+		options:        options,
+		requests:       make(chan *request, bufferSize),
+		stopped:        make(chan struct{}),
+	}
+}
+
+// Publish returns once the message is accepted into the buffer. The returned future completes once it is published.
+func (publisher *Publisher) Publish(ctx context.Context, message pubsub.Message) (*Future, error) {
+	future := newFuture()
+
+	if err := publisher.enqueue(ctx, &request{message: message, future: future}); err != nil {
+		return nil, err
+	}
+
+	return future, nil
+}
+
+// PublishCallback returns once the message is accepted into the buffer. callbackFunc is called once it is published.
+func (publisher *Publisher) PublishCallback(ctx context.Context, message pubsub.Message, callbackFunc CallbackFunc) error {
+	return publisher.enqueue(ctx, &request{message: message, callbackFunc: callbackFunc})
+}
+
+func (publisher *Publisher) enqueue(ctx context.Context, request *request) error {
+	publisher.mutex.RLock()
+
+	defer publisher.mutex.RUnlock()
+
+	if publisher.closed {
+		return ErrPublisherClosed
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-publisher.stopped:
+		return ErrPublisherStopped
+	case publisher.requests <- request:
+		return nil
+	}
+}
+
+// Close stops accepting messages and waits until Run has published the buffered messages.
+func (publisher *Publisher) Close(ctx context.Context) error {
+	publisher.mutex.Lock()
+
+	if !publisher.closed {
+		publisher.closed = true
+
+		close(publisher.requests)
+	}
+
+	publisher.mutex.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-publisher.stopped:
+		return nil
+	}
+}
+
+// Run publishes the buffered messages until Close is called, or until ctx is done, in which case the buffered messages fail.
+func (publisher *Publisher) Run(ctx context.Context) error {
+	defer publisher.stop()
+
+	batchSize := 100
+	linger := 100 * time.Millisecond
+
+	if publisher.options != nil {
+		if publisher.options.BatchSize > 0 {
+			batchSize = publisher.options.BatchSize
+		}
+
+		if publisher.options.Linger > 0 {
+			linger = publisher.options.Linger
+		}
+	}
+
+	batch := make([]*request, 0, batchSize)
+
+	var lingerTimer <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			complete(batch, ctx.Err())
+
+			publisher.stop()
+
+			publisher.drain(ctx.Err())
+
+			return ctx.Err()
+		case nextRequest, ok := <-publisher.requests:
+			if !ok {
+				publisher.publish(ctx, batch)
+
+				return nil
+			}
+
+			batch = append(batch, nextRequest)
+
+			if len(batch) == 1 {
+				lingerTimer = time.After(linger)
+			}
+
+			if len(batch) >= batchSize {
+				publisher.publish(ctx, batch)
+
+				batch = make([]*request, 0, batchSize)
+				lingerTimer = nil
+			}
+		case <-lingerTimer:
+			publisher.publish(ctx, batch)
+
+			batch = make([]*request, 0, batchSize)
+			lingerTimer = nil
+		}
+	}
+}
+
+func (publisher *Publisher) publish(ctx context.Context, batch []*request) {
+	if len(batch) == 0 {
+		return
+	}
+
+	messages := make([]pubsub.Message, 0, len(batch))
+
+	for _, request := range batch {
+		messages = append(messages, request.message)
+	}
+
+	err := publisher.batchPublisher.PublishBatch(ctx, messages)
+
+	var publishBatchErr *servicebus.PublishBatchError
+
+	if errors.As(err, &publishBatchErr) && len(publishBatchErr.Errs) == len(batch) {
+		for i, request := range batch {
+			request.complete(publishBatchErr.Errs[i])
+		}
+
+		publisher.logger.Error("message batch was not fully published", "error", err)
+
+		return
+	}
+
+	if err != nil {
+		publisher.logger.Error("message batch was not published", "messages", len(batch), "error", err)
+	}
+
+	complete(batch, err)
+}
+
+func (publisher *Publisher) stop() {
+	publisher.stopOnce.Do(func() {
+		close(publisher.stopped)
+	})
+}
+
+// drain fails the buffered messages. The publishers that wait for the buffer return once the publisher is stopped,
+// so no message is accepted after the buffer is closed.
+func (publisher *Publisher) drain(err error) {
+	publisher.mutex.Lock()
+
+	if !publisher.closed {
+		publisher.closed = true
+
+		close(publisher.requests)
+	}
+
+	publisher.mutex.Unlock()
+
+	for request := range publisher.requests {
+		request.complete(err)
+	}
+}
+
+func complete(batch []*request, err error) {
+	for _, request := range batch {
+		request.complete(err)
+	}
+}
+
+func (request *request) complete(err error) {
+	if request.future != nil {
+		request.future.err = err
+
+		close(request.future.done)
+	}
+
+	if request.callbackFunc != nil {
+		request.callbackFunc(request.message, err)
+	}
+}
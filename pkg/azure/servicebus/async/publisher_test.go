@@ -0,0 +1,223 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+type testMessage struct {
+	id int
+}
+
+func (message *testMessage) Discriminator() pubsub.Discriminator {
+	return "Test"
+}
+
+type testBatchPublisher struct {
+	mutex   sync.Mutex
+	batches [][]int
+	errFunc func(messages []pubsub.Message) error
+}
+
+func (batchPublisher *testBatchPublisher) PublishBatch(ctx context.Context, messages []pubsub.Message) error {
+	batchPublisher.mutex.Lock()
+
+	defer batchPublisher.mutex.Unlock()
+
+	ids := make([]int, 0, len(messages))
+
+	for _, message := range messages {
+		ids = append(ids, message.(*testMessage).id)
+	}
+
+	batchPublisher.batches = append(batchPublisher.batches, ids)
+
+	if batchPublisher.errFunc != nil {
+		return batchPublisher.errFunc(messages)
+	}
+
+	return nil
+}
+
+func (batchPublisher *testBatchPublisher) published() [][]int {
+	batchPublisher.mutex.Lock()
+
+	defer batchPublisher.mutex.Unlock()
+
+	return slices.Clone(batchPublisher.batches)
+}
+
+func newTestPublisher(batchPublisher BatchPublisher, options *PublisherOptions) *Publisher {
+	return NewPublisher(batchPublisher, slog.New(slog.NewTextHandler(io.Discard, nil)), options)
+}
+
+func TestPublisherPublishesTheBufferedMessagesInFullBatchesAndOnClose(t *testing.T) {
+	batchPublisher := &testBatchPublisher{}
+
+	publisher := newTestPublisher(batchPublisher, &PublisherOptions{BatchSize: 2, Linger: time.Hour})
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- publisher.Run(context.Background())
+	}()
+
+	futures := make([]*Future, 0, 5)
+
+	for id := 1; id <= 5; id++ {
+		future, err := publisher.Publish(context.Background(), &testMessage{id: id})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		futures = append(futures, future)
+	}
+
+	if err := publisher.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	expectedBatches := [][]int{{1, 2}, {3, 4}, {5}}
+
+	if batches := batchPublisher.published(); !slices.EqualFunc(batches, expectedBatches, slices.Equal) {
+		t.Fatalf("batches are %v, expected %v", batches, expectedBatches)
+	}
+
+	for i, future := range futures {
+		if err := future.Wait(context.Background()); err != nil {
+			t.Fatalf("message %d: %v", i+1, err)
+		}
+	}
+
+	if _, err := publisher.Publish(context.Background(), &testMessage{id: 6}); !errors.Is(err, ErrPublisherClosed) {
+		t.Fatalf("error is %v, expected %v", err, ErrPublisherClosed)
+	}
+}
+
+func TestPublisherPublishesTheBatchOnceTheFirstMessageHasLingered(t *testing.T) {
+	batchPublisher := &testBatchPublisher{}
+
+	publisher := newTestPublisher(batchPublisher, &PublisherOptions{Linger: 10 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	go publisher.Run(ctx)
+
+	future, err := publisher.Publish(context.Background(), &testMessage{id: 1})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	defer waitCancel()
+
+	if err := future.Wait(waitCtx); err != nil {
+		t.Fatal(err)
+	}
+
+	if batches := batchPublisher.published(); !slices.EqualFunc(batches, [][]int{{1}}, slices.Equal) {
+		t.Fatalf("batches are %v, expected [[1]]", batches)
+	}
+}
+
+func TestPublisherCompletesEachMessageWithItsError(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	batchPublisher := &testBatchPublisher{
+		errFunc: func(messages []pubsub.Message) error {
+			return &servicebus.PublishBatchError{
+				Errs: []error{nil, errFailed},
+			}
+		},
+	}
+
+	publisher := newTestPublisher(batchPublisher, &PublisherOptions{BatchSize: 2, Linger: time.Hour})
+
+	go publisher.Run(context.Background())
+
+	future, err := publisher.Publish(context.Background(), &testMessage{id: 1})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	callbackErrs := make(chan error, 1)
+
+	err = publisher.PublishCallback(context.Background(), &testMessage{id: 2}, func(message pubsub.Message, err error) {
+		callbackErrs <- err
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := publisher.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := future.Err(); err != nil {
+		t.Fatalf("error is %v, expected nil", err)
+	}
+
+	if err := <-callbackErrs; !errors.Is(err, errFailed) {
+		t.Fatalf("error is %v, expected %v", err, errFailed)
+	}
+}
+
+func TestPublisherFailsTheBufferedMessagesWhenStopped(t *testing.T) {
+	batchPublisher := &testBatchPublisher{}
+
+	publisher := newTestPublisher(batchPublisher, &PublisherOptions{Linger: time.Hour})
+
+	futures := make([]*Future, 0, 2)
+
+	for id := 1; id <= 2; id++ {
+		future, err := publisher.Publish(context.Background(), &testMessage{id: id})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		futures = append(futures, future)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cancel()
+
+	if err := publisher.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error is %v, expected %v", err, context.Canceled)
+	}
+
+	for i, future := range futures {
+		if err := future.Err(); !errors.Is(err, context.Canceled) {
+			t.Fatalf("message %d: error is %v, expected %v", i+1, err, context.Canceled)
+		}
+	}
+
+	if batches := batchPublisher.published(); len(batches) != 0 {
+		t.Fatalf("batches are %v, expected none", batches)
+	}
+
+	if _, err := publisher.Publish(context.Background(), &testMessage{id: 3}); !errors.Is(err, ErrPublisherClosed) {
+		t.Fatalf("error is %v, expected %v", err, ErrPublisherClosed)
+	}
+}
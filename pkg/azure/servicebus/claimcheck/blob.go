@@ -0,0 +1,159 @@
+package claimcheck
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	ErrBlobNotFound = errors.New("blob not found")
+	ErrInvalidKey   = errors.New("invalid key")
+)
+
+// BlobStore stores the bodies that are too large to be sent in the messages. The implementations must be shared by
+// the publishers and the subscribers, e.g. an Azure Blob Storage container or a mounted file share.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns ErrBlobNotFound if there is no blob with the key.
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Purger is optionally implemented by blob stores that can delete the blobs stored before a given time.
+type Purger interface {
+	Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+// FileBlobStore stores each blob in a file of directory.
+type FileBlobStore struct {
+	directory string
+}
+
+func NewFileBlobStore(directory string) *FileBlobStore {
+	return &FileBlobStore{
+		directory: directory,
+	}
+}
+
+func (blobStore *FileBlobStore) Put(ctx context.Context, key string, data []byte) error {
+	path, err := blobStore.path(key)
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(blobStore.directory, 0o755); err != nil {
+		return err
+	}
+
+	// The blob is written to a temporary file that is renamed once it is complete, so that a partial blob is never read.
+	tempFile, err := os.CreateTemp(blobStore.directory, "."+key+"-*.tmp")
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+
+		return err
+	}
+
+	if err := tempFile.Close(); err != nil {
+		os.Remove(tempFile.Name())
+
+		return err
+	}
+
+	if err := os.Rename(tempFile.Name(), path); err != nil {
+		os.Remove(tempFile.Name())
+
+		return err
+	}
+
+	return nil
+}
+
+func (blobStore *FileBlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := blobStore.path(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+
+	return data, err
+}
+
+func (blobStore *FileBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := blobStore.path(key)
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (blobStore *FileBlobStore) Purge(ctx context.Context, before time.Time) (int, error) {
+	dirEntries, err := os.ReadDir(blobStore.directory)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for _, dirEntry := range dirEntries {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+
+		if dirEntry.IsDir() {
+			continue
+		}
+
+		fileInfo, err := dirEntry.Info()
+
+		if err != nil {
+			continue
+		}
+
+		if !fileInfo.ModTime().Before(before) {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(blobStore.directory, dirEntry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return count, err
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+func (blobStore *FileBlobStore) path(key string) (string, error) {
+	if key == "" || key == "." || key == ".." || filepath.Base(key) != key {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(blobStore.directory, key), nil
+}
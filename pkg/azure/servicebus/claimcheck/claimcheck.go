@@ -0,0 +1,158 @@
+package claimcheck
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/google/uuid"
+	"github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+// ApplicationPropertyClaimCheck is the key of the blob that holds the body of the message. The body of the message is empty.
+const ApplicationPropertyClaimCheck string = "ClaimCheck"
+
+var (
+	ErrInvalidClaimCheck = errors.New("invalid claim check")
+)
+
+type MarshalOptions struct {
+	// Size in bytes above which the body is stored in the blob store, defaults to 192 KB, which leaves room for
+	// the application properties within the 256 KB limit of the standard tier.
+	Threshold int
+}
+
+// NewMarshalMessageFunc stores the bodies larger than the threshold in blobStore and sends the key of the blob instead.
+func NewMarshalMessageFunc(marshalMessageFunc servicebus.MarshalMessageFunc, blobStore BlobStore, options *MarshalOptions) servicebus.MarshalMessageFunc {
+	threshold := 192 * 1024
+
+	if options != nil && options.Threshold > 0 {
+		threshold = options.Threshold
+	}
+
+	return func(message pubsub.Message) (*azservicebus.Message, error) {
+		serviceBusMessage, err := marshalMessageFunc(message)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(serviceBusMessage.Body) <= threshold {
+			return serviceBusMessage, nil
+		}
+
+		key := uuid.NewString()
+
+		// The marshal functions have no context, the blob store applies its own timeouts.
+		if err := blobStore.Put(context.Background(), key, serviceBusMessage.Body); err != nil {
+			return nil, err
+		}
+
+		if serviceBusMessage.ApplicationProperties == nil {
+			serviceBusMessage.ApplicationProperties = map[string]any{}
+		}
+
+		serviceBusMessage.ApplicationProperties[ApplicationPropertyClaimCheck] = key
+		serviceBusMessage.Body = []byte{}
+
+		return serviceBusMessage, nil
+	}
+}
+
+// NewUnmarshalMessageFunc reads the body of the messages with a claim check from blobStore before unmarshaling them.
+// The blobs are not deleted once they are read, because a topic can have several subscriptions and a message can be
+// redelivered, so they are deleted by a Cleaner once they are older than the retention.
+func NewUnmarshalMessageFunc(unmarshalMessageFunc servicebus.UnmarshalMessageFunc, blobStore BlobStore) servicebus.UnmarshalMessageFunc {
+	return func(serviceBusReceivedMessage *azservicebus.ReceivedMessage) (pubsub.Message, error) {
+		value, ok := serviceBusReceivedMessage.ApplicationProperties[ApplicationPropertyClaimCheck]
+
+		if !ok {
+			return unmarshalMessageFunc(serviceBusReceivedMessage)
+		}
+
+		key, ok := value.(string)
+
+		if !ok {
+			return nil, ErrInvalidClaimCheck
+		}
+
+		body, err := blobStore.Get(context.Background(), key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// The received message is not modified, so that it is settled as it was received.
+		rehydratedServiceBusReceivedMessage := *serviceBusReceivedMessage
+
+		rehydratedServiceBusReceivedMessage.Body = body
+
+		return unmarshalMessageFunc(&rehydratedServiceBusReceivedMessage)
+	}
+}
+
+type CleanerOptions struct {
+	// Time interval to purge the blob store, defaults to 1 hour.
+	Interval time.Duration
+	// Time the blobs are kept, defaults to 14 days. Must be longer than the time to live of the messages, so that
+	// the blobs are not deleted before their messages are received.
+	Retention time.Duration
+}
+
+// Cleaner deletes the blobs once they are older than the retention.
+type Cleaner struct {
+	purger  Purger
+	logger  *slog.Logger
+	options *CleanerOptions
+}
+
+func NewCleaner(purger Purger, logger *slog.Logger, options *CleanerOptions) *Cleaner {
+	return &Cleaner{
+		purger:  purger,
+		logger:  logger,
+		options: options,
+	}
+}
+
+func (cleaner *Cleaner) Run(ctx context.Context) error {
+	interval := 1 * time.Hour
+	retention := 14 * 24 * time.Hour
+
+	if cleaner.options != nil {
+		if cleaner.options.Interval > 0 {
+			interval = cleaner.options.Interval
+		}
+
+		if cleaner.options.Retention > 0 {
+			retention = cleaner.options.Retention
+		}
+	}
+
+	tick := time.Tick(interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick:
+			count, err := cleaner.purger.Purge(ctx, time.Now().Add(-retention))
+
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				cleaner.logger.Error("claim check blobs were not purged", "error", err)
+
+				continue
+			}
+
+			if count > 0 {
+				cleaner.logger.Info("claim check blobs were purged", "count", count)
+			}
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package claimcheck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+type testMessage struct {
+	body []byte
+}
+
+func (message *testMessage) Discriminator() pubsub.Discriminator {
+	return "Test"
+}
+
+func marshalTestMessage(message pubsub.Message) (*azservicebus.Message, error) {
+	return &azservicebus.Message{
+		Body: message.(*testMessage).body,
+	}, nil
+}
+
+func unmarshalTestMessage(serviceBusReceivedMessage *azservicebus.ReceivedMessage) (pubsub.Message, error) {
+	return &testMessage{body: serviceBusReceivedMessage.Body}, nil
+}
+
+type testPurger struct {
+	befores chan time.Time
+}
+
+func (purger *testPurger) Purge(ctx context.Context, before time.Time) (int, error) {
+	select {
+	case purger.befores <- before:
+	default:
+	}
+
+	return 0, nil
+}
+
+func TestMarshalMessageFuncStoresTheBodiesAboveTheThreshold(t *testing.T) {
+	blobStore := NewFileBlobStore(t.TempDir())
+
+	marshalMessageFunc := NewMarshalMessageFunc(marshalTestMessage, blobStore, &MarshalOptions{Threshold: 4})
+
+	serviceBusMessage, err := marshalMessageFunc(&testMessage{body: []byte("1234")})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(serviceBusMessage.Body) != "1234" || serviceBusMessage.ApplicationProperties[ApplicationPropertyClaimCheck] != nil {
+		t.Fatalf("message at the threshold has a claim check: %+v", serviceBusMessage)
+	}
+
+	serviceBusMessage, err = marshalMessageFunc(&testMessage{body: []byte("12345")})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(serviceBusMessage.Body) != 0 {
+		t.Fatalf("body is %q, expected an empty body", serviceBusMessage.Body)
+	}
+
+	key, ok := serviceBusMessage.ApplicationProperties[ApplicationPropertyClaimCheck].(string)
+
+	if !ok {
+		t.Fatal("message above the threshold has no claim check")
+	}
+
+	body, err := blobStore.Get(context.Background(), key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(body) != "12345" {
+		t.Fatalf("blob is %q, expected %q", body, "12345")
+	}
+}
+
+func TestUnmarshalMessageFuncReadsTheClaimedBody(t *testing.T) {
+	blobStore := NewFileBlobStore(t.TempDir())
+
+	if err := blobStore.Put(context.Background(), "key", []byte("body")); err != nil {
+		t.Fatal(err)
+	}
+
+	unmarshalMessageFunc := NewUnmarshalMessageFunc(unmarshalTestMessage, blobStore)
+
+	serviceBusReceivedMessage := &azservicebus.ReceivedMessage{
+		Body:                  []byte{},
+		ApplicationProperties: map[string]any{ApplicationPropertyClaimCheck: "key"},
+	}
+
+	message, err := unmarshalMessageFunc(serviceBusReceivedMessage)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body := message.(*testMessage).body; string(body) != "body" {
+		t.Fatalf("body is %q, expected %q", body, "body")
+	}
+
+	// The received message is settled as it was received.
+	if len(serviceBusReceivedMessage.Body) != 0 {
+		t.Fatal("received message was modified")
+	}
+
+	message, err = unmarshalMessageFunc(&azservicebus.ReceivedMessage{Body: []byte("inline")})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body := message.(*testMessage).body; string(body) != "inline" {
+		t.Fatalf("body is %q, expected %q", body, "inline")
+	}
+
+	tests := []struct {
+		name     string
+		value    any
+		expected error
+	}{
+		{"missing blob", "missing", ErrBlobNotFound},
+		{"key that is not a string", 1, ErrInvalidClaimCheck},
+		{"key that is a path", "../key", ErrInvalidKey},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := unmarshalMessageFunc(&azservicebus.ReceivedMessage{
+				ApplicationProperties: map[string]any{ApplicationPropertyClaimCheck: test.value},
+			})
+
+			if !errors.Is(err, test.expected) {
+				t.Fatalf("error is %v, expected %v", err, test.expected)
+			}
+		})
+	}
+}
+
+func TestFileBlobStore(t *testing.T) {
+	ctx := context.Background()
+
+	blobStore := NewFileBlobStore(filepath.Join(t.TempDir(), "blobs"))
+
+	if _, err := blobStore.Get(ctx, "key"); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("error is %v, expected %v", err, ErrBlobNotFound)
+	}
+
+	if err := blobStore.Put(ctx, "key", []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := blobStore.Get(ctx, "key")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, []byte("data")) {
+		t.Fatalf("blob is %q, expected %q", data, "data")
+	}
+
+	if err := blobStore.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := blobStore.Delete(ctx, "key"); err != nil {
+		t.Fatalf("deleting a missing blob failed: %v", err)
+	}
+
+	for _, key := range []string{"", ".", "..", "a/b", "../key"} {
+		if err := blobStore.Put(ctx, key, []byte("data")); !errors.Is(err, ErrInvalidKey) {
+			t.Fatalf("%q: error is %v, expected %v", key, err, ErrInvalidKey)
+		}
+	}
+}
+
+func TestFileBlobStorePurgesTheBlobsBeforeTheTime(t *testing.T) {
+	ctx := context.Background()
+
+	directory := t.TempDir()
+
+	blobStore := NewFileBlobStore(directory)
+
+	for _, key := range []string{"old", "new"} {
+		if err := blobStore.Put(ctx, key, []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldTime := time.Now().Add(-2 * time.Hour)
+
+	if err := os.Chtimes(filepath.Join(directory, "old"), oldTime, oldTime); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := blobStore.Purge(ctx, time.Now().Add(-time.Hour))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 1 {
+		t.Fatalf("count is %d, expected 1", count)
+	}
+
+	if _, err := blobStore.Get(ctx, "old"); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("error is %v, expected %v", err, ErrBlobNotFound)
+	}
+
+	if _, err := blobStore.Get(ctx, "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	if count, err := NewFileBlobStore(filepath.Join(directory, "missing")).Purge(ctx, time.Now()); err != nil || count != 0 {
+		t.Fatalf("count is %d and error is %v for a missing directory", count, err)
+	}
+}
+
+func TestCleanerPurgesTheBlobsOlderThanTheRetention(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	purger := &testPurger{befores: make(chan time.Time, 1)}
+
+	retention := 24 * time.Hour
+
+	cleaner := NewCleaner(purger, slog.New(slog.NewTextHandler(io.Discard, nil)), &CleanerOptions{
+		Interval:  10 * time.Millisecond,
+		Retention: retention,
+	})
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- cleaner.Run(ctx)
+	}()
+
+	select {
+	case before := <-purger.befores:
+		if age := time.Since(before); age < retention || age > retention+time.Minute {
+			t.Fatalf("blobs are purged before %v, expected %v ago", before, retention)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("blobs were not purged")
+	}
+
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("error is %v, expected %v", err, context.Canceled)
+	}
+}
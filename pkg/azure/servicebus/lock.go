@@ -0,0 +1,67 @@
+package servicebus
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+)
+
+// WaitRenewingMessageLocks waits for the duration and renews the locks of the messages in the meantime, so that they do not expire while waiting.
+func WaitRenewingMessageLocks(ctx context.Context, receiver *azservicebus.Receiver, serviceBusReceivedMessages []*azservicebus.ReceivedMessage, duration time.Duration, logger *slog.Logger) error {
+	deadline := time.Now().Add(duration)
+
+	lockedServiceBusReceivedMessages := make([]*azservicebus.ReceivedMessage, 0, len(serviceBusReceivedMessages))
+
+	for _, serviceBusReceivedMessage := range serviceBusReceivedMessages {
+		if serviceBusReceivedMessage.LockedUntil != nil {
+			lockedServiceBusReceivedMessages = append(lockedServiceBusReceivedMessages, serviceBusReceivedMessage)
+		}
+	}
+
+	for {
+		wait := time.Until(deadline)
+
+		if wait <= 0 {
+			return nil
+		}
+
+		renewAfter := wait
+
+		for _, serviceBusReceivedMessage := range lockedServiceBusReceivedMessages {
+			renewAfter = min(renewAfter, max(time.Until(*serviceBusReceivedMessage.LockedUntil)/2, time.Second))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(renewAfter):
+		}
+
+		if renewAfter == wait {
+			return nil
+		}
+
+		stillLockedServiceBusReceivedMessages := lockedServiceBusReceivedMessages[:0]
+
+		for _, serviceBusReceivedMessage := range lockedServiceBusReceivedMessages {
+			if err := receiver.RenewMessageLock(ctx, serviceBusReceivedMessage, nil); err != nil {
+				var serviceBusErr *azservicebus.Error
+
+				if errors.As(err, &serviceBusErr) && serviceBusErr.Code == azservicebus.CodeLockLost {
+					logger.Warn("message lock was lost while trying to renew the message lock")
+
+					continue
+				}
+
+				return err
+			}
+
+			stillLockedServiceBusReceivedMessages = append(stillLockedServiceBusReceivedMessages, serviceBusReceivedMessage)
+		}
+
+		lockedServiceBusReceivedMessages = stillLockedServiceBusReceivedMessages
+	}
+}
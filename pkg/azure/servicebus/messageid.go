@@ -0,0 +1,130 @@
+package servicebus
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus/admin"
+	"github.com/google/uuid"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+var (
+	// ErrNoMessageKey is the error of the message ID functions for the messages that cannot be identified, instead of
+	// a random message ID that the duplicate detection cannot match when the message is published again.
+	ErrNoMessageKey = errors.New("no message key")
+)
+
+// GetMessageIDFunc returns the message ID used by the duplicate detection of the topic. An empty message ID leaves
+// the message ID to the SDK, which generates a random one.
+type GetMessageIDFunc func(message pubsub.Message) (string, error)
+
+// GetMessageKeyFunc returns the parts of the message that identify it, e.g. the type, code, operation and timestamp of
+// an event, so that the same event gets the same message ID when it is published again. No parts mean that the message
+// cannot be identified.
+type GetMessageKeyFunc func(message pubsub.Message) ([]string, error)
+
+// NewMarshalMessageIDFunc assigns the message ID returned by getMessageIDFunc to messages that do not have one yet,
+// so that the message IDs supplied by the caller take precedence.
+func NewMarshalMessageIDFunc(marshalMessageFunc MarshalMessageFunc, getMessageIDFunc GetMessageIDFunc) MarshalMessageFunc {
+	return func(message pubsub.Message) (*azservicebus.Message, error) {
+		serviceBusMessage, err := marshalMessageFunc(message)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if serviceBusMessage.MessageID != nil {
+			return serviceBusMessage, nil
+		}
+
+		messageID, err := getMessageIDFunc(message)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if messageID != "" {
+			serviceBusMessage.MessageID = &messageID
+		}
+
+		return serviceBusMessage, nil
+	}
+}
+
+// NewHashMessageIDFunc returns the hex encoded SHA-256 hash of the message key as message ID. It returns an error wrapping
+// ErrNoMessageKey for the messages without message key.
+func NewHashMessageIDFunc(getMessageKeyFunc GetMessageKeyFunc) GetMessageIDFunc {
+	return func(message pubsub.Message) (string, error) {
+		key, err := getMessageKey(getMessageKeyFunc, message)
+
+		if err != nil {
+			return "", err
+		}
+
+		hash := sha256.Sum256([]byte(key))
+
+		return hex.EncodeToString(hash[:]), nil
+	}
+}
+
+// NewUUIDMessageIDFunc returns the UUIDv5 of the message key in namespace as message ID. It returns an error wrapping
+// ErrNoMessageKey for the messages without message key.
+func NewUUIDMessageIDFunc(namespace uuid.UUID, getMessageKeyFunc GetMessageKeyFunc) GetMessageIDFunc {
+	return func(message pubsub.Message) (string, error) {
+		key, err := getMessageKey(getMessageKeyFunc, message)
+
+		if err != nil {
+			return "", err
+		}
+
+		return uuid.NewSHA1(namespace, []byte(key)).String(), nil
+	}
+}
+
+func getMessageKey(getMessageKeyFunc GetMessageKeyFunc, message pubsub.Message) (string, error) {
+	parts, err := getMessageKeyFunc(message)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(parts) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrNoMessageKey, message.Discriminator())
+	}
+
+	// The separator cannot be confused with the parts, unlike e.g. "~" that may be part of a code.
+	return strings.Join(parts, "\x00"), nil
+}
+
+type TopicGetter interface {
+	GetTopic(ctx context.Context, topicName string, options *admin.GetTopicOptions) (*admin.GetTopicResponse, error)
+}
+
+// CheckDuplicateDetection warns when the duplicate detection of the topic is disabled, because the deterministic message
+// IDs have no effect in that case. It reports whether the duplicate detection is enabled. Requires the Manage claim.
+func CheckDuplicateDetection(ctx context.Context, topicGetter TopicGetter, topicName string, logger *slog.Logger) (bool, error) {
+	topicResponse, err := topicGetter.GetTopic(ctx, topicName, nil)
+
+	if err != nil {
+		return false, err
+	}
+
+	if topicResponse == nil {
+		return false, ErrTopicNotFound
+	}
+
+	if topicResponse.RequiresDuplicateDetection == nil || !*topicResponse.RequiresDuplicateDetection {
+		logger.Warn("duplicate detection is disabled on the topic, so messages published again are delivered again", "topic", topicName)
+
+		return false, nil
+	}
+
+	return true, nil
+}
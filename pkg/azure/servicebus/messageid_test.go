@@ -0,0 +1,105 @@
+package servicebus_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scaleforce/synchronization-for-go/internal/azure/servicebus/util"
+	envelopemessage "github.com/scaleforce/synchronization-for-go/internal/message/envelope"
+	"github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event/masterdata"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+type testMessage struct {
+	key []string
+}
+
+func (message *testMessage) Discriminator() pubsub.Discriminator {
+	return "Test"
+}
+
+func getTestMessageKey(message pubsub.Message) ([]string, error) {
+	return message.(*testMessage).key, nil
+}
+
+func TestMessageIDFuncs(t *testing.T) {
+	getMessageIDFuncs := map[string]servicebus.GetMessageIDFunc{
+		"hash": servicebus.NewHashMessageIDFunc(getTestMessageKey),
+		"uuid": servicebus.NewUUIDMessageIDFunc(uuid.NameSpaceOID, getTestMessageKey),
+	}
+
+	for name, getMessageIDFunc := range getMessageIDFuncs {
+		t.Run(name, func(t *testing.T) {
+			messageID1, err := getMessageIDFunc(&testMessage{key: []string{"a", "b"}})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			messageID2, err := getMessageIDFunc(&testMessage{key: []string{"a", "b"}})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if messageID1 == "" || messageID1 != messageID2 {
+				t.Fatalf("message IDs are %q and %q, expected the same message ID", messageID1, messageID2)
+			}
+
+			// The separator keeps the parts apart.
+			messageID3, err := getMessageIDFunc(&testMessage{key: []string{"ab"}})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if messageID3 == messageID1 {
+				t.Fatal("message IDs of different keys are the same")
+			}
+
+			if _, err := getMessageIDFunc(&testMessage{}); !errors.Is(err, servicebus.ErrNoMessageKey) {
+				t.Fatalf("error is %v, expected %v", err, servicebus.ErrNoMessageKey)
+			}
+		})
+	}
+}
+
+func TestMessageIDsOfEventsInTheSameSecondAreDifferent(t *testing.T) {
+	getMessageID := servicebus.NewHashMessageIDFunc(util.GetMessageKey)
+
+	timestamp := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	newCityEvent := func(name string, timestamp time.Time) pubsub.Message {
+		return envelopemessage.NewEnvelope(masterdata.NewCityEventWithOptions(event.OperationAddOrSet, &masterdata.CityData{Code: "1", Name: name}, event.WithTenantGroupName("group"), event.WithTimestamp(timestamp)))
+	}
+
+	messageID1, err := getMessageID(newCityEvent("a", timestamp))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messageID2, err := getMessageID(newCityEvent("b", timestamp.Add(100*time.Millisecond)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if messageID1 == messageID2 {
+		t.Fatal("message IDs of different events in the same second are the same")
+	}
+
+	messageID3, err := getMessageID(newCityEvent("a", timestamp))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if messageID3 != messageID1 {
+		t.Fatalf("message IDs are %q and %q, expected the same message ID", messageID1, messageID3)
+	}
+}
@@ -5,29 +5,66 @@ import (
 	"errors"
 	"hash/fnv"
 	"log/slog"
+	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
 	"github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/filter"
 	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+	"github.com/scaleforce/synchronization-for-go/pkg/ratelimit"
+	"github.com/scaleforce/synchronization-for-go/pkg/validation"
 )
 
 type GetPartitionNameFunc func(message pubsub.Message) (string, error)
 
+// GetLaneFunc returns the index of the lane of the message in SubscriberOptions.Lanes.
+type GetLaneFunc func(message pubsub.Message) (int, error)
+
+// Lane is a priority class with its own partitions. The consumers take messages from the lanes in proportion to their weights,
+// so that messages of a lane are not stuck behind the messages of another lane. The order of messages with the same partition
+// name is kept within a lane only. A consumer takes at most Weight messages of a lane at once, so the weights also bound the batches
+// of the batch handlers, e.g. weights 10 and 100 rather than 1 and 10.
+type Lane struct {
+	Weight int
+}
+
 type SubscriberOptions struct {
 	Interval        time.Duration
 	MessagesLimit   int
 	PartitionsCount int
 	PartitionsLimit int
 	PartitionsDrain bool
+	// Must match the receive mode of the receiver, which NewSubscriberForSubscription ensures by creating the receiver in this mode.
+	// In ReceiveModeReceiveAndDelete the messages are not settled, and the messages that fail are dropped instead of being abandoned
+	// or dead lettered.
+	ReceiveMode azservicebus.ReceiveMode
+	// Interval of the renewal of the locks of the messages in flight, 10 seconds by default. The locks that expire within
+	// two intervals are renewed, while the messages wait in the partitions and while they are handled.
+	LockRenewalInterval time.Duration
+	// Upper bound on messages received but not yet settled, 0 means unbounded.
+	InFlightMessagesLimit int
+	// Upper bound on the total body size in bytes of messages received but not yet settled, 0 means unbounded.
+	InFlightBytesLimit int64
+	// Optional rate limit of the handled messages, shared by all partitions. The message locks are renewed while waiting.
+	Limiter *ratelimit.Limiter
+	// Optional priority lanes, used together with GetLaneFunc.
+	Lanes       []*Lane
+	GetLaneFunc GetLaneFunc
+	Hooks       *pubsub.Hooks
+	// Optional filter of the messages. The messages that are filtered out are completed before they are enqueued.
+	Filter *filter.Filter
 }
 
 type partitionMessage struct {
 	message                   pubsub.Message
 	serviceBusReceivedMessage *azservicebus.ReceivedMessage
+	// Expiry of the lock, zero in receive-and-delete mode. Guarded by Subscriber.inFlightMutex, because the locks are renewed
+	// concurrently with the consumers.
+	lockedUntil time.Time
 }
 
 type Subscriber struct {
@@ -37,10 +74,50 @@ type Subscriber struct {
 	getPartitionNameFunc GetPartitionNameFunc
 	logger               *slog.Logger
 	options              *SubscriberOptions
+	inFlightMessages     atomic.Int64
+	inFlightBytes        atomic.Int64
+	lifecycle            *pubsub.Lifecycle
+	paused               atomic.Bool
+	inFlightMutex        sync.Mutex
+	inFlight             map[*partitionMessage]struct{}
+	// Signaled when a message is released, to wake up the producer waiting for the in-flight bytes limit.
+	released chan struct{}
 }
 
 func NewSubscriber(receiver *azservicebus.Receiver, dispatcher *pubsub.Dispatcher, unmarshalMessageFunc servicebus.UnmarshalMessageFunc, getPartitionNameFunc GetPartitionNameFunc, logger *slog.Logger, options *SubscriberOptions) *Subscriber {
-	return &Subscriber{receiver: receiver, dispatcher: dispatcher, unmarshalMessageFunc: unmarshalMessageFunc, getPartitionNameFunc: getPartitionNameFunc, logger: logger, options: options}
+	var hooks *pubsub.Hooks
+
+	if options != nil {
+		hooks = options.Hooks
+	}
+
+	return &Subscriber{receiver: receiver, dispatcher: dispatcher, unmarshalMessageFunc: unmarshalMessageFunc, getPartitionNameFunc: getPartitionNameFunc, logger: logger, options: options, lifecycle: pubsub.NewLifecycle(hooks), inFlight: map[*partitionMessage]struct{}{}, released: make(chan struct{}, 1)}
+}
+
+// NewSubscriberForSubscription creates the receiver of the subscription in the receive mode of options, so that they match.
+// The receiver is closed by Close.
+func NewSubscriberForSubscription(client *azservicebus.Client, topicName string, subscriptionName string, dispatcher *pubsub.Dispatcher, unmarshalMessageFunc servicebus.UnmarshalMessageFunc, getPartitionNameFunc GetPartitionNameFunc, logger *slog.Logger, options *SubscriberOptions) (*Subscriber, error) {
+	receiverOptions := &azservicebus.ReceiverOptions{}
+
+	if options != nil {
+		receiverOptions.ReceiveMode = options.ReceiveMode
+	}
+
+	receiver, err := client.NewReceiverForSubscription(topicName, subscriptionName, receiverOptions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSubscriber(receiver, dispatcher, unmarshalMessageFunc, getPartitionNameFunc, logger, options), nil
+}
+
+func (subscriber *Subscriber) Close(ctx context.Context) error {
+	return subscriber.receiver.Close(ctx)
+}
+
+func (subscriber *Subscriber) Health() pubsub.HealthReport {
+	return subscriber.lifecycle.Report()
 }
 
 type RunError struct {
@@ -64,7 +141,35 @@ func (runErr *RunError) Error() string {
 	return errMsg
 }
 
+// Pause stops receiving new messages without stopping Run. The messages that were already received are still handled,
+// and their locks are still renewed.
+func (subscriber *Subscriber) Pause() {
+	subscriber.paused.Store(true)
+
+	subscriber.lifecycle.Paused(true)
+
+	subscriber.logger.Info("subscriber was paused")
+}
+
+func (subscriber *Subscriber) Resume() {
+	subscriber.paused.Store(false)
+
+	subscriber.lifecycle.Paused(false)
+
+	subscriber.logger.Info("subscriber was resumed")
+}
+
 func (subscriber *Subscriber) Run(ctx context.Context) error {
+	subscriber.lifecycle.Started()
+
+	err := subscriber.run(ctx)
+
+	subscriber.lifecycle.Stopped(err)
+
+	return err
+}
+
+func (subscriber *Subscriber) run(ctx context.Context) error {
 	partitionsCount := 1
 	partitionsLimit := 1
 	partitionsDrain := false
@@ -81,12 +186,35 @@ func (subscriber *Subscriber) Run(ctx context.Context) error {
 		partitionsDrain = subscriber.options.PartitionsDrain
 	}
 
-	partitions := make([]chan *partitionMessage, 0, partitionsCount)
+	subscriber.inFlightMessages.Store(0)
+	subscriber.inFlightBytes.Store(0)
+
+	subscriber.inFlightMutex.Lock()
+	clear(subscriber.inFlight)
+	subscriber.inFlightMutex.Unlock()
+
+	weights := []int{1}
+
+	if subscriber.options != nil && len(subscriber.options.Lanes) != 0 && subscriber.options.GetLaneFunc != nil {
+		weights = make([]int, 0, len(subscriber.options.Lanes))
+
+		for _, lane := range subscriber.options.Lanes {
+			weights = append(weights, max(lane.Weight, 1))
+		}
+	}
+
+	lanes := make([][]chan *partitionMessage, 0, len(weights))
 
-	for range partitionsCount {
-		partition := make(chan *partitionMessage, partitionsLimit)
+	for range weights {
+		partitions := make([]chan *partitionMessage, 0, partitionsCount)
 
-		partitions = append(partitions, partition)
+		for range partitionsCount {
+			partition := make(chan *partitionMessage, partitionsLimit)
+
+			partitions = append(partitions, partition)
+		}
+
+		lanes = append(lanes, partitions)
 	}
 
 	var consumerCtx context.Context
@@ -97,33 +225,57 @@ func (subscriber *Subscriber) Run(ctx context.Context) error {
 		consumerCtx = ctx
 	}
 
-	consumerErrs := make(chan error, len(partitions))
+	// The locks are renewed until the consumers are done, also while they drain the partitions.
+	renewalCtx, cancelRenewal := context.WithCancel(consumerCtx)
+
+	renewalDone := make(chan struct{})
+
+	go func() {
+		defer close(renewalDone)
+
+		subscriber.renewLocks(renewalCtx)
+	}()
+
+	consumerErrs := make(chan error, partitionsCount)
 
 	consumerGroup := sync.WaitGroup{}
 
-	consumerGroup.Add(len(partitions))
+	consumerGroup.Add(partitionsCount)
+
+	// Each consumer takes the messages from the partition with its index in every lane.
+	for partitionIndex := range partitionsCount {
+		partitions := make([]chan *partitionMessage, 0, len(lanes))
+
+		for _, lane := range lanes {
+			partitions = append(partitions, lane[partitionIndex])
+		}
 
-	for _, partition := range partitions {
 		go func() {
 			defer consumerGroup.Done()
 
-			consumerErrs <- subscriber.consume(consumerCtx, partition)
+			consumerErrs <- subscriber.consume(consumerCtx, partitions, weights)
 		}()
 	}
 
-	producerErr := subscriber.produce(ctx, partitions)
+	producerErr := subscriber.produce(ctx, lanes)
 
 	if partitionsDrain {
-		for _, partition := range partitions {
-			close(partition)
+		for _, partitions := range lanes {
+			for _, partition := range partitions {
+				close(partition)
+			}
 		}
 	}
 
 	consumerGroup.Wait()
 
+	cancelRenewal()
+
+	<-renewalDone
+
 	close(consumerErrs)
 
-	errs := make([]error, 0, len(partitions))
+	errs := make([]error, 0, partitionsCount)
 
 	for consumerErr := range consumerErrs {
 		if consumerErr != nil {
@@ -142,10 +294,31 @@ func (subscriber *Subscriber) Run(ctx context.Context) error {
 }
 
 var (
-// errPartitionLimit = errors.New("partition limit")
+	errInvalidLane = errors.New("invalid lane")
 )
 
-func (subscriber *Subscriber) enqueue(ctx context.Context, partitions []chan *partitionMessage, partitionMessage *partitionMessage) error {
+// enqueue adds the message to its partition, and waits while the partition is full. The message is in flight while it waits, so its
+// lock is renewed by renewLocks, and the messages are not abandoned, which would count as a delivery. The producer reports a skipped
+// receive at each interval, so that it is still live while it waits.
+func (subscriber *Subscriber) enqueue(ctx context.Context, lanes [][]chan *partitionMessage, partitionMessage *partitionMessage, interval time.Duration) error {
+	laneIndex := 0
+
+	if len(lanes) > 1 {
+		var err error
+
+		laneIndex, err = subscriber.options.GetLaneFunc(partitionMessage.message)
+
+		if err != nil {
+			return err
+		}
+
+		if laneIndex < 0 || laneIndex >= len(lanes) {
+			return errInvalidLane
+		}
+	}
+
+	partitions := lanes[laneIndex]
+
 	if len(partitions) == 0 {
 		return nil
 	}
@@ -164,20 +337,215 @@ func (subscriber *Subscriber) enqueue(ctx context.Context, partitions []chan *pa
 
 	partitionIndex := int(partitionHash % uint32(len(partitions)))
 
+	partition := partitions[partitionIndex]
+
 	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case partitions[partitionIndex] <- partitionMessage:
-		// default:
-		// 	return errPartitionLimit
+	case partition <- partitionMessage:
+		return nil
+	default:
+	}
+
+	subscriber.logger.Debug("message waits for its partition because it is full")
+
+	ticker := time.NewTicker(interval)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case partition <- partitionMessage:
+			return nil
+		case <-ticker.C:
+			subscriber.lifecycle.Skipped()
+		}
+	}
+}
+
+// receiveLimit returns how many messages can be received without exceeding the free capacity of the partitions or the in-flight limits.
+// The free capacity of a lane is the minimum free capacity of its partitions, because all the received messages can have the same
+// partition name. The limit is the free capacity of the least busy lane, so that a full bulk lane does not stop receiving for the other
+// lanes. The messages that land on a full partition wait for it in enqueue.
+func (subscriber *Subscriber) receiveLimit(lanes [][]chan *partitionMessage, messagesLimit int, inFlightMessagesLimit int, inFlightBytesLimit int64) int {
+	capacity := 0
+
+	for _, partitions := range lanes {
+		laneCapacity := messagesLimit
+
+		for _, partition := range partitions {
+			laneCapacity = min(laneCapacity, cap(partition)-len(partition))
+		}
+
+		capacity = max(capacity, laneCapacity)
+	}
+
+	limit := min(messagesLimit, capacity)
+
+	if inFlightMessagesLimit > 0 {
+		limit = min(limit, inFlightMessagesLimit-int(subscriber.inFlightMessages.Load()))
+	}
+
+	if inFlightBytesLimit > 0 && subscriber.inFlightBytes.Load() >= inFlightBytesLimit {
+		return 0
+	}
+
+	return max(limit, 0)
+}
+
+// overBytesLimit reports whether the messages in flight, including the acquired message, exceed the in-flight bytes limit. The size of
+// the messages is only known once they are received, so the limit is checked for each message. A message is always accepted when no
+// other message is in flight, so that a message larger than the limit does not stop the subscriber.
+func (subscriber *Subscriber) overBytesLimit(inFlightBytesLimit int64) bool {
+	if inFlightBytesLimit <= 0 || subscriber.inFlightMessages.Load() <= 1 {
+		return false
+	}
+
+	return subscriber.inFlightBytes.Load() > inFlightBytesLimit
+}
+
+// waitForBytes waits while the acquired message exceeds the in-flight bytes limit, until other messages are released. The message is
+// in flight while it waits, so its lock is renewed by renewLocks.
+func (subscriber *Subscriber) waitForBytes(ctx context.Context, inFlightBytesLimit int64, interval time.Duration) error {
+	if !subscriber.overBytesLimit(inFlightBytesLimit) {
+		return nil
+	}
+
+	subscriber.logger.Debug("message waits for the in-flight bytes limit")
+
+	ticker := time.NewTicker(interval)
+
+	defer ticker.Stop()
+
+	for subscriber.overBytesLimit(inFlightBytesLimit) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-subscriber.released:
+		case <-ticker.C:
+			subscriber.lifecycle.Skipped()
+		}
 	}
 
 	return nil
 }
 
-func (subscriber *Subscriber) produce(ctx context.Context, partitions []chan *partitionMessage) error {
+func (subscriber *Subscriber) saturation(lanes [][]chan *partitionMessage) float64 {
+	buffered := 0
+	capacity := 0
+
+	for _, partitions := range lanes {
+		for _, partition := range partitions {
+			buffered += len(partition)
+			capacity += cap(partition)
+		}
+	}
+
+	if capacity == 0 {
+		return 0
+	}
+
+	return float64(buffered) / float64(capacity)
+}
+
+func (subscriber *Subscriber) acquire(partitionMessage *partitionMessage) {
+	subscriber.inFlightMessages.Add(1)
+	subscriber.inFlightBytes.Add(int64(len(partitionMessage.serviceBusReceivedMessage.Body)))
+
+	subscriber.inFlightMutex.Lock()
+
+	if partitionMessage.serviceBusReceivedMessage.LockedUntil != nil {
+		partitionMessage.lockedUntil = *partitionMessage.serviceBusReceivedMessage.LockedUntil
+	}
+
+	subscriber.inFlight[partitionMessage] = struct{}{}
+
+	subscriber.inFlightMutex.Unlock()
+}
+
+func (subscriber *Subscriber) release(partitionMessage *partitionMessage) {
+	subscriber.inFlightMessages.Add(-1)
+	subscriber.inFlightBytes.Add(-int64(len(partitionMessage.serviceBusReceivedMessage.Body)))
+
+	subscriber.inFlightMutex.Lock()
+	delete(subscriber.inFlight, partitionMessage)
+	subscriber.inFlightMutex.Unlock()
+
+	select {
+	case subscriber.released <- struct{}{}:
+	default:
+	}
+}
+
+// renewLocks renews the locks of the messages in flight until ctx is done, independently of the producer, so that the locks
+// do not expire while the messages wait in the partitions or are handled, also while the subscriber is paused.
+func (subscriber *Subscriber) renewLocks(ctx context.Context) {
+	if subscriber.receiveAndDelete() {
+		return
+	}
+
+	interval := 10 * time.Second
+
+	if subscriber.options != nil && subscriber.options.LockRenewalInterval > 0 {
+		interval = subscriber.options.LockRenewalInterval
+	}
+
+	ticker := time.NewTicker(interval)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			subscriber.renew(ctx, time.Now().Add(2*interval))
+		}
+	}
+}
+
+// renew renews the locks of the messages in flight that expire before renewBefore.
+func (subscriber *Subscriber) renew(ctx context.Context, renewBefore time.Time) {
+	for _, partitionMessage := range subscriber.expiring(renewBefore) {
+		// The message can be settled by a consumer in the meantime, in which case the renewal fails.
+		if err := subscriber.receiver.RenewMessageLock(ctx, partitionMessage.serviceBusReceivedMessage, nil); err != nil {
+			subscriber.logger.Debug("message lock could not be renewed", "error", err)
+
+			continue
+		}
+
+		subscriber.inFlightMutex.Lock()
+
+		if partitionMessage.serviceBusReceivedMessage.LockedUntil != nil {
+			partitionMessage.lockedUntil = *partitionMessage.serviceBusReceivedMessage.LockedUntil
+		}
+
+		subscriber.inFlightMutex.Unlock()
+	}
+}
+
+// expiring returns the messages in flight whose locks expire before renewBefore.
+func (subscriber *Subscriber) expiring(renewBefore time.Time) []*partitionMessage {
+	subscriber.inFlightMutex.Lock()
+
+	defer subscriber.inFlightMutex.Unlock()
+
+	partitionMessages := make([]*partitionMessage, 0, len(subscriber.inFlight))
+
+	for partitionMessage := range subscriber.inFlight {
+		if !partitionMessage.lockedUntil.IsZero() && partitionMessage.lockedUntil.Before(renewBefore) {
+			partitionMessages = append(partitionMessages, partitionMessage)
+		}
+	}
+
+	return partitionMessages
+}
+
+func (subscriber *Subscriber) produce(ctx context.Context, lanes [][]chan *partitionMessage) error {
 	interval := 1 * time.Minute
 	messagesLimit := 1
+	inFlightMessagesLimit := 0
+	inFlightBytesLimit := int64(0)
 
 	if subscriber.options != nil {
 		if subscriber.options.Interval > 0 {
@@ -187,6 +555,14 @@ func (subscriber *Subscriber) produce(ctx context.Context, partitions []chan *pa
 		if subscriber.options.MessagesLimit > 0 {
 			messagesLimit = subscriber.options.MessagesLimit
 		}
+
+		if subscriber.options.InFlightMessagesLimit > 0 {
+			inFlightMessagesLimit = subscriber.options.InFlightMessagesLimit
+		}
+
+		if subscriber.options.InFlightBytesLimit > 0 {
+			inFlightBytesLimit = subscriber.options.InFlightBytesLimit
+		}
 	}
 
 	for {
@@ -194,35 +570,43 @@ func (subscriber *Subscriber) produce(ctx context.Context, partitions []chan *pa
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-time.Tick(interval):
-			serviceBusReceivedMessages, err := subscriber.receiver.ReceiveMessages(ctx, messagesLimit, nil)
+			subscriber.lifecycle.Saturated(subscriber.saturation(lanes))
+
+			if subscriber.paused.Load() {
+				continue
+			}
+
+			receiveLimit := subscriber.receiveLimit(lanes, messagesLimit, inFlightMessagesLimit, inFlightBytesLimit)
+
+			if receiveLimit == 0 {
+				subscriber.logger.Debug("receiving was skipped because there is no free capacity")
+
+				subscriber.lifecycle.Skipped()
+
+				continue
+			}
+
+			serviceBusReceivedMessages, err := subscriber.receiver.ReceiveMessages(ctx, receiveLimit, nil)
 
 			if err != nil {
 				return err
 			}
 
+			subscriber.lifecycle.Received(len(serviceBusReceivedMessages))
+
 			for _, serviceBusReceivedMessage := range serviceBusReceivedMessages {
 				message, err := subscriber.unmarshalMessageFunc(serviceBusReceivedMessage)
 
-				if err != nil {
-					deadLetterOptions := &azservicebus.DeadLetterOptions{
-						ErrorDescription: to.Ptr(err.Error()),
-						Reason:           to.Ptr("UnmarshalMessageError"),
-					}
-
-					if err := subscriber.receiver.DeadLetterMessage(ctx, serviceBusReceivedMessage, deadLetterOptions); err != nil {
-						var serviceBusErr *azservicebus.Error
-
-						if errors.As(err, &serviceBusErr) && serviceBusErr.Code == azservicebus.CodeLockLost {
-							subscriber.logger.Warn("message lock was lost while trying to dead letter the message")
-
-							continue
-						}
+				// The messages that are not valid are dead lettered before they reach the filter and the handlers.
+				if err == nil {
+					err = validation.Validate(message)
+				}
 
+				if err != nil {
+					if err := subscriber.deadLetter(ctx, serviceBusReceivedMessage, err); err != nil {
 						return err
 					}
 
-					subscriber.logger.Error("message was dead lettered", "error", err)
-
 					continue
 				}
 
@@ -231,20 +615,32 @@ func (subscriber *Subscriber) produce(ctx context.Context, partitions []chan *pa
 					serviceBusReceivedMessage: serviceBusReceivedMessage,
 				}
 
-				if err := subscriber.enqueue(ctx, partitions, partitionMessage); err != nil {
-					if err := subscriber.receiver.AbandonMessage(ctx, serviceBusReceivedMessage, nil); err != nil {
-						var serviceBusErr *azservicebus.Error
+				if result := subscriber.filter(message); result != nil {
+					if err := subscriber.settle(ctx, partitionMessage, result); err != nil {
+						return err
+					}
 
-						if errors.As(err, &serviceBusErr) && serviceBusErr.Code == azservicebus.CodeLockLost {
-							subscriber.logger.Warn("message lock was lost while trying to abandon the message")
+					continue
+				}
 
-							continue
-						}
+				subscriber.acquire(partitionMessage)
 
-						return err
+				err = subscriber.waitForBytes(ctx, inFlightBytesLimit, interval)
+
+				if err == nil {
+					err = subscriber.enqueue(ctx, lanes, partitionMessage, interval)
+				}
+
+				if err != nil {
+					subscriber.release(partitionMessage)
+
+					if ctxErr := ctx.Err(); ctxErr != nil {
+						return ctxErr
 					}
 
-					subscriber.logger.Error("message was abandoned", "error", err)
+					if err := subscriber.settle(ctx, partitionMessage, &pubsub.DispatchResult{Found: true, Err: err}); err != nil {
+						return err
+					}
 
 					continue
 				}
@@ -253,51 +649,330 @@ func (subscriber *Subscriber) produce(ctx context.Context, partitions []chan *pa
 	}
 }
 
-func (subscriber *Subscriber) consume(ctx context.Context, partition <-chan *partitionMessage) error {
+// consume takes the messages from the partitions of the lanes, one partition per lane.
+func (subscriber *Subscriber) consume(ctx context.Context, partitions []chan *partitionMessage, weights []int) error {
+	credits := make([]int, len(partitions))
+	closed := make([]bool, len(partitions))
+
 	for {
+		partitionMessages, err := subscriber.take(ctx, partitions, weights, credits, closed)
+
+		if err != nil {
+			return err
+		}
+
+		if partitionMessages == nil {
+			return nil
+		}
+
+		err = subscriber.handle(ctx, partitionMessages)
+
+		for _, partitionMessage := range partitionMessages {
+			subscriber.release(partitionMessage)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// take picks a lane and takes a window of messages from its partition. With several lanes, the window holds at most as many messages
+// as the weight of the lane, and each message counts as a pick of the lane, so that the lanes are served in proportion to their
+// weights in messages, not in windows. It returns nil messages when all partitions are closed.
+func (subscriber *Subscriber) take(ctx context.Context, partitions []chan *partitionMessage, weights []int, credits []int, closed []bool) ([]*partitionMessage, error) {
+	laneIndex, partitionMessage, err := subscriber.next(ctx, partitions, weights, credits, closed)
+
+	if err != nil || partitionMessage == nil {
+		return nil, err
+	}
+
+	if len(partitions) == 1 {
+		return subscriber.window(partitions[laneIndex], partitionMessage, cap(partitions[laneIndex])), nil
+	}
+
+	partitionMessages := subscriber.window(partitions[laneIndex], partitionMessage, weights[laneIndex])
+
+	// The first message was already counted by next.
+	for range len(partitionMessages) - 1 {
+		totalWeight := 0
+
+		for otherLaneIndex, partition := range partitions {
+			if otherLaneIndex == laneIndex || len(partition) != 0 {
+				credits[otherLaneIndex] += weights[otherLaneIndex]
+				totalWeight += weights[otherLaneIndex]
+			}
+		}
+
+		credits[laneIndex] -= totalWeight
+	}
+
+	return partitionMessages, nil
+}
+
+// next picks the lane with smooth weighted round-robin among the lanes with buffered messages and takes a message from its partition.
+// When all partitions are empty, it waits for a message from any of them. It returns a nil message when all partitions are closed.
+func (subscriber *Subscriber) next(ctx context.Context, partitions []chan *partitionMessage, weights []int, credits []int, closed []bool) (int, *partitionMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	pickedLaneIndex := -1
+	totalWeight := 0
+
+	for laneIndex, partition := range partitions {
+		if len(partition) == 0 {
+			continue
+		}
+
+		credits[laneIndex] += weights[laneIndex]
+		totalWeight += weights[laneIndex]
+
+		if pickedLaneIndex == -1 || credits[laneIndex] > credits[pickedLaneIndex] {
+			pickedLaneIndex = laneIndex
+		}
+	}
+
+	if pickedLaneIndex != -1 {
+		credits[pickedLaneIndex] -= totalWeight
+
+		// The consumer is the only receiver of its partitions, so a buffered message is still there.
+		partitionMessage, ok := <-partitions[pickedLaneIndex]
+
+		if ok {
+			return pickedLaneIndex, partitionMessage, nil
+		}
+	}
+
+	for {
+		cases := make([]reflect.SelectCase, 0, 1+len(partitions))
+		laneIndexes := make([]int, 0, len(partitions))
+
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+
+		for laneIndex, partition := range partitions {
+			if closed[laneIndex] {
+				continue
+			}
+
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(partition)})
+			laneIndexes = append(laneIndexes, laneIndex)
+		}
+
+		if len(laneIndexes) == 0 {
+			return 0, nil, nil
+		}
+
+		chosen, value, ok := reflect.Select(cases)
+
+		if chosen == 0 {
+			return 0, nil, ctx.Err()
+		}
+
+		laneIndex := laneIndexes[chosen-1]
+
+		if !ok {
+			closed[laneIndex] = true
+
+			continue
+		}
+
+		return laneIndex, value.Interface().(*partitionMessage), nil
+	}
+}
+
+// window takes the messages that are already buffered in the partition, so that batch handlers can process them together.
+// The window holds at most limit messages, and at most as many messages as the partition.
+func (subscriber *Subscriber) window(partition <-chan *partitionMessage, headPartitionMessage *partitionMessage, limit int) []*partitionMessage {
+	partitionMessages := []*partitionMessage{headPartitionMessage}
+
+	for len(partitionMessages) < min(cap(partition), limit) {
 		select {
-		case <-ctx.Done():
-			return ctx.Err()
 		case partitionMessage, ok := <-partition:
 			if !ok {
-				return nil
+				return partitionMessages
 			}
 
-			discriminator := partitionMessage.message.Discriminator()
+			partitionMessages = append(partitionMessages, partitionMessage)
+		default:
+			return partitionMessages
+		}
+	}
 
-			if handler, ok := subscriber.dispatcher.Dispatch(discriminator); ok {
-				if err := handler.Handle(partitionMessage.message); err != nil {
-					if err := subscriber.receiver.AbandonMessage(ctx, partitionMessage.serviceBusReceivedMessage, nil); err != nil {
-						var serviceBusErr *azservicebus.Error
+	return partitionMessages
+}
 
-						if errors.As(err, &serviceBusErr) && serviceBusErr.Code == azservicebus.CodeLockLost {
-							subscriber.logger.Warn("message lock was lost while trying to abandon the message")
+func (subscriber *Subscriber) handle(ctx context.Context, partitionMessages []*partitionMessage) error {
+	messages := make([]pubsub.Message, 0, len(partitionMessages))
 
-							continue
-						}
+	for _, partitionMessage := range partitionMessages {
+		messages = append(messages, partitionMessage.message)
+	}
 
-						return err
-					}
+	results := subscriber.dispatcher.DispatchBatchWithOptions(messages, &pubsub.DispatchOptions{
+		WaitFunc: subscriber.newWaitFunc(ctx),
+	})
 
-					subscriber.logger.Error("message was abandoned", "error", err)
+	for i, result := range results {
+		if err := subscriber.settle(ctx, partitionMessages[i], result); err != nil {
+			return err
+		}
+	}
 
-					continue
-				}
-			} else {
-				subscriber.logger.Info("message handler was not found", "discriminator", discriminator)
-			}
+	return nil
+}
 
-			if err := subscriber.receiver.CompleteMessage(ctx, partitionMessage.serviceBusReceivedMessage, nil); err != nil {
-				var serviceBusErr *azservicebus.Error
+// filter returns the result for the messages that must not be dispatched, either because they are filtered out
+// or because the filter failed, or nil for the messages that are dispatched.
+func (subscriber *Subscriber) filter(message pubsub.Message) *pubsub.DispatchResult {
+	if subscriber.options == nil || subscriber.options.Filter == nil {
+		return nil
+	}
 
-				if errors.As(err, &serviceBusErr) && serviceBusErr.Code == azservicebus.CodeLockLost {
-					subscriber.logger.Warn("message lock was lost while trying to complete the message")
+	accepted, err := subscriber.options.Filter.Accept(message)
 
-					continue
-				}
+	if err != nil {
+		return &pubsub.DispatchResult{Found: true, Err: err}
+	}
 
-				return err
+	if !accepted {
+		return &pubsub.DispatchResult{Skipped: true}
+	}
+
+	return nil
+}
+
+// newWaitFunc returns the function that waits for the rate limit. The locks of all the messages in flight, including the messages
+// that wait in the partitions of the other consumers, are renewed by renewLocks while waiting.
+func (subscriber *Subscriber) newWaitFunc(ctx context.Context) pubsub.WaitFunc {
+	if subscriber.options == nil || subscriber.options.Limiter == nil {
+		return nil
+	}
+
+	return func(messages []pubsub.Message) error {
+		delay, err := subscriber.options.Limiter.Reserve(messages)
+
+		if err != nil {
+			return err
+		}
+
+		if delay <= 0 {
+			return nil
+		}
+
+		subscriber.logger.Debug("handler invocation was delayed by the rate limit", "delay", delay)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+			return nil
+		}
+	}
+}
+
+// report reports the result of a message that was deleted when it was received, so it cannot be settled.
+func (subscriber *Subscriber) report(discriminator pubsub.Discriminator, result *pubsub.DispatchResult) {
+	switch {
+	case result.Skipped:
+		subscriber.logger.Info("message was skipped by the filter", "discriminator", discriminator)
+
+		subscriber.lifecycle.Settled(discriminator, pubsub.SettlementSkipped, nil)
+	case !result.Found:
+		subscriber.logger.Info("message handler was not found", "discriminator", discriminator)
+
+		subscriber.lifecycle.Settled(discriminator, pubsub.SettlementCompleted, nil)
+	case result.Err != nil:
+		subscriber.logger.Error("message was dropped", "error", result.Err)
+
+		subscriber.lifecycle.Settled(discriminator, pubsub.SettlementDropped, result.Err)
+	default:
+		subscriber.lifecycle.Settled(discriminator, pubsub.SettlementCompleted, nil)
+	}
+}
+
+func (subscriber *Subscriber) receiveAndDelete() bool {
+	return subscriber.options != nil && subscriber.options.ReceiveMode == azservicebus.ReceiveModeReceiveAndDelete
+}
+
+func (subscriber *Subscriber) deadLetter(ctx context.Context, serviceBusReceivedMessage *azservicebus.ReceivedMessage, err error) error {
+	if subscriber.receiveAndDelete() {
+		subscriber.logger.Error("message was dropped", "error", err)
+
+		subscriber.lifecycle.Settled(pubsub.DiscriminatorEmpty, pubsub.SettlementDropped, err)
+
+		return nil
+	}
+
+	deadLetterOptions := servicebus.NewDeadLetterOptions(err)
+
+	if err := subscriber.receiver.DeadLetterMessage(ctx, serviceBusReceivedMessage, deadLetterOptions); err != nil {
+		var serviceBusErr *azservicebus.Error
+
+		if errors.As(err, &serviceBusErr) && serviceBusErr.Code == azservicebus.CodeLockLost {
+			subscriber.logger.Warn("message lock was lost while trying to dead letter the message")
+
+			return nil
+		}
+
+		return err
+	}
+
+	subscriber.logger.Error("message was dead lettered", "error", err)
+
+	subscriber.lifecycle.Settled(pubsub.DiscriminatorEmpty, pubsub.SettlementDeadLettered, err)
+
+	return nil
+}
+
+func (subscriber *Subscriber) settle(ctx context.Context, partitionMessage *partitionMessage, result *pubsub.DispatchResult) error {
+	if subscriber.receiveAndDelete() {
+		subscriber.report(partitionMessage.message.Discriminator(), result)
+
+		return nil
+	}
+
+	settlement := pubsub.SettlementCompleted
+
+	if result.Skipped {
+		subscriber.logger.Info("message was skipped by the filter", "discriminator", partitionMessage.message.Discriminator())
+
+		settlement = pubsub.SettlementSkipped
+	} else if !result.Found {
+		subscriber.logger.Info("message handler was not found", "discriminator", partitionMessage.message.Discriminator())
+	} else if result.Err != nil {
+		if err := subscriber.receiver.AbandonMessage(ctx, partitionMessage.serviceBusReceivedMessage, nil); err != nil {
+			var serviceBusErr *azservicebus.Error
+
+			if errors.As(err, &serviceBusErr) && serviceBusErr.Code == azservicebus.CodeLockLost {
+				subscriber.logger.Warn("message lock was lost while trying to abandon the message")
+
+				return nil
 			}
+
+			return err
 		}
+
+		subscriber.logger.Error("message was abandoned", "error", result.Err)
+
+		subscriber.lifecycle.Settled(partitionMessage.message.Discriminator(), pubsub.SettlementAbandoned, result.Err)
+
+		return nil
 	}
+
+	if err := subscriber.receiver.CompleteMessage(ctx, partitionMessage.serviceBusReceivedMessage, nil); err != nil {
+		var serviceBusErr *azservicebus.Error
+
+		if errors.As(err, &serviceBusErr) && serviceBusErr.Code == azservicebus.CodeLockLost {
+			subscriber.logger.Warn("message lock was lost while trying to complete the message")
+
+			return nil
+		}
+
+		return err
+	}
+
+	subscriber.lifecycle.Settled(partitionMessage.message.Discriminator(), settlement, nil)
+
+	return nil
 }
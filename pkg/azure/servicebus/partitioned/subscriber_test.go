@@ -0,0 +1,310 @@
+package partitioned
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+type testMessage struct {
+	discriminator pubsub.Discriminator
+	partitionName string
+}
+
+func (message *testMessage) Discriminator() pubsub.Discriminator {
+	return message.discriminator
+}
+
+func newTestSubscriber(options *SubscriberOptions) *Subscriber {
+	getPartitionNameFunc := func(message pubsub.Message) (string, error) {
+		return message.(*testMessage).partitionName, nil
+	}
+
+	return NewSubscriber(nil, pubsub.NewDispatcher(), nil, getPartitionNameFunc, slog.New(slog.NewTextHandler(io.Discard, nil)), options)
+}
+
+func newTestPartitionMessage(discriminator pubsub.Discriminator, partitionName string, size int) *partitionMessage {
+	return &partitionMessage{
+		message: &testMessage{
+			discriminator: discriminator,
+			partitionName: partitionName,
+		},
+		serviceBusReceivedMessage: &azservicebus.ReceivedMessage{
+			Body: make([]byte, size),
+		},
+	}
+}
+
+func newTestLanes(lanesCount int, partitionsCount int, partitionsLimit int) [][]chan *partitionMessage {
+	lanes := make([][]chan *partitionMessage, 0, lanesCount)
+
+	for range lanesCount {
+		partitions := make([]chan *partitionMessage, 0, partitionsCount)
+
+		for range partitionsCount {
+			partitions = append(partitions, make(chan *partitionMessage, partitionsLimit))
+		}
+
+		lanes = append(lanes, partitions)
+	}
+
+	return lanes
+}
+
+func TestReceiveLimitIsBoundedByMinimumFreeCapacity(t *testing.T) {
+	subscriber := newTestSubscriber(nil)
+
+	lanes := newTestLanes(1, 3, 4)
+
+	if limit := subscriber.receiveLimit(lanes, 10, 0, 0); limit != 4 {
+		t.Fatalf("receive limit is %d, expected 4", limit)
+	}
+
+	lanes[0][1] <- newTestPartitionMessage("A", "a", 0)
+	lanes[0][1] <- newTestPartitionMessage("A", "a", 0)
+	lanes[0][1] <- newTestPartitionMessage("A", "a", 0)
+
+	// All the received messages can land on the partition with 1 free slot.
+	if limit := subscriber.receiveLimit(lanes, 10, 0, 0); limit != 1 {
+		t.Fatalf("receive limit is %d, expected 1", limit)
+	}
+}
+
+func TestReceiveLimitIsBoundedByInFlightLimits(t *testing.T) {
+	subscriber := newTestSubscriber(nil)
+
+	lanes := newTestLanes(1, 1, 10)
+
+	subscriber.acquire(newTestPartitionMessage("A", "a", 100))
+	subscriber.acquire(newTestPartitionMessage("A", "a", 100))
+
+	if limit := subscriber.receiveLimit(lanes, 10, 5, 0); limit != 3 {
+		t.Fatalf("receive limit is %d, expected 3", limit)
+	}
+
+	if limit := subscriber.receiveLimit(lanes, 10, 0, 200); limit != 0 {
+		t.Fatalf("receive limit is %d, expected 0", limit)
+	}
+}
+
+func TestOverBytesLimitIsCheckedPerMessage(t *testing.T) {
+	subscriber := newTestSubscriber(nil)
+
+	large := newTestPartitionMessage("A", "a", 300)
+
+	subscriber.acquire(large)
+
+	// A message larger than the limit is accepted when nothing else is in flight.
+	if subscriber.overBytesLimit(200) {
+		t.Fatal("message is over the limit with nothing else in flight")
+	}
+
+	subscriber.release(large)
+
+	subscriber.acquire(newTestPartitionMessage("A", "a", 150))
+
+	message := newTestPartitionMessage("A", "a", 51)
+
+	subscriber.acquire(message)
+
+	if !subscriber.overBytesLimit(200) {
+		t.Fatal("message is not over the limit")
+	}
+
+	subscriber.release(message)
+
+	subscriber.acquire(newTestPartitionMessage("A", "a", 50))
+
+	if subscriber.overBytesLimit(200) {
+		t.Fatal("message is over the limit")
+	}
+}
+
+func TestWaitForBytesWaitsUntilAMessageIsReleased(t *testing.T) {
+	subscriber := newTestSubscriber(nil)
+
+	inFlight := newTestPartitionMessage("A", "a", 150)
+
+	subscriber.acquire(inFlight)
+	subscriber.acquire(newTestPartitionMessage("A", "a", 100))
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- subscriber.waitForBytes(context.Background(), 200, time.Hour)
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("message did not wait, error is %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	subscriber.release(inFlight)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWindowTakesAtMostTheSizeOfThePartition(t *testing.T) {
+	subscriber := newTestSubscriber(nil)
+
+	partition := make(chan *partitionMessage, 3)
+
+	for range 3 {
+		partition <- newTestPartitionMessage("A", "a", 0)
+	}
+
+	head := <-partition
+
+	// A message is enqueued while the head is taken, so that the partition is full again.
+	partition <- newTestPartitionMessage("A", "a", 0)
+
+	if window := subscriber.window(partition, head, 10); len(window) != 3 {
+		t.Fatalf("window has %d messages, expected 3", len(window))
+	}
+
+	if len(partition) != 1 {
+		t.Fatalf("partition has %d messages, expected 1", len(partition))
+	}
+}
+
+func TestReceiveLimitIsTheFreeCapacityOfTheLeastBusyLane(t *testing.T) {
+	subscriber := newTestSubscriber(nil)
+
+	lanes := newTestLanes(2, 1, 4)
+
+	for range 4 {
+		lanes[0][0] <- newTestPartitionMessage("A", "a", 0)
+	}
+
+	// The full lane does not stop receiving for the other lane.
+	if limit := subscriber.receiveLimit(lanes, 10, 0, 0); limit != 4 {
+		t.Fatalf("receive limit is %d, expected 4", limit)
+	}
+}
+
+func TestEnqueueWaitsForAFullPartition(t *testing.T) {
+	subscriber := newTestSubscriber(nil)
+
+	subscriber.lifecycle.Started()
+
+	lanes := newTestLanes(1, 1, 1)
+
+	if err := subscriber.enqueue(context.Background(), lanes, newTestPartitionMessage("A", "a", 0), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- subscriber.enqueue(context.Background(), lanes, newTestPartitionMessage("A", "a", 0), 5*time.Millisecond)
+	}()
+
+	lastReceiveTime := subscriber.lifecycle.Report().LastReceiveTime
+
+	select {
+	case err := <-done:
+		t.Fatalf("message did not wait, error is %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// The producer is still live while it waits, and the message is not abandoned.
+	if report := subscriber.lifecycle.Report(); !report.LastReceiveTime.After(lastReceiveTime) || report.ConsecutiveFailures != 0 {
+		t.Fatalf("unexpected health report %+v", report)
+	}
+
+	<-lanes[0][0]
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cancel()
+
+	if err := subscriber.enqueue(ctx, lanes, newTestPartitionMessage("A", "a", 0), time.Hour); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error is %v, expected %v", err, context.Canceled)
+	}
+}
+
+func TestTakeServesTheLanesInProportionToTheirWeights(t *testing.T) {
+	subscriber := newTestSubscriber(nil)
+
+	partitions := []chan *partitionMessage{
+		make(chan *partitionMessage, 100),
+		make(chan *partitionMessage, 100),
+	}
+
+	weights := []int{1, 3}
+
+	for range 100 {
+		partitions[0] <- newTestPartitionMessage("A", "a", 0)
+		partitions[1] <- newTestPartitionMessage("B", "b", 0)
+	}
+
+	credits := make([]int, len(partitions))
+	closed := make([]bool, len(partitions))
+
+	counts := map[pubsub.Discriminator]int{}
+
+	for counts["A"]+counts["B"] < 40 {
+		partitionMessages, err := subscriber.take(context.Background(), partitions, weights, credits, closed)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(partitionMessages) > 3 {
+			t.Fatalf("window has %d messages, expected at most 3", len(partitionMessages))
+		}
+
+		for _, partitionMessage := range partitionMessages {
+			counts[partitionMessage.message.Discriminator()]++
+		}
+	}
+
+	if counts["A"] != 10 || counts["B"] != 30 {
+		t.Fatalf("lanes took %d and %d messages, expected 10 and 30", counts["A"], counts["B"])
+	}
+}
+
+func TestExpiringReturnsTheMessagesInFlightWhoseLocksExpire(t *testing.T) {
+	subscriber := newTestSubscriber(nil)
+
+	now := time.Now()
+
+	expiring := newTestPartitionMessage("A", "a", 0)
+	expiring.serviceBusReceivedMessage.LockedUntil = to.Ptr(now.Add(5 * time.Second))
+
+	locked := newTestPartitionMessage("A", "a", 0)
+	locked.serviceBusReceivedMessage.LockedUntil = to.Ptr(now.Add(time.Hour))
+
+	// Messages received in receive-and-delete mode have no lock.
+	unlocked := newTestPartitionMessage("A", "a", 0)
+
+	subscriber.acquire(expiring)
+	subscriber.acquire(locked)
+	subscriber.acquire(unlocked)
+
+	partitionMessages := subscriber.expiring(now.Add(20 * time.Second))
+
+	if len(partitionMessages) != 1 || partitionMessages[0] != expiring {
+		t.Fatalf("expiring messages are %v, expected only the first message", partitionMessages)
+	}
+
+	subscriber.release(expiring)
+
+	if partitionMessages := subscriber.expiring(now.Add(20 * time.Second)); len(partitionMessages) != 0 {
+		t.Fatalf("expiring messages are %v, expected none after the message was released", partitionMessages)
+	}
+}
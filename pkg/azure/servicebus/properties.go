@@ -0,0 +1,61 @@
+package servicebus
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+// Names of the application properties that subscription rules can filter on.
+//
+//   - Event (bool): true for events.
+//   - Type (string): discriminator of the message.
+//   - Version (string): version of the event.
+//   - Operation (string): operation of the event, e.g. "AddOrSet".
+//   - TenantGroupName (string): tenant group of the event, if any.
+//   - TenantName (string): tenant of the event, only if the event concerns exactly one tenant.
+//
+// The properties that do not apply to a message are not set, so the rules must not assume they are present.
+const (
+	ApplicationPropertyEvent           string = "Event"
+	ApplicationPropertyType            string = "Type"
+	ApplicationPropertyVersion         string = "Version"
+	ApplicationPropertyOperation       string = "Operation"
+	ApplicationPropertyTenantGroupName string = "TenantGroupName"
+	ApplicationPropertyTenantName      string = "TenantName"
+)
+
+type GetApplicationPropertiesFunc func(message pubsub.Message) (map[string]any, error)
+
+// NewMarshalApplicationPropertiesFunc adds the application properties returned by getApplicationPropertiesFunc to
+// the marshaled messages. The application properties that are already set are not overwritten.
+func NewMarshalApplicationPropertiesFunc(marshalMessageFunc MarshalMessageFunc, getApplicationPropertiesFunc GetApplicationPropertiesFunc) MarshalMessageFunc {
+	return func(message pubsub.Message) (*azservicebus.Message, error) {
+		serviceBusMessage, err := marshalMessageFunc(message)
+
+		if err != nil {
+			return nil, err
+		}
+
+		applicationProperties, err := getApplicationPropertiesFunc(message)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(applicationProperties) == 0 {
+			return serviceBusMessage, nil
+		}
+
+		if serviceBusMessage.ApplicationProperties == nil {
+			serviceBusMessage.ApplicationProperties = make(map[string]any, len(applicationProperties))
+		}
+
+		for name, value := range applicationProperties {
+			if _, ok := serviceBusMessage.ApplicationProperties[name]; !ok {
+				serviceBusMessage.ApplicationProperties[name] = value
+			}
+		}
+
+		return serviceBusMessage, nil
+	}
+}
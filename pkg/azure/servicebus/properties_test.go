@@ -0,0 +1,69 @@
+package servicebus
+
+import (
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+// The names of the application properties are relied on by the subscription rules, so they must not change.
+func TestApplicationPropertyNames(t *testing.T) {
+	for name, expected := range map[string]string{
+		ApplicationPropertyEvent:           "Event",
+		ApplicationPropertyType:            "Type",
+		ApplicationPropertyVersion:         "Version",
+		ApplicationPropertyOperation:       "Operation",
+		ApplicationPropertyTenantGroupName: "TenantGroupName",
+		ApplicationPropertyTenantName:      "TenantName",
+	} {
+		if name != expected {
+			t.Fatalf("application property is %q, expected %q", name, expected)
+		}
+	}
+}
+
+func TestMarshalApplicationPropertiesFunc(t *testing.T) {
+	errTestProperties := errors.New("properties failed")
+
+	tests := []struct {
+		name                  string
+		existing              map[string]any
+		applicationProperties map[string]any
+		err                   error
+		expected              map[string]any
+	}{
+		{"added", nil, map[string]any{"Type": "Test", "Event": true}, nil, map[string]any{"Type": "Test", "Event": true}},
+		{"not overwritten", map[string]any{"Type": "Custom", "Other": 1}, map[string]any{"Type": "Test", "Event": true}, nil, map[string]any{"Type": "Custom", "Other": 1, "Event": true}},
+		{"none", nil, map[string]any{}, nil, nil},
+		{"failed", nil, nil, errTestProperties, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			marshalMessageFunc := func(message pubsub.Message) (*azservicebus.Message, error) {
+				return &azservicebus.Message{ApplicationProperties: maps.Clone(test.existing)}, nil
+			}
+
+			getApplicationPropertiesFunc := func(message pubsub.Message) (map[string]any, error) {
+				return test.applicationProperties, test.err
+			}
+
+			serviceBusMessage, err := NewMarshalApplicationPropertiesFunc(marshalMessageFunc, getApplicationPropertiesFunc)(&testBatchMessage{})
+
+			if !errors.Is(err, test.err) {
+				t.Fatalf("error is %v, expected %v", err, test.err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if !maps.Equal(serviceBusMessage.ApplicationProperties, test.expected) {
+				t.Fatalf("application properties are %v, expected %v", serviceBusMessage.ApplicationProperties, test.expected)
+			}
+		})
+	}
+}
@@ -2,24 +2,97 @@ package servicebus
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
 	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+	"github.com/scaleforce/synchronization-for-go/pkg/validation"
+)
+
+var (
+	// ErrPrecedingMessageFailed is the error of the messages that were not sent, because a preceding message with the same partition key failed.
+	ErrPrecedingMessageFailed = errors.New("preceding message failed")
+	ErrTopicNotFound          = errors.New("topic not found")
+
+	errInvalidSequenceNumbers = errors.New("invalid sequence numbers")
+	errInvalidMessageBatch    = errors.New("invalid message batch")
 )
 
 type MarshalMessageFunc func(message pubsub.Message) (*azservicebus.Message, error)
 
-type PublisherOptions struct{}
+type GetPartitionKeyFunc func(message pubsub.Message) (string, error)
+
+type PublisherOptions struct {
+	// Optional maximum size in bytes of the batches sent by PublishBatch, defaults to the maximum message size of the link.
+	BatchMaxBytes uint64
+	// Optional partition key of the messages published by PublishBatch. If set, the messages with the same partition key are
+	// delivered in order: once a message fails, the following messages with the same partition key are not sent. The messages
+	// with an empty partition key are not ordered, so they do not stop each other. It is only called with the messages that
+	// are valid, and the messages that are not valid do not stop the following messages.
+	GetPartitionKeyFunc GetPartitionKeyFunc
+}
+
+// MessageBatch is the part of azservicebus.MessageBatch used by the publisher.
+type MessageBatch interface {
+	AddMessage(message *azservicebus.Message, options *azservicebus.AddMessageOptions) error
+	NumBytes() uint64
+	NumMessages() int32
+}
+
+// Sender is the part of azservicebus.Sender used by the publisher, so that it can be replaced, e.g. in tests. NewSender
+// adapts an azservicebus.Sender.
+type Sender interface {
+	SendMessage(ctx context.Context, message *azservicebus.Message, options *azservicebus.SendMessageOptions) error
+	NewMessageBatch(ctx context.Context, options *azservicebus.MessageBatchOptions) (MessageBatch, error)
+	SendMessageBatch(ctx context.Context, batch MessageBatch, options *azservicebus.SendMessageBatchOptions) error
+	ScheduleMessages(ctx context.Context, messages []*azservicebus.Message, scheduledEnqueueTime time.Time, options *azservicebus.ScheduleMessagesOptions) ([]int64, error)
+	CancelScheduledMessages(ctx context.Context, sequenceNumbers []int64, options *azservicebus.CancelScheduledMessagesOptions) error
+}
+
+type serviceBusSender struct {
+	*azservicebus.Sender
+}
+
+func NewSender(sender *azservicebus.Sender) Sender {
+	return &serviceBusSender{
+		Sender: sender,
+	}
+}
+
+func (serviceBusSender *serviceBusSender) NewMessageBatch(ctx context.Context, options *azservicebus.MessageBatchOptions) (MessageBatch, error) {
+	serviceBusMessageBatch, err := serviceBusSender.Sender.NewMessageBatch(ctx, options)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return serviceBusMessageBatch, nil
+}
+
+// SendMessageBatch only sends the batches created by NewMessageBatch.
+func (serviceBusSender *serviceBusSender) SendMessageBatch(ctx context.Context, batch MessageBatch, options *azservicebus.SendMessageBatchOptions) error {
+	serviceBusMessageBatch, ok := batch.(*azservicebus.MessageBatch)
+
+	if !ok {
+		return errInvalidMessageBatch
+	}
+
+	return serviceBusSender.Sender.SendMessageBatch(ctx, serviceBusMessageBatch, options)
+}
 
 type Publisher struct {
-	sender             *azservicebus.Sender
+	sender             Sender
 	marshalMessageFunc MarshalMessageFunc
 	logger             *slog.Logger
 	options            *PublisherOptions
 }
 
-func NewPublisher(sender *azservicebus.Sender, marshalMessageFunc MarshalMessageFunc, logger *slog.Logger, options *PublisherOptions) *Publisher {
+func NewPublisher(sender Sender, marshalMessageFunc MarshalMessageFunc, logger *slog.Logger, options *PublisherOptions) *Publisher {
 	return &Publisher{
 		sender:             sender,
 		marshalMessageFunc: marshalMessageFunc,
@@ -28,7 +101,12 @@ func NewPublisher(sender *azservicebus.Sender, marshalMessageFunc MarshalMessage
 	}
 }
 
+// Publish returns a *validation.ValidationError without sending the message if the message is not valid.
 func (publisher *Publisher) Publish(ctx context.Context, message pubsub.Message) error {
+	if err := validation.Validate(message); err != nil {
+		return err
+	}
+
 	serviceBusMessage, err := publisher.marshalMessageFunc(message)
 
 	if err != nil {
@@ -41,3 +119,214 @@ func (publisher *Publisher) Publish(ctx context.Context, message pubsub.Message)
 
 	return nil
 }
+
+// IsRetryable reports whether publishing can succeed later after it failed with err. Only the errors caused by the
+// message itself, e.g. a message that is too large, is not valid or cannot be marshaled, are not retryable.
+func IsRetryable(err error) bool {
+	if errors.Is(err, azservicebus.ErrMessageTooLarge) || errors.Is(err, validation.ErrInvalidMessage) {
+		return false
+	}
+
+	var unsupportedTypeErr *json.UnsupportedTypeError
+
+	var unsupportedValueErr *json.UnsupportedValueError
+
+	var marshalerErr *json.MarshalerError
+
+	return !errors.As(err, &unsupportedTypeErr) && !errors.As(err, &unsupportedValueErr) && !errors.As(err, &marshalerErr)
+}
+
+// PublishBatchError contains the error of each message passed to PublishBatch, in the order of the messages.
+// The error of the messages that were sent is nil.
+type PublishBatchError struct {
+	Errs []error
+}
+
+func (publishBatchErr *PublishBatchError) Error() string {
+	errMsgs := make([]string, 0, len(publishBatchErr.Errs))
+
+	for i, err := range publishBatchErr.Errs {
+		if err != nil {
+			errMsgs = append(errMsgs, fmt.Sprintf("message %d: %s", i, err.Error()))
+		}
+	}
+
+	errMsg := strings.Join(errMsgs, "\n")
+
+	return errMsg
+}
+
+type batchMessage struct {
+	index        int
+	partitionKey string
+}
+
+// PublishBatch sends the messages in as few batches as possible, in the order of the messages, starting a new batch
+// whenever the current one is full. It returns a *PublishBatchError if any of the messages failed.
+func (publisher *Publisher) PublishBatch(ctx context.Context, messages []pubsub.Message) error {
+	messageBatchOptions := &azservicebus.MessageBatchOptions{}
+
+	var getPartitionKeyFunc GetPartitionKeyFunc
+
+	if publisher.options != nil {
+		messageBatchOptions.MaxBytes = publisher.options.BatchMaxBytes
+
+		getPartitionKeyFunc = publisher.options.GetPartitionKeyFunc
+	}
+
+	errs := make([]error, len(messages))
+
+	// Partition keys of the messages that failed, only tracked with GetPartitionKeyFunc and except the empty partition key.
+	failedPartitionKeys := map[string]struct{}{}
+
+	fail := func(batchMessages []*batchMessage, err error) {
+		for _, batchMessage := range batchMessages {
+			errs[batchMessage.index] = err
+
+			if getPartitionKeyFunc != nil && batchMessage.partitionKey != "" {
+				failedPartitionKeys[batchMessage.partitionKey] = struct{}{}
+			}
+		}
+	}
+
+	var serviceBusMessageBatch MessageBatch
+
+	var batchMessages []*batchMessage
+
+	send := func() {
+		if serviceBusMessageBatch == nil || serviceBusMessageBatch.NumMessages() == 0 {
+			return
+		}
+
+		if err := publisher.sender.SendMessageBatch(ctx, serviceBusMessageBatch, nil); err != nil {
+			publisher.logger.Error("message batch was not sent", "messages", len(batchMessages), "error", err)
+
+			fail(batchMessages, err)
+		} else {
+			publisher.logger.Debug("message batch was sent", "messages", len(batchMessages), "bytes", serviceBusMessageBatch.NumBytes())
+		}
+
+		serviceBusMessageBatch = nil
+		batchMessages = nil
+	}
+
+	for i, message := range messages {
+		if err := ctx.Err(); err != nil {
+			errs[i] = err
+
+			continue
+		}
+
+		if err := validation.Validate(message); err != nil {
+			errs[i] = err
+
+			continue
+		}
+
+		partitionKey := ""
+
+		if getPartitionKeyFunc != nil {
+			var err error
+
+			partitionKey, err = getPartitionKeyFunc(message)
+
+			if err != nil {
+				errs[i] = err
+
+				continue
+			}
+
+			if _, ok := failedPartitionKeys[partitionKey]; ok {
+				errs[i] = ErrPrecedingMessageFailed
+
+				continue
+			}
+		}
+
+		serviceBusMessage, err := publisher.marshalMessageFunc(message)
+
+		if err != nil {
+			fail([]*batchMessage{{index: i, partitionKey: partitionKey}}, err)
+
+			continue
+		}
+
+		for {
+			if serviceBusMessageBatch == nil {
+				serviceBusMessageBatch, err = publisher.sender.NewMessageBatch(ctx, messageBatchOptions)
+
+				if err != nil {
+					fail([]*batchMessage{{index: i, partitionKey: partitionKey}}, err)
+
+					break
+				}
+			}
+
+			err = serviceBusMessageBatch.AddMessage(serviceBusMessage, nil)
+
+			if err == nil {
+				batchMessages = append(batchMessages, &batchMessage{index: i, partitionKey: partitionKey})
+
+				break
+			}
+
+			// The message does not fit even in an empty batch.
+			if !errors.Is(err, azservicebus.ErrMessageTooLarge) || serviceBusMessageBatch.NumMessages() == 0 {
+				fail([]*batchMessage{{index: i, partitionKey: partitionKey}}, err)
+
+				break
+			}
+
+			// The batch is full, so it is sent and the message is added to a new batch. The preceding messages with the same
+			// partition key are in the sent batch, so the message is not sent if the batch failed.
+			send()
+
+			if _, ok := failedPartitionKeys[partitionKey]; ok && getPartitionKeyFunc != nil {
+				errs[i] = ErrPrecedingMessageFailed
+
+				break
+			}
+		}
+	}
+
+	send()
+
+	for _, err := range errs {
+		if err != nil {
+			return &PublishBatchError{
+				Errs: errs,
+			}
+		}
+	}
+
+	return nil
+}
+
+// Schedule sends the message to be enqueued at scheduledTime and returns its sequence number.
+func (publisher *Publisher) Schedule(ctx context.Context, message pubsub.Message, scheduledTime time.Time) (int64, error) {
+	if err := validation.Validate(message); err != nil {
+		return 0, err
+	}
+
+	serviceBusMessage, err := publisher.marshalMessageFunc(message)
+
+	if err != nil {
+		return 0, err
+	}
+
+	sequenceNumbers, err := publisher.sender.ScheduleMessages(ctx, []*azservicebus.Message{serviceBusMessage}, scheduledTime, nil)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if len(sequenceNumbers) != 1 {
+		return 0, errInvalidSequenceNumbers
+	}
+
+	return sequenceNumbers[0], nil
+}
+
+func (publisher *Publisher) CancelScheduled(ctx context.Context, sequenceNumber int64) error {
+	return publisher.sender.CancelScheduledMessages(ctx, []int64{sequenceNumber}, nil)
+}
@@ -0,0 +1,237 @@
+package servicebus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+	"github.com/scaleforce/synchronization-for-go/pkg/validation"
+)
+
+var errTestSend = errors.New("send failed")
+
+type testBatchMessage struct {
+	name         string
+	partitionKey string
+	invalid      bool
+}
+
+func (message *testBatchMessage) Discriminator() pubsub.Discriminator {
+	return "Test"
+}
+
+func (message *testBatchMessage) Validate() error {
+	if message.invalid {
+		return validation.ErrInvalidMessage
+	}
+
+	return nil
+}
+
+// testMessageBatch holds at most capacity messages, and never the messages named "large".
+type testMessageBatch struct {
+	capacity int
+	names    []string
+}
+
+func (batch *testMessageBatch) AddMessage(message *azservicebus.Message, options *azservicebus.AddMessageOptions) error {
+	if string(message.Body) == "large" || len(batch.names) == batch.capacity {
+		return azservicebus.ErrMessageTooLarge
+	}
+
+	batch.names = append(batch.names, string(message.Body))
+
+	return nil
+}
+
+func (batch *testMessageBatch) NumBytes() uint64 {
+	return 0
+}
+
+func (batch *testMessageBatch) NumMessages() int32 {
+	return int32(len(batch.names))
+}
+
+// testSender fails the batches that contain the message named failing.
+type testSender struct {
+	capacity int
+	failing  string
+	batches  [][]string
+}
+
+func (sender *testSender) SendMessage(ctx context.Context, message *azservicebus.Message, options *azservicebus.SendMessageOptions) error {
+	return nil
+}
+
+func (sender *testSender) NewMessageBatch(ctx context.Context, options *azservicebus.MessageBatchOptions) (MessageBatch, error) {
+	return &testMessageBatch{capacity: sender.capacity}, nil
+}
+
+func (sender *testSender) SendMessageBatch(ctx context.Context, batch MessageBatch, options *azservicebus.SendMessageBatchOptions) error {
+	names := batch.(*testMessageBatch).names
+
+	if slices.Contains(names, sender.failing) {
+		return errTestSend
+	}
+
+	sender.batches = append(sender.batches, names)
+
+	return nil
+}
+
+func (sender *testSender) ScheduleMessages(ctx context.Context, messages []*azservicebus.Message, scheduledEnqueueTime time.Time, options *azservicebus.ScheduleMessagesOptions) ([]int64, error) {
+	return nil, nil
+}
+
+func (sender *testSender) CancelScheduledMessages(ctx context.Context, sequenceNumbers []int64, options *azservicebus.CancelScheduledMessagesOptions) error {
+	return nil
+}
+
+func newTestPublisher(sender *testSender, ordered bool) *Publisher {
+	marshalMessageFunc := func(message pubsub.Message) (*azservicebus.Message, error) {
+		return &azservicebus.Message{Body: []byte(message.(*testBatchMessage).name)}, nil
+	}
+
+	options := &PublisherOptions{}
+
+	if ordered {
+		options.GetPartitionKeyFunc = func(message pubsub.Message) (string, error) {
+			return message.(*testBatchMessage).partitionKey, nil
+		}
+	}
+
+	return NewPublisher(sender, marshalMessageFunc, slog.New(slog.NewTextHandler(io.Discard, nil)), options)
+}
+
+// publishTestBatch returns the error of each message, nil if all the messages were sent.
+func publishTestBatch(t *testing.T, publisher *Publisher, messages []pubsub.Message) []error {
+	t.Helper()
+
+	err := publisher.PublishBatch(context.Background(), messages)
+
+	if err == nil {
+		return nil
+	}
+
+	var publishBatchErr *PublishBatchError
+
+	if !errors.As(err, &publishBatchErr) {
+		t.Fatalf("error is %v, expected a *PublishBatchError", err)
+	}
+
+	if len(publishBatchErr.Errs) != len(messages) {
+		t.Fatalf("errors are %d, expected one per message (%d)", len(publishBatchErr.Errs), len(messages))
+	}
+
+	return publishBatchErr.Errs
+}
+
+func TestPublishBatchStartsANewBatchWhenTheBatchIsFull(t *testing.T) {
+	sender := &testSender{capacity: 2}
+
+	messages := []pubsub.Message{
+		&testBatchMessage{name: "a"},
+		&testBatchMessage{name: "b"},
+		&testBatchMessage{name: "c"},
+		&testBatchMessage{name: "d"},
+		&testBatchMessage{name: "e"},
+	}
+
+	if errs := publishTestBatch(t, newTestPublisher(sender, false), messages); errs != nil {
+		t.Fatalf("errors are %v, expected none", errs)
+	}
+
+	expected := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+
+	if !slices.EqualFunc(sender.batches, expected, slices.Equal) {
+		t.Fatalf("batches are %v, expected %v", sender.batches, expected)
+	}
+}
+
+func TestPublishBatchFailsTheMessageTooLargeForAnEmptyBatch(t *testing.T) {
+	sender := &testSender{capacity: 2}
+
+	messages := []pubsub.Message{
+		&testBatchMessage{name: "a"},
+		&testBatchMessage{name: "large"},
+		&testBatchMessage{name: "b"},
+	}
+
+	errs := publishTestBatch(t, newTestPublisher(sender, false), messages)
+
+	if errs[0] != nil || !errors.Is(errs[1], azservicebus.ErrMessageTooLarge) || errs[2] != nil {
+		t.Fatalf("errors are %v, expected %v for message 1 only", errs, azservicebus.ErrMessageTooLarge)
+	}
+
+	// The full batch is sent before the message is tried in an empty batch.
+	expected := [][]string{{"a"}, {"b"}}
+
+	if !slices.EqualFunc(sender.batches, expected, slices.Equal) {
+		t.Fatalf("batches are %v, expected %v", sender.batches, expected)
+	}
+}
+
+func TestPublishBatchDoesNotSendTheMessagesAfterAFailedPartitionKey(t *testing.T) {
+	sender := &testSender{capacity: 2, failing: "a1"}
+
+	messages := []pubsub.Message{
+		&testBatchMessage{name: "a1", partitionKey: "a"},
+		&testBatchMessage{name: "b1", partitionKey: "b"},
+		&testBatchMessage{name: "a2", partitionKey: "a"},
+		&testBatchMessage{name: "c1", partitionKey: "c"},
+		&testBatchMessage{name: "n1"},
+		&testBatchMessage{name: "b2", partitionKey: "b"},
+	}
+
+	errs := publishTestBatch(t, newTestPublisher(sender, true), messages)
+
+	expected := []error{errTestSend, errTestSend, ErrPrecedingMessageFailed, nil, nil, ErrPrecedingMessageFailed}
+
+	for i, err := range errs {
+		if !errors.Is(err, expected[i]) {
+			t.Fatalf("error of message %d is %v, expected %v", i, err, expected[i])
+		}
+	}
+
+	if expectedBatches := [][]string{{"c1", "n1"}}; !slices.EqualFunc(sender.batches, expectedBatches, slices.Equal) {
+		t.Fatalf("batches are %v, expected %v", sender.batches, expectedBatches)
+	}
+}
+
+func TestPublishBatchDoesNotStopTheMessagesAfterAnInvalidMessage(t *testing.T) {
+	sender := &testSender{capacity: 10}
+
+	messages := []pubsub.Message{
+		&testBatchMessage{name: "a1", partitionKey: "a", invalid: true},
+		&testBatchMessage{name: "a2", partitionKey: "a"},
+		&testBatchMessage{name: "a3", partitionKey: "a"},
+	}
+
+	errs := publishTestBatch(t, newTestPublisher(sender, true), messages)
+
+	if !errors.Is(errs[0], validation.ErrInvalidMessage) || errs[1] != nil || errs[2] != nil {
+		t.Fatalf("errors are %v, expected %v for message 0 only", errs, validation.ErrInvalidMessage)
+	}
+
+	if expected := [][]string{{"a2", "a3"}}; !slices.EqualFunc(sender.batches, expected, slices.Equal) {
+		t.Fatalf("batches are %v, expected %v", sender.batches, expected)
+	}
+}
+
+func TestPublishBatchErrorListsTheFailedMessagesByIndex(t *testing.T) {
+	publishBatchErr := &PublishBatchError{
+		Errs: []error{nil, errTestSend, nil, ErrPrecedingMessageFailed},
+	}
+
+	expected := "message 1: send failed\nmessage 3: preceding message failed"
+
+	if errMsg := publishBatchErr.Error(); errMsg != expected {
+		t.Fatalf("error is %q, expected %q", errMsg, expected)
+	}
+}
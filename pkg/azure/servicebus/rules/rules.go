@@ -0,0 +1,217 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus/admin"
+	"github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/filter"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+const (
+	RuleNameDefault string = "$Default"
+)
+
+var (
+	ErrNoRules = errors.New("no rules")
+)
+
+// AdminClient is the part of *admin.Client used to reconcile the rules, so that it can be replaced by a fake.
+type AdminClient interface {
+	NewListRulesPager(topicName string, subscriptionName string, options *admin.ListRulesOptions) *runtime.Pager[admin.ListRulesResponse]
+	CreateRule(ctx context.Context, topicName string, subscriptionName string, options *admin.CreateRuleOptions) (admin.CreateRuleResponse, error)
+	UpdateRule(ctx context.Context, topicName string, subscriptionName string, properties admin.RuleProperties) (admin.UpdateRuleResponse, error)
+	DeleteRule(ctx context.Context, topicName string, subscriptionName string, ruleName string, options *admin.DeleteRuleOptions) (admin.DeleteRuleResponse, error)
+}
+
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func in(applicationProperty string, values []string) string {
+	quotedValues := make([]string, 0, len(values))
+
+	for _, value := range values {
+		quotedValues = append(quotedValues, quote(value))
+	}
+
+	return fmt.Sprintf("user.%s IN (%s)", applicationProperty, strings.Join(quotedValues, ", "))
+}
+
+// NewRules computes the subscription rules that deliver only the messages of the registered handlers that pass the filter.
+// Only the filter options backed by application properties are used, the rest of the filter still applies on the client side.
+// It returns no rules when no handler is registered or the filter excludes all of them.
+func NewRules(ruleName string, dispatcher *pubsub.Dispatcher, filterOptions *filter.FilterOptions) []*admin.RuleProperties {
+	types := make([]string, 0)
+
+	for _, discriminator := range dispatcher.Discriminators() {
+		if discriminator == pubsub.DiscriminatorEmpty {
+			continue
+		}
+
+		if filterOptions != nil && len(filterOptions.Discriminators) != 0 && !slices.Contains(filterOptions.Discriminators, discriminator) {
+			continue
+		}
+
+		types = append(types, string(discriminator))
+	}
+
+	if len(types) == 0 {
+		return nil
+	}
+
+	conditions := []string{in(servicebus.ApplicationPropertyType, types)}
+
+	if filterOptions != nil && len(filterOptions.TenantGroupNames) != 0 {
+		conditions = append(conditions, in(servicebus.ApplicationPropertyTenantGroupName, filterOptions.TenantGroupNames))
+	}
+
+	rule := &admin.RuleProperties{
+		Name: ruleName,
+		Filter: &admin.SQLFilter{
+			Expression: strings.Join(conditions, " AND "),
+		},
+	}
+
+	return []*admin.RuleProperties{rule}
+}
+
+type ReconcilerOptions struct {
+	// Whether the changes are only logged, without being applied.
+	DryRun bool
+	// Whether all rules are deleted when there are no desired rules, so that the subscription does not receive any message.
+	// Otherwise Reconcile returns ErrNoRules and the rules are left unchanged.
+	AllowNoRules bool
+}
+
+// Reconciler makes the rules of a subscription equal to the desired rules. The rules that are not desired are deleted,
+// including the $Default rule that delivers all messages, unless there are no desired rules.
+type Reconciler struct {
+	adminClient      AdminClient
+	topicName        string
+	subscriptionName string
+	logger           *slog.Logger
+	options          *ReconcilerOptions
+}
+
+func NewReconciler(adminClient AdminClient, topicName string, subscriptionName string, logger *slog.Logger, options *ReconcilerOptions) *Reconciler {
+	return &Reconciler{
+		adminClient:      adminClient,
+		topicName:        topicName,
+		subscriptionName: subscriptionName,
+		logger:           logger,
+		options:          options,
+	}
+}
+
+func equalFilters(filter1 admin.RuleFilter, filter2 admin.RuleFilter) bool {
+	sqlFilter1, ok1 := filter1.(*admin.SQLFilter)
+	sqlFilter2, ok2 := filter2.(*admin.SQLFilter)
+
+	if !ok1 || !ok2 {
+		return false
+	}
+
+	return sqlFilter1.Expression == sqlFilter2.Expression && maps.Equal(sqlFilter1.Parameters, sqlFilter2.Parameters)
+}
+
+func (reconciler *Reconciler) Reconcile(ctx context.Context, rules []*admin.RuleProperties) error {
+	dryRun := false
+	allowNoRules := false
+
+	if reconciler.options != nil {
+		dryRun = reconciler.options.DryRun
+		allowNoRules = reconciler.options.AllowNoRules
+	}
+
+	// E.g. no handler is registered or the filter excludes all of them, which is more likely a misconfiguration than
+	// a subscription that must not receive any message.
+	if len(rules) == 0 && !allowNoRules {
+		return ErrNoRules
+	}
+
+	existingRules := map[string]admin.RuleProperties{}
+
+	pager := reconciler.adminClient.NewListRulesPager(reconciler.topicName, reconciler.subscriptionName, nil)
+
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		for _, rule := range page.Rules {
+			existingRules[rule.Name] = rule
+		}
+	}
+
+	if len(rules) == 0 {
+		reconciler.logger.Warn("subscription will not receive any message, because there are no rules")
+	}
+
+	// The desired rules are created before the other rules are deleted, so that no message is missed in the meantime.
+	for _, rule := range rules {
+		existingRule, ok := existingRules[rule.Name]
+
+		if !ok {
+			reconciler.logger.Info("subscription rule will be created", "name", rule.Name, "dryRun", dryRun)
+
+			if dryRun {
+				continue
+			}
+
+			createRuleOptions := &admin.CreateRuleOptions{
+				Name:   &rule.Name,
+				Filter: rule.Filter,
+				Action: rule.Action,
+			}
+
+			if _, err := reconciler.adminClient.CreateRule(ctx, reconciler.topicName, reconciler.subscriptionName, createRuleOptions); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if equalFilters(existingRule.Filter, rule.Filter) && existingRule.Action == nil && rule.Action == nil {
+			continue
+		}
+
+		reconciler.logger.Info("subscription rule will be updated", "name", rule.Name, "dryRun", dryRun)
+
+		if dryRun {
+			continue
+		}
+
+		if _, err := reconciler.adminClient.UpdateRule(ctx, reconciler.topicName, reconciler.subscriptionName, *rule); err != nil {
+			return err
+		}
+	}
+
+	for ruleName := range existingRules {
+		if slices.ContainsFunc(rules, func(rule *admin.RuleProperties) bool { return rule.Name == ruleName }) {
+			continue
+		}
+
+		reconciler.logger.Info("subscription rule will be deleted", "name", ruleName, "dryRun", dryRun)
+
+		if dryRun {
+			continue
+		}
+
+		if _, err := reconciler.adminClient.DeleteRule(ctx, reconciler.topicName, reconciler.subscriptionName, ruleName, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus/admin"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+type fakeAdminClient struct {
+	rules map[string]admin.RuleProperties
+	calls []string
+}
+
+func newFakeAdminClient(rules ...admin.RuleProperties) *fakeAdminClient {
+	adminClient := &fakeAdminClient{
+		rules: map[string]admin.RuleProperties{},
+	}
+
+	for _, rule := range rules {
+		adminClient.rules[rule.Name] = rule
+	}
+
+	return adminClient
+}
+
+func (adminClient *fakeAdminClient) NewListRulesPager(topicName string, subscriptionName string, options *admin.ListRulesOptions) *runtime.Pager[admin.ListRulesResponse] {
+	fetched := false
+
+	return runtime.NewPager(runtime.PagingHandler[admin.ListRulesResponse]{
+		More: func(response admin.ListRulesResponse) bool {
+			return !fetched
+		},
+		Fetcher: func(ctx context.Context, response *admin.ListRulesResponse) (admin.ListRulesResponse, error) {
+			fetched = true
+
+			rules := make([]admin.RuleProperties, 0, len(adminClient.rules))
+
+			for _, rule := range adminClient.rules {
+				rules = append(rules, rule)
+			}
+
+			return admin.ListRulesResponse{Rules: rules}, nil
+		},
+	})
+}
+
+func (adminClient *fakeAdminClient) CreateRule(ctx context.Context, topicName string, subscriptionName string, options *admin.CreateRuleOptions) (admin.CreateRuleResponse, error) {
+	adminClient.calls = append(adminClient.calls, "create "+*options.Name)
+
+	rule := admin.RuleProperties{
+		Name:   *options.Name,
+		Filter: options.Filter,
+		Action: options.Action,
+	}
+
+	adminClient.rules[rule.Name] = rule
+
+	return admin.CreateRuleResponse{RuleProperties: rule}, nil
+}
+
+func (adminClient *fakeAdminClient) UpdateRule(ctx context.Context, topicName string, subscriptionName string, properties admin.RuleProperties) (admin.UpdateRuleResponse, error) {
+	adminClient.calls = append(adminClient.calls, "update "+properties.Name)
+
+	adminClient.rules[properties.Name] = properties
+
+	return admin.UpdateRuleResponse{RuleProperties: properties}, nil
+}
+
+func (adminClient *fakeAdminClient) DeleteRule(ctx context.Context, topicName string, subscriptionName string, ruleName string, options *admin.DeleteRuleOptions) (admin.DeleteRuleResponse, error) {
+	adminClient.calls = append(adminClient.calls, "delete "+ruleName)
+
+	delete(adminClient.rules, ruleName)
+
+	return admin.DeleteRuleResponse{}, nil
+}
+
+type testHandler struct {
+	discriminator pubsub.Discriminator
+}
+
+func (handler *testHandler) Discriminator() pubsub.Discriminator {
+	return handler.discriminator
+}
+
+func (handler *testHandler) Handle(message pubsub.Message) error {
+	return nil
+}
+
+func newTestReconciler(adminClient AdminClient, options *ReconcilerOptions) *Reconciler {
+	return NewReconciler(adminClient, "topic", "subscription", slog.New(slog.NewTextHandler(io.Discard, nil)), options)
+}
+
+func newTestRule(name string, expression string) admin.RuleProperties {
+	return admin.RuleProperties{
+		Name:   name,
+		Filter: &admin.SQLFilter{Expression: expression},
+	}
+}
+
+func TestNewRules(t *testing.T) {
+	dispatcher := pubsub.NewDispatcher()
+
+	dispatcher.Register(&testHandler{discriminator: "HR_Role"})
+	dispatcher.Register(&testHandler{discriminator: "HR_Employee"})
+
+	rules := NewRules("Synchronization", dispatcher, nil)
+
+	if len(rules) != 1 {
+		t.Fatalf("there are %d rules, expected 1", len(rules))
+	}
+
+	expression := rules[0].Filter.(*admin.SQLFilter).Expression
+
+	if expression != "user.Type IN ('HR_Employee', 'HR_Role')" {
+		t.Fatalf("expression is %q", expression)
+	}
+
+	if rules := NewRules("Synchronization", pubsub.NewDispatcher(), nil); rules != nil {
+		t.Fatalf("rules are %v, expected none without handlers", rules)
+	}
+}
+
+func TestReconcileCreatesTheRulesBeforeDeletingTheOthers(t *testing.T) {
+	adminClient := newFakeAdminClient(newTestRule(RuleNameDefault, "1=1"))
+
+	reconciler := newTestReconciler(adminClient, nil)
+
+	rule := newTestRule("Synchronization", "user.Type IN ('HR_Role')")
+
+	if err := reconciler.Reconcile(context.Background(), []*admin.RuleProperties{&rule}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedCalls := []string{"create Synchronization", "delete $Default"}
+
+	if !slices.Equal(adminClient.calls, expectedCalls) {
+		t.Fatalf("calls are %v, expected %v", adminClient.calls, expectedCalls)
+	}
+}
+
+func TestReconcileUpdatesTheChangedRules(t *testing.T) {
+	adminClient := newFakeAdminClient(newTestRule("Synchronization", "user.Type IN ('HR_Role')"), newTestRule("Unchanged", "1=1"))
+
+	reconciler := newTestReconciler(adminClient, nil)
+
+	rule := newTestRule("Synchronization", "user.Type IN ('HR_Employee')")
+	unchangedRule := newTestRule("Unchanged", "1=1")
+
+	if err := reconciler.Reconcile(context.Background(), []*admin.RuleProperties{&rule, &unchangedRule}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedCalls := []string{"update Synchronization"}
+
+	if !slices.Equal(adminClient.calls, expectedCalls) {
+		t.Fatalf("calls are %v, expected %v", adminClient.calls, expectedCalls)
+	}
+}
+
+func TestReconcileDoesNotChangeTheRulesInDryRun(t *testing.T) {
+	adminClient := newFakeAdminClient(newTestRule(RuleNameDefault, "1=1"), newTestRule("Synchronization", "user.Type IN ('HR_Role')"))
+
+	reconciler := newTestReconciler(adminClient, &ReconcilerOptions{DryRun: true})
+
+	rule := newTestRule("Synchronization", "user.Type IN ('HR_Employee')")
+	addedRule := newTestRule("Added", "1=1")
+
+	if err := reconciler.Reconcile(context.Background(), []*admin.RuleProperties{&rule, &addedRule}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(adminClient.calls) != 0 {
+		t.Fatalf("calls are %v, expected none", adminClient.calls)
+	}
+}
+
+func TestReconcileKeepsTheRulesWithoutDesiredRules(t *testing.T) {
+	adminClient := newFakeAdminClient(newTestRule(RuleNameDefault, "1=1"))
+
+	reconciler := newTestReconciler(adminClient, nil)
+
+	if err := reconciler.Reconcile(context.Background(), nil); !errors.Is(err, ErrNoRules) {
+		t.Fatalf("error is %v, expected %v", err, ErrNoRules)
+	}
+
+	if len(adminClient.calls) != 0 {
+		t.Fatalf("calls are %v, expected none", adminClient.calls)
+	}
+
+	reconciler = newTestReconciler(adminClient, &ReconcilerOptions{AllowNoRules: true})
+
+	if err := reconciler.Reconcile(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedCalls := []string{"delete $Default"}
+
+	if !slices.Equal(adminClient.calls, expectedCalls) {
+		t.Fatalf("calls are %v, expected %v", adminClient.calls, expectedCalls)
+	}
+}
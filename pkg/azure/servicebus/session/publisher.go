@@ -0,0 +1,37 @@
+package session
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+type GetSessionIDFunc func(message pubsub.Message) (string, error)
+
+// NewMarshalMessageFunc assigns the session ID returned by getSessionIDFunc to messages that do not have one yet.
+// Messages for which getSessionIDFunc returns an empty session ID are left without a session ID.
+func NewMarshalMessageFunc(marshalMessageFunc servicebus.MarshalMessageFunc, getSessionIDFunc GetSessionIDFunc) servicebus.MarshalMessageFunc {
+	return func(message pubsub.Message) (*azservicebus.Message, error) {
+		serviceBusMessage, err := marshalMessageFunc(message)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if serviceBusMessage.SessionID != nil {
+			return serviceBusMessage, nil
+		}
+
+		sessionID, err := getSessionIDFunc(message)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if sessionID != "" {
+			serviceBusMessage.SessionID = &sessionID
+		}
+
+		return serviceBusMessage, nil
+	}
+}
@@ -0,0 +1,615 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/azure/servicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/filter"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+	"github.com/scaleforce/synchronization-for-go/pkg/ratelimit"
+	"github.com/scaleforce/synchronization-for-go/pkg/validation"
+)
+
+// SessionReceiver is the part of azservicebus.SessionReceiver used by the subscriber.
+type SessionReceiver interface {
+	SessionID() string
+	LockedUntil() time.Time
+	RenewSessionLock(ctx context.Context, options *azservicebus.RenewSessionLockOptions) error
+	GetSessionState(ctx context.Context, options *azservicebus.GetSessionStateOptions) ([]byte, error)
+	SetSessionState(ctx context.Context, state []byte, options *azservicebus.SetSessionStateOptions) error
+	ReceiveMessages(ctx context.Context, maxMessages int, options *azservicebus.ReceiveMessagesOptions) ([]*azservicebus.ReceivedMessage, error)
+	CompleteMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.CompleteMessageOptions) error
+	AbandonMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.AbandonMessageOptions) error
+	DeadLetterMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.DeadLetterOptions) error
+	Close(ctx context.Context) error
+}
+
+type AcceptNextSessionFunc func(ctx context.Context) (SessionReceiver, error)
+
+func NewAcceptNextSessionForSubscriptionFunc(client *azservicebus.Client, topicName string, subscriptionName string, options *azservicebus.SessionReceiverOptions) AcceptNextSessionFunc {
+	return func(ctx context.Context) (SessionReceiver, error) {
+		sessionReceiver, err := client.AcceptNextSessionForSubscription(ctx, topicName, subscriptionName, options)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return sessionReceiver, nil
+	}
+}
+
+type SubscriberOptions struct {
+	// Time to wait before trying to accept a session again when no session is available.
+	Interval      time.Duration
+	MessagesLimit int
+	// Number of sessions that are processed concurrently.
+	SessionsCount int
+	// Maximum number of messages processed from a session before it is released, so that other sessions get their turn.
+	SessionMessagesLimit int
+	// Time to wait for the next message of a session before it is released.
+	SessionIdleTimeout time.Duration
+	// Whether the sequence number of the last handled message is stored in the session state, so that a message
+	// redelivered after it was handled but before it was completed is completed without being handled again.
+	SessionStateTracking bool
+	// Optional rate limit of the handled messages, shared by all sessions. The session lock is renewed while waiting.
+	Limiter *ratelimit.Limiter
+	Hooks   *pubsub.Hooks
+	// Optional filter of the messages. The messages that are filtered out are completed without being dispatched.
+	Filter *filter.Filter
+	// Must match the receive mode of the session receivers, which NewSubscriberForSubscription ensures by accepting the sessions
+	// in this mode. In ReceiveModeReceiveAndDelete the messages are not settled, and the messages that fail are dropped instead
+	// of being abandoned or dead lettered.
+	ReceiveMode azservicebus.ReceiveMode
+}
+
+type Subscriber struct {
+	acceptNextSessionFunc AcceptNextSessionFunc
+	dispatcher            *pubsub.Dispatcher
+	unmarshalMessageFunc  servicebus.UnmarshalMessageFunc
+	logger                *slog.Logger
+	options               *SubscriberOptions
+	lifecycle             *pubsub.Lifecycle
+	paused                atomic.Bool
+}
+
+func NewSubscriber(acceptNextSessionFunc AcceptNextSessionFunc, dispatcher *pubsub.Dispatcher, unmarshalMessageFunc servicebus.UnmarshalMessageFunc, logger *slog.Logger, options *SubscriberOptions) *Subscriber {
+	var hooks *pubsub.Hooks
+
+	if options != nil {
+		hooks = options.Hooks
+	}
+
+	return &Subscriber{
+		acceptNextSessionFunc: acceptNextSessionFunc,
+		dispatcher:            dispatcher,
+		unmarshalMessageFunc:  unmarshalMessageFunc,
+		logger:                logger,
+		options:               options,
+		lifecycle:             pubsub.NewLifecycle(hooks),
+	}
+}
+
+// NewSubscriberForSubscription accepts the sessions of the subscription in the receive mode of options, so that they match.
+func NewSubscriberForSubscription(client *azservicebus.Client, topicName string, subscriptionName string, dispatcher *pubsub.Dispatcher, unmarshalMessageFunc servicebus.UnmarshalMessageFunc, logger *slog.Logger, options *SubscriberOptions) *Subscriber {
+	sessionReceiverOptions := &azservicebus.SessionReceiverOptions{}
+
+	if options != nil {
+		sessionReceiverOptions.ReceiveMode = options.ReceiveMode
+	}
+
+	acceptNextSessionFunc := NewAcceptNextSessionForSubscriptionFunc(client, topicName, subscriptionName, sessionReceiverOptions)
+
+	return NewSubscriber(acceptNextSessionFunc, dispatcher, unmarshalMessageFunc, logger, options)
+}
+
+func (subscriber *Subscriber) Health() pubsub.HealthReport {
+	return subscriber.lifecycle.Report()
+}
+
+type RunError struct {
+	SessionErrs []error
+}
+
+func (runErr *RunError) Error() string {
+	errMsgs := make([]string, 0, len(runErr.SessionErrs))
+
+	for _, sessionErr := range runErr.SessionErrs {
+		errMsgs = append(errMsgs, sessionErr.Error())
+	}
+
+	errMsg := strings.Join(errMsgs, "\n")
+
+	return errMsg
+}
+
+type sessionState struct {
+	LastSequenceNumber *int64 `json:"LastSequenceNumber"`
+}
+
+type runOptions struct {
+	interval             time.Duration
+	messagesLimit        int
+	sessionMessagesLimit int
+	sessionIdleTimeout   time.Duration
+	sessionStateTracking bool
+}
+
+// Pause stops receiving new messages without stopping Run. The messages that were already received are still handled,
+// and then the sessions are released.
+func (subscriber *Subscriber) Pause() {
+	subscriber.paused.Store(true)
+
+	subscriber.lifecycle.Paused(true)
+
+	subscriber.logger.Info("subscriber was paused")
+}
+
+func (subscriber *Subscriber) Resume() {
+	subscriber.paused.Store(false)
+
+	subscriber.lifecycle.Paused(false)
+
+	subscriber.logger.Info("subscriber was resumed")
+}
+
+func (subscriber *Subscriber) Run(ctx context.Context) error {
+	subscriber.lifecycle.Started()
+
+	err := subscriber.run(ctx)
+
+	subscriber.lifecycle.Stopped(err)
+
+	return err
+}
+
+func (subscriber *Subscriber) run(ctx context.Context) error {
+	sessionsCount := 1
+
+	runOptions := &runOptions{
+		interval:             1 * time.Minute,
+		messagesLimit:        1,
+		sessionMessagesLimit: 100,
+		sessionIdleTimeout:   10 * time.Second,
+		sessionStateTracking: false,
+	}
+
+	if subscriber.options != nil {
+		if subscriber.options.Interval > 0 {
+			runOptions.interval = subscriber.options.Interval
+		}
+
+		if subscriber.options.MessagesLimit > 0 {
+			runOptions.messagesLimit = subscriber.options.MessagesLimit
+		}
+
+		if subscriber.options.SessionsCount > 0 {
+			sessionsCount = subscriber.options.SessionsCount
+		}
+
+		if subscriber.options.SessionMessagesLimit > 0 {
+			runOptions.sessionMessagesLimit = subscriber.options.SessionMessagesLimit
+		}
+
+		if subscriber.options.SessionIdleTimeout > 0 {
+			runOptions.sessionIdleTimeout = subscriber.options.SessionIdleTimeout
+		}
+
+		runOptions.sessionStateTracking = subscriber.options.SessionStateTracking
+	}
+
+	workerCtx, cancelWorkerCtx := context.WithCancel(ctx)
+
+	defer cancelWorkerCtx()
+
+	workerErrs := make(chan error, sessionsCount)
+
+	workerGroup := sync.WaitGroup{}
+
+	workerGroup.Add(sessionsCount)
+
+	for range sessionsCount {
+		go func() {
+			defer workerGroup.Done()
+
+			err := subscriber.work(workerCtx, runOptions)
+
+			// One failing worker stops the others, the same way a failing receive stops the non-partitioned subscriber.
+			if err != nil && workerCtx.Err() == nil {
+				cancelWorkerCtx()
+			}
+
+			workerErrs <- err
+		}()
+	}
+
+	workerGroup.Wait()
+
+	close(workerErrs)
+
+	errs := make([]error, 0, sessionsCount)
+
+	for workerErr := range workerErrs {
+		if workerErr != nil {
+			errs = append(errs, workerErr)
+		}
+	}
+
+	if len(errs) != 0 {
+		return &RunError{
+			SessionErrs: errs,
+		}
+	}
+
+	return nil
+}
+
+func (subscriber *Subscriber) work(ctx context.Context, runOptions *runOptions) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if subscriber.paused.Load() {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(runOptions.interval):
+			}
+
+			continue
+		}
+
+		sessionReceiver, err := subscriber.acceptNextSessionFunc(ctx)
+
+		if err != nil {
+			var serviceBusErr *azservicebus.Error
+
+			if errors.As(err, &serviceBusErr) && serviceBusErr.Code == azservicebus.CodeTimeout {
+				subscriber.logger.Debug("session was not available")
+
+				subscriber.lifecycle.Received(0)
+
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(runOptions.interval):
+				}
+
+				continue
+			}
+
+			return err
+		}
+
+		err = subscriber.process(ctx, sessionReceiver, runOptions)
+
+		if err := sessionReceiver.Close(context.WithoutCancel(ctx)); err != nil {
+			subscriber.logger.Warn("session receiver could not be closed", "sessionID", sessionReceiver.SessionID(), "error", err)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (subscriber *Subscriber) renew(ctx context.Context, sessionReceiver SessionReceiver) {
+	for {
+		renewAfter := max(time.Until(sessionReceiver.LockedUntil())/2, time.Second)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(renewAfter):
+			if err := sessionReceiver.RenewSessionLock(ctx, nil); err != nil {
+				if ctx.Err() == nil {
+					subscriber.logger.Warn("session lock could not be renewed", "sessionID", sessionReceiver.SessionID(), "error", err)
+				}
+
+				return
+			}
+		}
+	}
+}
+
+func (subscriber *Subscriber) getSessionState(ctx context.Context, sessionReceiver SessionReceiver) (*sessionState, error) {
+	state := &sessionState{}
+
+	data, err := sessionReceiver.GetSessionState(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return state, nil
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		subscriber.logger.Warn("session state was reset because it could not be read", "sessionID", sessionReceiver.SessionID(), "error", err)
+
+		return &sessionState{}, nil
+	}
+
+	return state, nil
+}
+
+func (subscriber *Subscriber) setSessionState(ctx context.Context, sessionReceiver SessionReceiver, state *sessionState) error {
+	data, err := json.Marshal(state)
+
+	if err != nil {
+		return err
+	}
+
+	return sessionReceiver.SetSessionState(ctx, data, nil)
+}
+
+// process handles the messages of an accepted session in order, until the session is idle, the session messages limit is
+// reached or a message is abandoned. A message that is abandoned is redelivered first, so the rest of the received messages
+// are abandoned as well and the session is released to keep the order of its messages.
+func (subscriber *Subscriber) process(ctx context.Context, sessionReceiver SessionReceiver, runOptions *runOptions) error {
+	sessionID := sessionReceiver.SessionID()
+
+	renewCtx, cancelRenewCtx := context.WithCancel(ctx)
+
+	defer cancelRenewCtx()
+
+	go subscriber.renew(renewCtx, sessionReceiver)
+
+	var state *sessionState
+
+	if runOptions.sessionStateTracking {
+		var err error
+
+		state, err = subscriber.getSessionState(ctx, sessionReceiver)
+
+		if err != nil {
+			return subscriber.sessionErr(sessionID, err)
+		}
+	}
+
+	for processedCount := 0; processedCount < runOptions.sessionMessagesLimit && !subscriber.paused.Load(); {
+		receiveCtx, cancelReceiveCtx := context.WithTimeout(ctx, runOptions.sessionIdleTimeout)
+
+		serviceBusReceivedMessages, err := sessionReceiver.ReceiveMessages(receiveCtx, min(runOptions.messagesLimit, runOptions.sessionMessagesLimit-processedCount), nil)
+
+		cancelReceiveCtx()
+
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				subscriber.lifecycle.Received(0)
+
+				break
+			}
+
+			return subscriber.sessionErr(sessionID, err)
+		}
+
+		subscriber.lifecycle.Received(len(serviceBusReceivedMessages))
+
+		if len(serviceBusReceivedMessages) == 0 {
+			break
+		}
+
+		for i, serviceBusReceivedMessage := range serviceBusReceivedMessages {
+			processedCount++
+
+			ok, err := subscriber.handle(ctx, sessionReceiver, serviceBusReceivedMessage, state)
+
+			if err != nil {
+				return subscriber.sessionErr(sessionID, err)
+			}
+
+			if !ok {
+				if err := subscriber.abandonRest(ctx, sessionReceiver, serviceBusReceivedMessages[i+1:]); err != nil {
+					return subscriber.sessionErr(sessionID, err)
+				}
+
+				subscriber.logger.Info("session was released because a message was abandoned", "sessionID", sessionID)
+
+				return nil
+			}
+		}
+	}
+
+	subscriber.logger.Debug("session was released", "sessionID", sessionID)
+
+	return nil
+}
+
+// sessionErr decides whether an error ends the session only or the whole worker. Losing the session lock only ends the session.
+func (subscriber *Subscriber) sessionErr(sessionID string, err error) error {
+	var serviceBusErr *azservicebus.Error
+
+	if errors.As(err, &serviceBusErr) && serviceBusErr.Code == azservicebus.CodeLockLost {
+		subscriber.logger.Warn("session lock was lost", "sessionID", sessionID)
+
+		return nil
+	}
+
+	return err
+}
+
+func (subscriber *Subscriber) wait(ctx context.Context, message pubsub.Message) error {
+	if subscriber.options == nil || subscriber.options.Limiter == nil {
+		return nil
+	}
+
+	delay, err := subscriber.options.Limiter.Reserve([]pubsub.Message{message})
+
+	if err != nil {
+		return err
+	}
+
+	if delay <= 0 {
+		return nil
+	}
+
+	subscriber.logger.Debug("handler invocation was delayed by the rate limit", "delay", delay)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+	}
+
+	return nil
+}
+
+func (subscriber *Subscriber) receiveAndDelete() bool {
+	return subscriber.options != nil && subscriber.options.ReceiveMode == azservicebus.ReceiveModeReceiveAndDelete
+}
+
+func (subscriber *Subscriber) complete(ctx context.Context, sessionReceiver SessionReceiver, serviceBusReceivedMessage *azservicebus.ReceivedMessage) error {
+	if subscriber.receiveAndDelete() {
+		return nil
+	}
+
+	return sessionReceiver.CompleteMessage(ctx, serviceBusReceivedMessage, nil)
+}
+
+// abandon reports whether the session can continue with the next message. In ReceiveModeReceiveAndDelete the message
+// is dropped and the session continues, because the message is not redelivered anyway.
+func (subscriber *Subscriber) abandon(ctx context.Context, sessionReceiver SessionReceiver, serviceBusReceivedMessage *azservicebus.ReceivedMessage, discriminator pubsub.Discriminator, err error) (bool, error) {
+	if subscriber.receiveAndDelete() {
+		subscriber.logger.Error("message was dropped", "error", err)
+
+		subscriber.lifecycle.Settled(discriminator, pubsub.SettlementDropped, err)
+
+		return true, nil
+	}
+
+	if err := sessionReceiver.AbandonMessage(ctx, serviceBusReceivedMessage, nil); err != nil {
+		return false, err
+	}
+
+	subscriber.logger.Error("message was abandoned", "error", err)
+
+	subscriber.lifecycle.Settled(discriminator, pubsub.SettlementAbandoned, err)
+
+	return false, nil
+}
+
+// abandonRest abandons the received messages that follow an abandoned message, so that they are redelivered after it.
+// They were not handled, so they are not settlements.
+func (subscriber *Subscriber) abandonRest(ctx context.Context, sessionReceiver SessionReceiver, serviceBusReceivedMessages []*azservicebus.ReceivedMessage) error {
+	for _, serviceBusReceivedMessage := range serviceBusReceivedMessages {
+		if err := sessionReceiver.AbandonMessage(ctx, serviceBusReceivedMessage, nil); err != nil {
+			return err
+		}
+	}
+
+	if len(serviceBusReceivedMessages) != 0 {
+		subscriber.logger.Debug("messages that follow the abandoned message were abandoned", "sessionID", sessionReceiver.SessionID(), "count", len(serviceBusReceivedMessages))
+	}
+
+	return nil
+}
+
+func (subscriber *Subscriber) deadLetter(ctx context.Context, sessionReceiver SessionReceiver, serviceBusReceivedMessage *azservicebus.ReceivedMessage, err error) error {
+	if subscriber.receiveAndDelete() {
+		subscriber.logger.Error("message was dropped", "error", err)
+
+		subscriber.lifecycle.Settled(pubsub.DiscriminatorEmpty, pubsub.SettlementDropped, err)
+
+		return nil
+	}
+
+	deadLetterOptions := servicebus.NewDeadLetterOptions(err)
+
+	if err := sessionReceiver.DeadLetterMessage(ctx, serviceBusReceivedMessage, deadLetterOptions); err != nil {
+		return err
+	}
+
+	subscriber.logger.Error("message was dead lettered", "error", err)
+
+	subscriber.lifecycle.Settled(pubsub.DiscriminatorEmpty, pubsub.SettlementDeadLettered, err)
+
+	return nil
+}
+
+// handle settles a single message of the session and reports whether the session can continue with the next message.
+func (subscriber *Subscriber) handle(ctx context.Context, sessionReceiver SessionReceiver, serviceBusReceivedMessage *azservicebus.ReceivedMessage, state *sessionState) (bool, error) {
+	if state != nil && state.LastSequenceNumber != nil && serviceBusReceivedMessage.SequenceNumber != nil && *serviceBusReceivedMessage.SequenceNumber <= *state.LastSequenceNumber {
+		subscriber.logger.Info("message was already handled", "sessionID", sessionReceiver.SessionID(), "sequenceNumber", *serviceBusReceivedMessage.SequenceNumber)
+
+		if err := subscriber.complete(ctx, sessionReceiver, serviceBusReceivedMessage); err != nil {
+			return false, err
+		}
+
+		subscriber.lifecycle.Settled(pubsub.DiscriminatorEmpty, pubsub.SettlementCompleted, nil)
+
+		return true, nil
+	}
+
+	message, err := subscriber.unmarshalMessageFunc(serviceBusReceivedMessage)
+
+	// The messages that are not valid are dead lettered before they reach the filter and the handlers.
+	if err == nil {
+		err = validation.Validate(message)
+	}
+
+	if err != nil {
+		if err := subscriber.deadLetter(ctx, sessionReceiver, serviceBusReceivedMessage, err); err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	discriminator := message.Discriminator()
+
+	settlement := pubsub.SettlementCompleted
+
+	accepted := true
+
+	if subscriber.options != nil && subscriber.options.Filter != nil {
+		accepted, err = subscriber.options.Filter.Accept(message)
+	}
+
+	if err != nil {
+		return subscriber.abandon(ctx, sessionReceiver, serviceBusReceivedMessage, discriminator, err)
+	}
+
+	if !accepted {
+		subscriber.logger.Info("message was skipped by the filter", "discriminator", discriminator)
+
+		settlement = pubsub.SettlementSkipped
+	} else if handler, ok := subscriber.dispatcher.Dispatch(discriminator); ok {
+		err := subscriber.wait(ctx, message)
+
+		if err == nil {
+			err = handler.Handle(message)
+		}
+
+		if err != nil {
+			return subscriber.abandon(ctx, sessionReceiver, serviceBusReceivedMessage, discriminator, err)
+		}
+	} else {
+		subscriber.logger.Info("message handler was not found", "discriminator", discriminator)
+	}
+
+	if state != nil && serviceBusReceivedMessage.SequenceNumber != nil {
+		state.LastSequenceNumber = serviceBusReceivedMessage.SequenceNumber
+
+		if err := subscriber.setSessionState(ctx, sessionReceiver, state); err != nil {
+			return false, err
+		}
+	}
+
+	if err := subscriber.complete(ctx, sessionReceiver, serviceBusReceivedMessage); err != nil {
+		return false, err
+	}
+
+	subscriber.lifecycle.Settled(discriminator, settlement, nil)
+
+	return true, nil
+}
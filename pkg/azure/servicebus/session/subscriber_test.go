@@ -0,0 +1,254 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+type testMessage struct {
+	name string
+}
+
+func (message *testMessage) Discriminator() pubsub.Discriminator {
+	return "Test"
+}
+
+type testHandler struct {
+	handled []string
+	failing string
+}
+
+func (handler *testHandler) Discriminator() pubsub.Discriminator {
+	return "Test"
+}
+
+func (handler *testHandler) Handle(message pubsub.Message) error {
+	name := message.(*testMessage).name
+
+	handler.handled = append(handler.handled, name)
+
+	if name == handler.failing {
+		return errors.New("handler failed")
+	}
+
+	return nil
+}
+
+type testSessionReceiver struct {
+	batches      [][]*azservicebus.ReceivedMessage
+	receiveCount int
+	state        []byte
+	completed    []string
+	abandoned    []string
+}
+
+func (sessionReceiver *testSessionReceiver) SessionID() string {
+	return "session"
+}
+
+func (sessionReceiver *testSessionReceiver) LockedUntil() time.Time {
+	return time.Now().Add(time.Minute)
+}
+
+func (sessionReceiver *testSessionReceiver) RenewSessionLock(ctx context.Context, options *azservicebus.RenewSessionLockOptions) error {
+	return nil
+}
+
+func (sessionReceiver *testSessionReceiver) GetSessionState(ctx context.Context, options *azservicebus.GetSessionStateOptions) ([]byte, error) {
+	return sessionReceiver.state, nil
+}
+
+func (sessionReceiver *testSessionReceiver) SetSessionState(ctx context.Context, state []byte, options *azservicebus.SetSessionStateOptions) error {
+	sessionReceiver.state = state
+
+	return nil
+}
+
+func (sessionReceiver *testSessionReceiver) ReceiveMessages(ctx context.Context, maxMessages int, options *azservicebus.ReceiveMessagesOptions) ([]*azservicebus.ReceivedMessage, error) {
+	sessionReceiver.receiveCount++
+
+	if len(sessionReceiver.batches) == 0 {
+		return nil, nil
+	}
+
+	batch := sessionReceiver.batches[0]
+
+	sessionReceiver.batches = sessionReceiver.batches[1:]
+
+	return batch, nil
+}
+
+func (sessionReceiver *testSessionReceiver) CompleteMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.CompleteMessageOptions) error {
+	sessionReceiver.completed = append(sessionReceiver.completed, string(message.Body))
+
+	return nil
+}
+
+func (sessionReceiver *testSessionReceiver) AbandonMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.AbandonMessageOptions) error {
+	sessionReceiver.abandoned = append(sessionReceiver.abandoned, string(message.Body))
+
+	return nil
+}
+
+func (sessionReceiver *testSessionReceiver) DeadLetterMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.DeadLetterOptions) error {
+	return nil
+}
+
+func (sessionReceiver *testSessionReceiver) Close(ctx context.Context) error {
+	return nil
+}
+
+func newTestReceivedMessage(name string, sequenceNumber int64) *azservicebus.ReceivedMessage {
+	return &azservicebus.ReceivedMessage{
+		Body:           []byte(name),
+		SequenceNumber: to.Ptr(sequenceNumber),
+	}
+}
+
+func newTestSubscriber(handler *testHandler) *Subscriber {
+	dispatcher := pubsub.NewDispatcher()
+
+	dispatcher.Register(handler)
+
+	unmarshalMessageFunc := func(serviceBusReceivedMessage *azservicebus.ReceivedMessage) (pubsub.Message, error) {
+		return &testMessage{name: string(serviceBusReceivedMessage.Body)}, nil
+	}
+
+	return NewSubscriber(nil, dispatcher, unmarshalMessageFunc, slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+}
+
+func newTestRunOptions(sessionStateTracking bool) *runOptions {
+	return &runOptions{
+		interval:             time.Second,
+		messagesLimit:        10,
+		sessionMessagesLimit: 100,
+		sessionIdleTimeout:   time.Second,
+		sessionStateTracking: sessionStateTracking,
+	}
+}
+
+func TestProcessHandlesTheMessagesInOrder(t *testing.T) {
+	handler := &testHandler{}
+
+	subscriber := newTestSubscriber(handler)
+
+	sessionReceiver := &testSessionReceiver{
+		batches: [][]*azservicebus.ReceivedMessage{
+			{newTestReceivedMessage("a", 1), newTestReceivedMessage("b", 2)},
+			{newTestReceivedMessage("c", 3)},
+		},
+	}
+
+	if err := subscriber.process(context.Background(), sessionReceiver, newTestRunOptions(false)); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"a", "b", "c"}; !slices.Equal(handler.handled, expected) {
+		t.Fatalf("handled messages are %v, expected %v", handler.handled, expected)
+	}
+
+	if expected := []string{"a", "b", "c"}; !slices.Equal(sessionReceiver.completed, expected) {
+		t.Fatalf("completed messages are %v, expected %v", sessionReceiver.completed, expected)
+	}
+}
+
+func TestProcessAbandonsTheRestOfTheBatchAndReleasesTheSession(t *testing.T) {
+	handler := &testHandler{failing: "b"}
+
+	subscriber := newTestSubscriber(handler)
+
+	sessionReceiver := &testSessionReceiver{
+		batches: [][]*azservicebus.ReceivedMessage{
+			{newTestReceivedMessage("a", 1), newTestReceivedMessage("b", 2), newTestReceivedMessage("c", 3)},
+			{newTestReceivedMessage("d", 4)},
+		},
+	}
+
+	if err := subscriber.process(context.Background(), sessionReceiver, newTestRunOptions(false)); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"a", "b"}; !slices.Equal(handler.handled, expected) {
+		t.Fatalf("handled messages are %v, expected %v", handler.handled, expected)
+	}
+
+	if expected := []string{"a"}; !slices.Equal(sessionReceiver.completed, expected) {
+		t.Fatalf("completed messages are %v, expected %v", sessionReceiver.completed, expected)
+	}
+
+	if expected := []string{"b", "c"}; !slices.Equal(sessionReceiver.abandoned, expected) {
+		t.Fatalf("abandoned messages are %v, expected %v", sessionReceiver.abandoned, expected)
+	}
+
+	if sessionReceiver.receiveCount != 1 {
+		t.Fatalf("receive count is %d, expected 1", sessionReceiver.receiveCount)
+	}
+
+	// The messages that were not handled are not failures.
+	if failures := subscriber.Health().ConsecutiveFailures; failures != 1 {
+		t.Fatalf("consecutive failures are %d, expected 1", failures)
+	}
+}
+
+func TestProcessCompletesTheMessagesHandledBeforeTheSessionState(t *testing.T) {
+	handler := &testHandler{}
+
+	subscriber := newTestSubscriber(handler)
+
+	sessionReceiver := &testSessionReceiver{
+		batches: [][]*azservicebus.ReceivedMessage{
+			{newTestReceivedMessage("a", 1), newTestReceivedMessage("b", 2), newTestReceivedMessage("c", 3)},
+		},
+		state: []byte(`{"LastSequenceNumber":2}`),
+	}
+
+	if err := subscriber.process(context.Background(), sessionReceiver, newTestRunOptions(true)); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"c"}; !slices.Equal(handler.handled, expected) {
+		t.Fatalf("handled messages are %v, expected %v", handler.handled, expected)
+	}
+
+	if expected := []string{"a", "b", "c"}; !slices.Equal(sessionReceiver.completed, expected) {
+		t.Fatalf("completed messages are %v, expected %v", sessionReceiver.completed, expected)
+	}
+
+	if state := string(sessionReceiver.state); state != `{"LastSequenceNumber":3}` {
+		t.Fatalf("session state is %s, expected %s", state, `{"LastSequenceNumber":3}`)
+	}
+}
+
+func TestProcessResetsTheSessionStateThatCannotBeRead(t *testing.T) {
+	handler := &testHandler{}
+
+	subscriber := newTestSubscriber(handler)
+
+	sessionReceiver := &testSessionReceiver{
+		batches: [][]*azservicebus.ReceivedMessage{
+			{newTestReceivedMessage("a", 1)},
+		},
+		state: []byte("invalid"),
+	}
+
+	if err := subscriber.process(context.Background(), sessionReceiver, newTestRunOptions(true)); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"a"}; !slices.Equal(handler.handled, expected) {
+		t.Fatalf("handled messages are %v, expected %v", handler.handled, expected)
+	}
+
+	if state := string(sessionReceiver.state); state != `{"LastSequenceNumber":1}` {
+		t.Fatalf("session state is %s, expected %s", state, `{"LastSequenceNumber":1}`)
+	}
+}
@@ -2,13 +2,23 @@ package servicebus
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log/slog"
+	"sync/atomic"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/scaleforce/synchronization-for-go/pkg/filter"
 	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+	"github.com/scaleforce/synchronization-for-go/pkg/ratelimit"
+	"github.com/scaleforce/synchronization-for-go/pkg/validation"
+)
+
+const (
+	DeadLetterReasonUnmarshalMessageError = "UnmarshalMessageError"
+	DeadLetterReasonValidationError       = "ValidationError"
 )
 
 type UnmarshalMessageFunc func(serviceBusReceivedMessage *azservicebus.ReceivedMessage) (pubsub.Message, error)
@@ -16,6 +26,15 @@ type UnmarshalMessageFunc func(serviceBusReceivedMessage *azservicebus.ReceivedM
 type SubscriberOptions struct {
 	Interval      time.Duration
 	MessagesLimit int
+	// Must match the receive mode of the receiver, which NewSubscriberForSubscription ensures by creating the receiver in this mode.
+	// In ReceiveModeReceiveAndDelete the messages are not settled, and the messages that fail are dropped instead of being abandoned
+	// or dead lettered.
+	ReceiveMode azservicebus.ReceiveMode
+	// Optional rate limit of the handled messages. The message locks are renewed while waiting.
+	Limiter *ratelimit.Limiter
+	Hooks   *pubsub.Hooks
+	// Optional filter of the messages. The messages that are filtered out are completed without being dispatched.
+	Filter *filter.Filter
 }
 
 type Subscriber struct {
@@ -24,19 +43,81 @@ type Subscriber struct {
 	unmarshalMessageFunc UnmarshalMessageFunc
 	logger               *slog.Logger
 	options              *SubscriberOptions
+	lifecycle            *pubsub.Lifecycle
+	paused               atomic.Bool
 }
 
 func NewSubscriber(receiver *azservicebus.Receiver, dispatcher *pubsub.Dispatcher, unmarshalMessageFunc UnmarshalMessageFunc, logger *slog.Logger, options *SubscriberOptions) *Subscriber {
+	var hooks *pubsub.Hooks
+
+	if options != nil {
+		hooks = options.Hooks
+	}
+
 	return &Subscriber{
 		receiver:             receiver,
 		dispatcher:           dispatcher,
 		unmarshalMessageFunc: unmarshalMessageFunc,
 		logger:               logger,
 		options:              options,
+		lifecycle:            pubsub.NewLifecycle(hooks),
 	}
 }
 
+// NewSubscriberForSubscription creates the receiver of the subscription in the receive mode of options, so that they match.
+// The receiver is closed by Close.
+func NewSubscriberForSubscription(client *azservicebus.Client, topicName string, subscriptionName string, dispatcher *pubsub.Dispatcher, unmarshalMessageFunc UnmarshalMessageFunc, logger *slog.Logger, options *SubscriberOptions) (*Subscriber, error) {
+	receiverOptions := &azservicebus.ReceiverOptions{}
+
+	if options != nil {
+		receiverOptions.ReceiveMode = options.ReceiveMode
+	}
+
+	receiver, err := client.NewReceiverForSubscription(topicName, subscriptionName, receiverOptions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSubscriber(receiver, dispatcher, unmarshalMessageFunc, logger, options), nil
+}
+
+func (subscriber *Subscriber) Close(ctx context.Context) error {
+	return subscriber.receiver.Close(ctx)
+}
+
+func (subscriber *Subscriber) Health() pubsub.HealthReport {
+	return subscriber.lifecycle.Report()
+}
+
+// Pause stops receiving new messages without stopping Run. The messages that were already received are still handled.
+func (subscriber *Subscriber) Pause() {
+	subscriber.paused.Store(true)
+
+	subscriber.lifecycle.Paused(true)
+
+	subscriber.logger.Info("subscriber was paused")
+}
+
+func (subscriber *Subscriber) Resume() {
+	subscriber.paused.Store(false)
+
+	subscriber.lifecycle.Paused(false)
+
+	subscriber.logger.Info("subscriber was resumed")
+}
+
 func (subscriber *Subscriber) Run(ctx context.Context) error {
+	subscriber.lifecycle.Started()
+
+	err := subscriber.run(ctx)
+
+	subscriber.lifecycle.Stopped(err)
+
+	return err
+}
+
+func (subscriber *Subscriber) run(ctx context.Context) error {
 	interval := 1 * time.Minute
 	messagesLimit := 1
 
@@ -57,74 +138,239 @@ func (subscriber *Subscriber) Run(ctx context.Context) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-tick:
+			if subscriber.paused.Load() {
+				continue
+			}
+
 			serviceBusReceivedMessages, err := subscriber.receiver.ReceiveMessages(ctx, messagesLimit, nil)
 
 			if err != nil {
 				return err
 			}
 
-			for _, serviceBusReceivedMessage := range serviceBusReceivedMessages {
-				message, err := subscriber.unmarshalMessageFunc(serviceBusReceivedMessage)
+			subscriber.lifecycle.Received(len(serviceBusReceivedMessages))
 
-				if err != nil {
-					deadLetterOptions := &azservicebus.DeadLetterOptions{
-						ErrorDescription: to.Ptr(err.Error()),
-						Reason:           to.Ptr("UnmarshalMessageError"),
-					}
+			if err := subscriber.handle(ctx, serviceBusReceivedMessages); err != nil {
+				return err
+			}
+		}
+	}
+}
 
-					if err := subscriber.receiver.DeadLetterMessage(ctx, serviceBusReceivedMessage, deadLetterOptions); err != nil {
-						var serviceBusErr *azservicebus.Error
+func (subscriber *Subscriber) handle(ctx context.Context, serviceBusReceivedMessages []*azservicebus.ReceivedMessage) error {
+	messages := make([]pubsub.Message, 0, len(serviceBusReceivedMessages))
+	dispatchedServiceBusReceivedMessages := make([]*azservicebus.ReceivedMessage, 0, len(serviceBusReceivedMessages))
 
-						if errors.As(err, &serviceBusErr) && serviceBusErr.Code == azservicebus.CodeLockLost {
-							subscriber.logger.Warn("message lock was lost while trying to dead letter the message")
+	for _, serviceBusReceivedMessage := range serviceBusReceivedMessages {
+		message, err := subscriber.unmarshalMessageFunc(serviceBusReceivedMessage)
 
-							continue
-						}
+		// The messages that are not valid are dead lettered before they reach the filter and the handlers.
+		if err == nil {
+			err = validation.Validate(message)
+		}
 
-						return err
-					}
+		if err != nil {
+			if err := subscriber.deadLetter(ctx, serviceBusReceivedMessage, err); err != nil {
+				return err
+			}
 
-					subscriber.logger.Error("message was dead lettered", "error", err)
+			continue
+		}
 
-					continue
-				}
+		if result := subscriber.filter(message); result != nil {
+			if err := subscriber.settle(ctx, message, serviceBusReceivedMessage, result); err != nil {
+				return err
+			}
 
-				discriminator := message.Discriminator()
+			continue
+		}
 
-				if handler, ok := subscriber.dispatcher.Dispatch(discriminator); ok {
-					if err := handler.Handle(message); err != nil {
-						if err := subscriber.receiver.AbandonMessage(ctx, serviceBusReceivedMessage, nil); err != nil {
-							var serviceBusErr *azservicebus.Error
+		messages = append(messages, message)
+		dispatchedServiceBusReceivedMessages = append(dispatchedServiceBusReceivedMessages, serviceBusReceivedMessage)
+	}
+
+	results := subscriber.dispatcher.DispatchBatchWithOptions(messages, &pubsub.DispatchOptions{
+		// All the messages in flight are renewed while waiting, because the subscriber only has the messages of the receive in flight.
+		WaitFunc: subscriber.newWaitFunc(ctx, dispatchedServiceBusReceivedMessages),
+	})
+
+	for i, result := range results {
+		if err := subscriber.settle(ctx, messages[i], dispatchedServiceBusReceivedMessages[i], result); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
-							if errors.As(err, &serviceBusErr) && serviceBusErr.Code == azservicebus.CodeLockLost {
-								subscriber.logger.Warn("message lock was lost while trying to abandon the message")
+// filter returns the result for the messages that must not be dispatched, either because they are filtered out
+// or because the filter failed, or nil for the messages that are dispatched.
+func (subscriber *Subscriber) filter(message pubsub.Message) *pubsub.DispatchResult {
+	if subscriber.options == nil || subscriber.options.Filter == nil {
+		return nil
+	}
 
-								continue
-							}
+	accepted, err := subscriber.options.Filter.Accept(message)
 
-							return err
-						}
+	if err != nil {
+		return &pubsub.DispatchResult{Found: true, Err: err}
+	}
 
-						subscriber.logger.Error("message was abandoned", "error", err)
+	if !accepted {
+		return &pubsub.DispatchResult{Skipped: true}
+	}
 
-						continue
-					}
-				} else {
-					subscriber.logger.Info("message handler was not found", "discriminator", discriminator)
-				}
+	return nil
+}
 
-				if err := subscriber.receiver.CompleteMessage(ctx, serviceBusReceivedMessage, nil); err != nil {
-					var serviceBusErr *azservicebus.Error
+func (subscriber *Subscriber) newWaitFunc(ctx context.Context, serviceBusReceivedMessages []*azservicebus.ReceivedMessage) pubsub.WaitFunc {
+	if subscriber.options == nil || subscriber.options.Limiter == nil {
+		return nil
+	}
 
-					if errors.As(err, &serviceBusErr) && serviceBusErr.Code == azservicebus.CodeLockLost {
-						subscriber.logger.Warn("message lock was lost while trying to complete the message")
+	return func(messages []pubsub.Message) error {
+		delay, err := subscriber.options.Limiter.Reserve(messages)
 
-						continue
-					}
+		if err != nil {
+			return err
+		}
 
-					return err
-				}
+		if delay <= 0 {
+			return nil
+		}
+
+		subscriber.logger.Debug("handler invocation was delayed by the rate limit", "delay", delay)
+
+		return WaitRenewingMessageLocks(ctx, subscriber.receiver, serviceBusReceivedMessages, delay, subscriber.logger)
+	}
+}
+
+// report reports the result of a message that was deleted when it was received, so it cannot be settled.
+func (subscriber *Subscriber) report(discriminator pubsub.Discriminator, result *pubsub.DispatchResult) {
+	switch {
+	case result.Skipped:
+		subscriber.logger.Info("message was skipped by the filter", "discriminator", discriminator)
+
+		subscriber.lifecycle.Settled(discriminator, pubsub.SettlementSkipped, nil)
+	case !result.Found:
+		subscriber.logger.Info("message handler was not found", "discriminator", discriminator)
+
+		subscriber.lifecycle.Settled(discriminator, pubsub.SettlementCompleted, nil)
+	case result.Err != nil:
+		subscriber.logger.Error("message was dropped", "error", result.Err)
+
+		subscriber.lifecycle.Settled(discriminator, pubsub.SettlementDropped, result.Err)
+	default:
+		subscriber.lifecycle.Settled(discriminator, pubsub.SettlementCompleted, nil)
+	}
+}
+
+func (subscriber *Subscriber) receiveAndDelete() bool {
+	return subscriber.options != nil && subscriber.options.ReceiveMode == azservicebus.ReceiveModeReceiveAndDelete
+}
+
+func (subscriber *Subscriber) deadLetter(ctx context.Context, serviceBusReceivedMessage *azservicebus.ReceivedMessage, err error) error {
+	if subscriber.receiveAndDelete() {
+		subscriber.logger.Error("message was dropped", "error", err)
+
+		subscriber.lifecycle.Settled(pubsub.DiscriminatorEmpty, pubsub.SettlementDropped, err)
+
+		return nil
+	}
+
+	deadLetterOptions := NewDeadLetterOptions(err)
+
+	if err := subscriber.receiver.DeadLetterMessage(ctx, serviceBusReceivedMessage, deadLetterOptions); err != nil {
+		var serviceBusErr *azservicebus.Error
+
+		if errors.As(err, &serviceBusErr) && serviceBusErr.Code == azservicebus.CodeLockLost {
+			subscriber.logger.Warn("message lock was lost while trying to dead letter the message")
+
+			return nil
+		}
+
+		return err
+	}
+
+	subscriber.logger.Error("message was dead lettered", "error", err)
+
+	subscriber.lifecycle.Settled(pubsub.DiscriminatorEmpty, pubsub.SettlementDeadLettered, err)
+
+	return nil
+}
+
+// NewDeadLetterOptions returns the options to dead letter a message that was not dispatched because of err. The error
+// description of a message that is not valid is the JSON array of its field errors.
+func NewDeadLetterOptions(err error) *azservicebus.DeadLetterOptions {
+	var validationErr *validation.ValidationError
+
+	if errors.As(err, &validationErr) {
+		errorDescription := err.Error()
+
+		if data, err := json.Marshal(validationErr.Errs); err == nil {
+			errorDescription = string(data)
+		}
+
+		return &azservicebus.DeadLetterOptions{
+			ErrorDescription: to.Ptr(errorDescription),
+			Reason:           to.Ptr(DeadLetterReasonValidationError),
+		}
+	}
+
+	return &azservicebus.DeadLetterOptions{
+		ErrorDescription: to.Ptr(err.Error()),
+		Reason:           to.Ptr(DeadLetterReasonUnmarshalMessageError),
+	}
+}
+
+func (subscriber *Subscriber) settle(ctx context.Context, message pubsub.Message, serviceBusReceivedMessage *azservicebus.ReceivedMessage, result *pubsub.DispatchResult) error {
+	if subscriber.receiveAndDelete() {
+		subscriber.report(message.Discriminator(), result)
+
+		return nil
+	}
+
+	settlement := pubsub.SettlementCompleted
+
+	if result.Skipped {
+		subscriber.logger.Info("message was skipped by the filter", "discriminator", message.Discriminator())
+
+		settlement = pubsub.SettlementSkipped
+	} else if !result.Found {
+		subscriber.logger.Info("message handler was not found", "discriminator", message.Discriminator())
+	} else if result.Err != nil {
+		if err := subscriber.receiver.AbandonMessage(ctx, serviceBusReceivedMessage, nil); err != nil {
+			var serviceBusErr *azservicebus.Error
+
+			if errors.As(err, &serviceBusErr) && serviceBusErr.Code == azservicebus.CodeLockLost {
+				subscriber.logger.Warn("message lock was lost while trying to abandon the message")
+
+				return nil
 			}
+
+			return err
+		}
+
+		subscriber.logger.Error("message was abandoned", "error", result.Err)
+
+		subscriber.lifecycle.Settled(message.Discriminator(), pubsub.SettlementAbandoned, result.Err)
+
+		return nil
+	}
+
+	if err := subscriber.receiver.CompleteMessage(ctx, serviceBusReceivedMessage, nil); err != nil {
+		var serviceBusErr *azservicebus.Error
+
+		if errors.As(err, &serviceBusErr) && serviceBusErr.Code == azservicebus.CodeLockLost {
+			subscriber.logger.Warn("message lock was lost while trying to complete the message")
+
+			return nil
 		}
+
+		return err
 	}
+
+	subscriber.lifecycle.Settled(message.Discriminator(), settlement, nil)
+
+	return nil
 }
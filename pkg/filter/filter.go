@@ -0,0 +1,94 @@
+package filter
+
+import (
+	"slices"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+// Attributes are the properties of a message that the filter predicates are evaluated on.
+type Attributes struct {
+	Discriminator   pubsub.Discriminator
+	Version         string
+	Operation       string
+	TenantGroupName string
+	// Names of the tenants of the message, from the event and from its data. Empty for messages that apply to all tenants.
+	TenantNames []string
+}
+
+type GetAttributesFunc func(message pubsub.Message) (*Attributes, error)
+
+type Predicate func(attributes *Attributes) bool
+
+// FilterOptions lists the accepted values of each attribute. An empty list accepts any value.
+type FilterOptions struct {
+	Discriminators   []pubsub.Discriminator
+	Versions         []string
+	Operations       []string
+	TenantGroupNames []string
+	// Messages without tenant names are accepted, because they apply to all tenants.
+	TenantNames []string
+	// Additional predicates that must all accept the message.
+	Predicates []Predicate
+}
+
+type Filter struct {
+	getAttributesFunc GetAttributesFunc
+	options           *FilterOptions
+}
+
+func NewFilter(getAttributesFunc GetAttributesFunc, options *FilterOptions) *Filter {
+	return &Filter{
+		getAttributesFunc: getAttributesFunc,
+		options:           options,
+	}
+}
+
+func accepts[T comparable](values []T, value T) bool {
+	return len(values) == 0 || slices.Contains(values, value)
+}
+
+// Accept reports whether the message should be dispatched.
+func (filter *Filter) Accept(message pubsub.Message) (bool, error) {
+	if filter.options == nil {
+		return true, nil
+	}
+
+	attributes, err := filter.getAttributesFunc(message)
+
+	if err != nil {
+		return false, err
+	}
+
+	if !accepts(filter.options.Discriminators, attributes.Discriminator) {
+		return false, nil
+	}
+
+	if !accepts(filter.options.Versions, attributes.Version) {
+		return false, nil
+	}
+
+	if !accepts(filter.options.Operations, attributes.Operation) {
+		return false, nil
+	}
+
+	if !accepts(filter.options.TenantGroupNames, attributes.TenantGroupName) {
+		return false, nil
+	}
+
+	if len(filter.options.TenantNames) != 0 && len(attributes.TenantNames) != 0 {
+		if !slices.ContainsFunc(attributes.TenantNames, func(tenantName string) bool {
+			return slices.Contains(filter.options.TenantNames, tenantName)
+		}) {
+			return false, nil
+		}
+	}
+
+	for _, predicate := range filter.options.Predicates {
+		if !predicate(attributes) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
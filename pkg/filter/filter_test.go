@@ -0,0 +1,101 @@
+package filter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+type testMessage struct {
+	attributes *Attributes
+}
+
+func (message *testMessage) Discriminator() pubsub.Discriminator {
+	return message.attributes.Discriminator
+}
+
+func getTestAttributes(message pubsub.Message) (*Attributes, error) {
+	return message.(*testMessage).attributes, nil
+}
+
+func TestAccept(t *testing.T) {
+	attributes := &Attributes{
+		Discriminator:   "HR_Employee",
+		Version:         "1",
+		Operation:       "Add",
+		TenantGroupName: "group",
+		TenantNames:     []string{"tenant1", "tenant2"},
+	}
+
+	testCases := []struct {
+		name       string
+		options    *FilterOptions
+		attributes *Attributes
+		accepted   bool
+	}{
+		{name: "no options", options: nil, attributes: attributes, accepted: true},
+		{name: "empty lists", options: &FilterOptions{}, attributes: attributes, accepted: true},
+		{name: "discriminator accepted", options: &FilterOptions{Discriminators: []pubsub.Discriminator{"HR_Role", "HR_Employee"}}, attributes: attributes, accepted: true},
+		{name: "discriminator rejected", options: &FilterOptions{Discriminators: []pubsub.Discriminator{"HR_Role"}}, attributes: attributes, accepted: false},
+		{name: "version accepted", options: &FilterOptions{Versions: []string{"1"}}, attributes: attributes, accepted: true},
+		{name: "version rejected", options: &FilterOptions{Versions: []string{"2"}}, attributes: attributes, accepted: false},
+		{name: "operation accepted", options: &FilterOptions{Operations: []string{"Add", "Remove"}}, attributes: attributes, accepted: true},
+		{name: "operation rejected", options: &FilterOptions{Operations: []string{"Remove"}}, attributes: attributes, accepted: false},
+		{name: "tenant group accepted", options: &FilterOptions{TenantGroupNames: []string{"group"}}, attributes: attributes, accepted: true},
+		{name: "tenant group rejected", options: &FilterOptions{TenantGroupNames: []string{"other"}}, attributes: attributes, accepted: false},
+		{name: "any tenant accepted", options: &FilterOptions{TenantNames: []string{"tenant2"}}, attributes: attributes, accepted: true},
+		{name: "tenants rejected", options: &FilterOptions{TenantNames: []string{"tenant3"}}, attributes: attributes, accepted: false},
+		{name: "message without tenants accepted", options: &FilterOptions{TenantNames: []string{"tenant3"}}, attributes: &Attributes{Discriminator: "HR_Employee"}, accepted: true},
+		{
+			name: "predicates accepted",
+			options: &FilterOptions{Predicates: []Predicate{
+				func(attributes *Attributes) bool { return attributes.Version == "1" },
+				func(attributes *Attributes) bool { return attributes.Operation == "Add" },
+			}},
+			attributes: attributes,
+			accepted:   true,
+		},
+		{
+			name: "predicate rejected",
+			options: &FilterOptions{Predicates: []Predicate{
+				func(attributes *Attributes) bool { return true },
+				func(attributes *Attributes) bool { return attributes.Operation == "Remove" },
+			}},
+			attributes: attributes,
+			accepted:   false,
+		},
+		{
+			name:       "all attributes accepted",
+			options:    &FilterOptions{Discriminators: []pubsub.Discriminator{"HR_Employee"}, Versions: []string{"1"}, Operations: []string{"Add"}, TenantGroupNames: []string{"group"}, TenantNames: []string{"tenant1"}},
+			attributes: attributes,
+			accepted:   true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			accepted, err := NewFilter(getTestAttributes, testCase.options).Accept(&testMessage{attributes: testCase.attributes})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if accepted != testCase.accepted {
+				t.Fatalf("accepted is %t, expected %t", accepted, testCase.accepted)
+			}
+		})
+	}
+}
+
+func TestAcceptReturnsTheErrorOfTheAttributes(t *testing.T) {
+	errAttributes := errors.New("no attributes")
+
+	filter := NewFilter(func(message pubsub.Message) (*Attributes, error) {
+		return nil, errAttributes
+	}, &FilterOptions{})
+
+	if _, err := filter.Accept(&testMessage{attributes: &Attributes{}}); !errors.Is(err, errAttributes) {
+		t.Fatalf("error is %v, expected %v", err, errAttributes)
+	}
+}
@@ -0,0 +1,114 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidEnum = errors.New("invalid enum")
+)
+
+// The functions below implement the enums of the message models, which are integers on the wire, but can also be
+// parsed from their names, e.g. in configuration.
+
+// EnumString returns the name of value, or its number if it has no name.
+func EnumString[T ~int](value T, names map[T]string) string {
+	if name, ok := names[value]; ok {
+		return name
+	}
+
+	return strconv.Itoa(int(value))
+}
+
+// ParseEnum accepts the name, case-insensitive, or the number of a value that has a name.
+func ParseEnum[T ~int](value string, names map[T]string) (T, error) {
+	value = strings.TrimSpace(value)
+
+	if number, err := strconv.Atoi(value); err == nil {
+		if _, ok := names[T(number)]; ok {
+			return T(number), nil
+		}
+
+		return 0, fmt.Errorf("%w: %d", ErrInvalidEnum, number)
+	}
+
+	for enum, name := range names {
+		if strings.EqualFold(name, value) {
+			return enum, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %q", ErrInvalidEnum, value)
+}
+
+// UnmarshalEnumJSON accepts a JSON number, or a JSON string with a number or the name of a value. The numbers that have
+// no name are accepted too, so that they are reported with the other fields by the validation of the message.
+func UnmarshalEnumJSON[T ~int](data []byte, names map[T]string) (T, error) {
+	data = bytes.TrimSpace(data)
+
+	if bytes.HasPrefix(data, []byte(`"`)) {
+		var value string
+
+		if err := json.Unmarshal(data, &value); err != nil {
+			return 0, err
+		}
+
+		if number, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+			return T(number), nil
+		}
+
+		return ParseEnum(value, names)
+	}
+
+	var number int
+
+	if err := json.Unmarshal(data, &number); err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidEnum, data)
+	}
+
+	return T(number), nil
+}
+
+// EnumValues returns the numbers of the values that have a name, in ascending order, e.g. for JSON Schemas.
+func EnumValues[T ~int](names map[T]string) []any {
+	enums := make([]T, 0, len(names))
+
+	for enum := range names {
+		enums = append(enums, enum)
+	}
+
+	slices.Sort(enums)
+
+	values := make([]any, 0, len(enums))
+
+	for _, enum := range enums {
+		values = append(values, int(enum))
+	}
+
+	return values
+}
+
+// EnumNames returns the names of the values, in the ascending order of the values, e.g. for JSON Schemas.
+func EnumNames[T ~int](names map[T]string) []string {
+	enums := make([]T, 0, len(names))
+
+	for enum := range names {
+		enums = append(enums, enum)
+	}
+
+	slices.Sort(enums)
+
+	enumNames := make([]string, 0, len(enums))
+
+	for _, enum := range enums {
+		enumNames = append(enumNames, names[enum])
+	}
+
+	return enumNames
+}
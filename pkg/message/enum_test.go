@@ -0,0 +1,70 @@
+package message
+
+import (
+	"errors"
+	"testing"
+)
+
+type testEnum int
+
+var testEnumNames = map[testEnum]string{
+	0: "None",
+	1: "Active",
+}
+
+var testFlagsNames = map[testEnum]string{
+	1: "First",
+	2: "Second",
+}
+
+func TestUnmarshalEnumJSONAcceptsTheNumbersWithoutName(t *testing.T) {
+	for data, expectedValue := range map[string]testEnum{
+		`1`:        1,
+		`7`:        7,
+		`"7"`:      7,
+		`"active"`: 1,
+	} {
+		value, err := UnmarshalEnumJSON([]byte(data), testEnumNames)
+
+		if err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+
+		if value != expectedValue {
+			t.Fatalf("%s: value is %d, expected %d", data, value, expectedValue)
+		}
+	}
+
+	for _, data := range []string{`"Unknown"`, `1.5`, `true`} {
+		if _, err := UnmarshalEnumJSON([]byte(data), testEnumNames); !errors.Is(err, ErrInvalidEnum) {
+			t.Fatalf("%s: error is %v, expected %v", data, err, ErrInvalidEnum)
+		}
+	}
+}
+
+func TestUnmarshalFlagsJSONAcceptsTheFlagsWithoutName(t *testing.T) {
+	for data, expectedValue := range map[string]testEnum{
+		`3`:              3,
+		`12`:             12,
+		`"12"`:           12,
+		`"first|second"`: 3,
+	} {
+		value, err := UnmarshalFlagsJSON([]byte(data), testFlagsNames)
+
+		if err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+
+		if value != expectedValue {
+			t.Fatalf("%s: value is %d, expected %d", data, value, expectedValue)
+		}
+	}
+
+	if ValidFlags(testEnum(12), testFlagsNames) {
+		t.Fatal("flags without name are valid")
+	}
+
+	if _, err := UnmarshalFlagsJSON([]byte(`"First|Unknown"`), testFlagsNames); !errors.Is(err, ErrInvalidEnum) {
+		t.Fatalf("error is %v, expected %v", err, ErrInvalidEnum)
+	}
+}
@@ -0,0 +1,80 @@
+package event
+
+import (
+	"time"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/message"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+type eventOptions struct {
+	version         string
+	timestamp       time.Time
+	tenantGroupName string
+	tenantName      string
+}
+
+type Option func(options *eventOptions)
+
+func WithVersion(version string) Option {
+	return func(options *eventOptions) {
+		options.version = version
+	}
+}
+
+func WithTimestamp(timestamp time.Time) Option {
+	return func(options *eventOptions) {
+		options.timestamp = timestamp
+	}
+}
+
+// WithTenantGroupName is ignored for the events that do not embed TenantGroupEvent.
+func WithTenantGroupName(tenantGroupName string) Option {
+	return func(options *eventOptions) {
+		options.tenantGroupName = tenantGroupName
+	}
+}
+
+// WithTenantName is ignored for the events that do not embed TenantEvent.
+func WithTenantName(tenantName string) Option {
+	return func(options *eventOptions) {
+		options.tenantName = tenantName
+	}
+}
+
+// New sets the common fields of event and returns it, e.g. New(&hr.EmployeeEvent{Data: data}, OperationAdd,
+// WithTenantGroupName(name)). The Type is the discriminator of the event, the version defaults to LatestVersion and
+// the timestamp to the current UTC time. The tenant group name and the tenant name are set for the events that embed
+// TenantGroupEvent and TenantEvent.
+func New[T interface {
+	pubsub.Message
+	EventMessage
+}](event T, operation Operation, options ...Option) T {
+	eventOptions := &eventOptions{
+		version:   LatestVersion,
+		timestamp: time.Now().UTC(),
+	}
+
+	for _, option := range options {
+		option(eventOptions)
+	}
+
+	commonEvent := event.GetEvent()
+
+	commonEvent.Message = message.Message{
+		Type: string(event.Discriminator()),
+	}
+	commonEvent.Version = eventOptions.version
+	commonEvent.Operation = operation
+	commonEvent.Timestamp = message.NewTime(eventOptions.timestamp)
+
+	if tenantGroupEventMessage, ok := any(event).(TenantGroupEventMessage); ok {
+		tenantGroupEventMessage.GetTenantGroupEvent().TenantGroupName = eventOptions.tenantGroupName
+	}
+
+	if tenantEventMessage, ok := any(event).(TenantEventMessage); ok {
+		tenantEventMessage.GetTenantEvent().TenantName = eventOptions.tenantName
+	}
+
+	return event
+}
@@ -0,0 +1,118 @@
+package event_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event/hr"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event/masterdata"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event/partner"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event/xnms"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+type testEvent struct {
+	event.Event
+}
+
+func (message *testEvent) Discriminator() pubsub.Discriminator {
+	return "Test_Event"
+}
+
+type testTenantEvent struct {
+	event.TenantEvent
+}
+
+func (message *testTenantEvent) Discriminator() pubsub.Discriminator {
+	return "Test_TenantEvent"
+}
+
+func TestNewSetsTheDefaults(t *testing.T) {
+	before := time.Now().UTC()
+
+	defaultEvent := event.New(&testEvent{}, event.OperationAddOrSet)
+
+	after := time.Now().UTC()
+
+	if defaultEvent.Type != "Test_Event" {
+		t.Fatalf("type is %q, expected %q", defaultEvent.Type, "Test_Event")
+	}
+
+	if defaultEvent.Version != event.LatestVersion {
+		t.Fatalf("version is %q, expected %q", defaultEvent.Version, event.LatestVersion)
+	}
+
+	if defaultEvent.Operation != event.OperationAddOrSet {
+		t.Fatalf("operation is %q, expected %q", defaultEvent.Operation, event.OperationAddOrSet)
+	}
+
+	if defaultEvent.Timestamp.Before(before) || defaultEvent.Timestamp.After(after) || defaultEvent.Timestamp.Location() != time.UTC {
+		t.Fatalf("timestamp is %v, expected the current UTC time", defaultEvent.Timestamp.Time)
+	}
+}
+
+func TestNewAppliesTheOptions(t *testing.T) {
+	timestamp := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		option   event.Option
+		check    func(tenantEvent *testTenantEvent) bool
+		expected string
+	}{
+		{"version", event.WithVersion("2"), func(tenantEvent *testTenantEvent) bool { return tenantEvent.Version == "2" }, "version 2"},
+		{"timestamp", event.WithTimestamp(timestamp), func(tenantEvent *testTenantEvent) bool { return tenantEvent.Timestamp.Equal(timestamp) }, "timestamp " + timestamp.String()},
+		{"tenant group name", event.WithTenantGroupName("group"), func(tenantEvent *testTenantEvent) bool { return tenantEvent.TenantGroupName == "group" }, "tenant group name group"},
+		{"tenant name", event.WithTenantName("tenant"), func(tenantEvent *testTenantEvent) bool { return tenantEvent.TenantName == "tenant" }, "tenant name tenant"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tenantEvent := event.New(&testTenantEvent{}, event.OperationRemove, test.option)
+
+			if !test.check(tenantEvent) {
+				t.Fatalf("event is %+v, expected %s", tenantEvent, test.expected)
+			}
+		})
+	}
+}
+
+func TestNewIgnoresTheTenantOptionsOfEventsWithoutTenant(t *testing.T) {
+	ignoringEvent := event.New(&testEvent{}, event.OperationAddOrSet, event.WithTenantGroupName("group"), event.WithTenantName("tenant"))
+
+	if ignoringEvent.Type != "Test_Event" || ignoringEvent.Version != event.LatestVersion {
+		t.Fatalf("event is %+v", ignoringEvent)
+	}
+}
+
+func TestDeprecatedConstructorsCreateTheSameEvents(t *testing.T) {
+	timestamp := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	options := []event.Option{event.WithVersion(event.Version1), event.WithTimestamp(timestamp), event.WithTenantGroupName("group")}
+
+	tests := []struct {
+		name        string
+		deprecated  pubsub.Message
+		withOptions pubsub.Message
+	}{
+		{"employee", hr.NewEmployeeEvent(event.Version1, event.OperationAddOrSet, timestamp, "group", &hr.EmployeeData{}), hr.NewEmployeeEventWithOptions(event.OperationAddOrSet, &hr.EmployeeData{}, options...)},
+		{"position", hr.NewPositionEvent(event.Version1, event.OperationAddOrSet, timestamp, "group", &hr.PositionData{}), hr.NewPositionEventWithOptions(event.OperationAddOrSet, &hr.PositionData{}, options...)},
+		{"role", hr.NewRoleEvent(event.Version1, event.OperationAddOrSet, timestamp, "group", &hr.RoleData{}), hr.NewRoleEventWithOptions(event.OperationAddOrSet, &hr.RoleData{}, options...)},
+		{"city", masterdata.NewCityEvent(event.Version1, event.OperationAddOrSet, timestamp, "group", &masterdata.CityData{}), masterdata.NewCityEventWithOptions(event.OperationAddOrSet, &masterdata.CityData{}, options...)},
+		{"circle", masterdata.NewCircleEvent(event.Version1, event.OperationAddOrSet, timestamp, "group", &masterdata.CircleData{}), masterdata.NewCircleEventWithOptions(event.OperationAddOrSet, &masterdata.CircleData{}, options...)},
+		{"zone", masterdata.NewZoneEvent(event.Version1, event.OperationAddOrSet, timestamp, "group", &masterdata.ZoneData{}), masterdata.NewZoneEventWithOptions(event.OperationAddOrSet, &masterdata.ZoneData{}, options...)},
+		{"partner group", partner.NewPartnerGroupEvent(event.Version1, event.OperationAddOrSet, timestamp, "group", &partner.PartnerGroupData{}), partner.NewPartnerGroupEventWithOptions(event.OperationAddOrSet, &partner.PartnerGroupData{}, options...)},
+		{"partner", partner.NewPartnerEvent(event.Version1, event.OperationAddOrSet, timestamp, "group", &partner.PartnerData{}), partner.NewPartnerEventWithOptions(event.OperationAddOrSet, &partner.PartnerData{}, options...)},
+		{"device", xnms.NewDeviceEvent(event.Version1, event.OperationAddOrSet, timestamp, "group", &xnms.DeviceData{}), xnms.NewDeviceEventWithOptions(event.OperationAddOrSet, &xnms.DeviceData{}, options...)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !reflect.DeepEqual(test.deprecated, test.withOptions) {
+				t.Fatalf("event is %+v, expected %+v", test.deprecated, test.withOptions)
+			}
+		})
+	}
+}
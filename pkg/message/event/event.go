@@ -1,32 +1,105 @@
 package event
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+
 	"github.com/scaleforce/synchronization-for-go/pkg/message"
 )
 
 const (
 	Version1 string = "1"
+	// LatestVersion is the version of the events created by New.
+	LatestVersion string = Version1
 )
 
+type Operation string
+
 const (
-	OperationAdd      string = "Add"
-	OperationAddOrSet string = "AddOrSet"
-	OperationRemove   string = "Remove"
+	OperationAdd      Operation = "Add"
+	OperationAddOrSet Operation = "AddOrSet"
+	OperationRemove   Operation = "Remove"
 )
 
+var operations = []Operation{OperationAdd, OperationAddOrSet, OperationRemove}
+
+func (operation Operation) String() string {
+	return string(operation)
+}
+
+func (operation Operation) Valid() bool {
+	return slices.Contains(operations, operation)
+}
+
+func (operation Operation) EnumValues() []any {
+	values := make([]any, 0, len(operations))
+
+	for _, operation := range operations {
+		values = append(values, string(operation))
+	}
+
+	return values
+}
+
+func (operation Operation) MarshalText() ([]byte, error) {
+	return []byte(operation), nil
+}
+
+// UnmarshalText accepts the names of the operations, case-insensitive. Operations have no numeric form.
+func (operation *Operation) UnmarshalText(data []byte) error {
+	value := strings.TrimSpace(string(data))
+
+	for _, knownOperation := range operations {
+		if strings.EqualFold(string(knownOperation), value) {
+			*operation = knownOperation
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %q", message.ErrInvalidEnum, value)
+}
+
 type Event struct {
 	message.Message
-	Version   string `json:"Version"`
-	Operation string `json:"Operation"`
-	Timestamp string `json:"Timestamp"`
+	Version   string       `json:"Version"`
+	Operation Operation    `json:"Operation" schema:"required"`
+	Timestamp message.Time `json:"Timestamp"`
 }
 
 type TenantGroupEvent struct {
 	Event
-	TenantGroupName string `json:"TenantGroupName"`
+	TenantGroupName string `json:"TenantGroupName" schema:"required"`
 }
 
 type TenantEvent struct {
 	TenantGroupEvent
-	TenantName string `json:"TenantName"`
+	TenantName string `json:"TenantName" schema:"required"`
+}
+
+// The interfaces below give access to the common fields of the event models, regardless of their concrete type.
+
+type EventMessage interface {
+	GetEvent() *Event
+}
+
+type TenantGroupEventMessage interface {
+	GetTenantGroupEvent() *TenantGroupEvent
+}
+
+type TenantEventMessage interface {
+	GetTenantEvent() *TenantEvent
+}
+
+func (event *Event) GetEvent() *Event {
+	return event
+}
+
+func (event *TenantGroupEvent) GetTenantGroupEvent() *TenantGroupEvent {
+	return event
+}
+
+func (event *TenantEvent) GetTenantEvent() *TenantEvent {
+	return event
 }
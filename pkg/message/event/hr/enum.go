@@ -0,0 +1,45 @@
+package hr
+
+//go:generate go run ../../../../cmd/enumgen -output enum_gen.go Scope Status Qualification
+
+type Scope int
+
+const (
+	ScopeNone Scope = iota
+	ScopeTenantGroup
+	ScopePartnerGroup
+)
+
+var scopeNames = map[Scope]string{
+	ScopeNone:         "None",
+	ScopeTenantGroup:  "TenantGroup",
+	ScopePartnerGroup: "PartnerGroup",
+}
+
+type Status int
+
+const (
+	StatusNone Status = iota
+	StatusActive
+	StatusInactive
+	StatusLeft
+)
+
+var statusNames = map[Status]string{
+	StatusNone:     "None",
+	StatusActive:   "Active",
+	StatusInactive: "Inactive",
+	StatusLeft:     "Left",
+}
+
+type Qualification int
+
+const (
+	QualificationNone Qualification = iota
+	QualificationCertified
+)
+
+var qualificationNames = map[Qualification]string{
+	QualificationNone:      "None",
+	QualificationCertified: "Certified",
+}
@@ -0,0 +1,159 @@
+// Code generated by enumgen. DO NOT EDIT.
+
+package hr
+
+import (
+	"encoding/json"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/message"
+)
+
+func (scope Scope) String() string {
+	return message.EnumString(scope, scopeNames)
+}
+
+func (scope Scope) Valid() bool {
+	_, ok := scopeNames[scope]
+
+	return ok
+}
+
+func (scope Scope) EnumValues() []any {
+	return message.EnumValues(scopeNames)
+}
+
+func (scope Scope) EnumNames() []string {
+	return message.EnumNames(scopeNames)
+}
+
+func (scope Scope) MarshalText() ([]byte, error) {
+	return []byte(scope.String()), nil
+}
+
+func (scope *Scope) UnmarshalText(data []byte) error {
+	value, err := message.ParseEnum(string(data), scopeNames)
+
+	if err != nil {
+		return err
+	}
+
+	*scope = value
+
+	return nil
+}
+
+func (scope Scope) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int(scope))
+}
+
+func (scope *Scope) UnmarshalJSON(data []byte) error {
+	value, err := message.UnmarshalEnumJSON(data, scopeNames)
+
+	if err != nil {
+		return err
+	}
+
+	*scope = value
+
+	return nil
+}
+
+func (status Status) String() string {
+	return message.EnumString(status, statusNames)
+}
+
+func (status Status) Valid() bool {
+	_, ok := statusNames[status]
+
+	return ok
+}
+
+func (status Status) EnumValues() []any {
+	return message.EnumValues(statusNames)
+}
+
+func (status Status) EnumNames() []string {
+	return message.EnumNames(statusNames)
+}
+
+func (status Status) MarshalText() ([]byte, error) {
+	return []byte(status.String()), nil
+}
+
+func (status *Status) UnmarshalText(data []byte) error {
+	value, err := message.ParseEnum(string(data), statusNames)
+
+	if err != nil {
+		return err
+	}
+
+	*status = value
+
+	return nil
+}
+
+func (status Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int(status))
+}
+
+func (status *Status) UnmarshalJSON(data []byte) error {
+	value, err := message.UnmarshalEnumJSON(data, statusNames)
+
+	if err != nil {
+		return err
+	}
+
+	*status = value
+
+	return nil
+}
+
+func (qualification Qualification) String() string {
+	return message.EnumString(qualification, qualificationNames)
+}
+
+func (qualification Qualification) Valid() bool {
+	_, ok := qualificationNames[qualification]
+
+	return ok
+}
+
+func (qualification Qualification) EnumValues() []any {
+	return message.EnumValues(qualificationNames)
+}
+
+func (qualification Qualification) EnumNames() []string {
+	return message.EnumNames(qualificationNames)
+}
+
+func (qualification Qualification) MarshalText() ([]byte, error) {
+	return []byte(qualification.String()), nil
+}
+
+func (qualification *Qualification) UnmarshalText(data []byte) error {
+	value, err := message.ParseEnum(string(data), qualificationNames)
+
+	if err != nil {
+		return err
+	}
+
+	*qualification = value
+
+	return nil
+}
+
+func (qualification Qualification) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int(qualification))
+}
+
+func (qualification *Qualification) UnmarshalJSON(data []byte) error {
+	value, err := message.UnmarshalEnumJSON(data, qualificationNames)
+
+	if err != nil {
+		return err
+	}
+
+	*qualification = value
+
+	return nil
+}
@@ -0,0 +1,16 @@
+package hr
+
+//go:generate go run ../../../../cmd/enumgen -flags -output flags_gen.go ContactTypes
+
+type ContactTypes int
+
+const ContactTypesNone ContactTypes = 0
+const (
+	ContactTypesPrimaryEscalationPoint ContactTypes = 1 << iota
+	ContactTypesSecondaryEscalationPoint
+)
+
+var contactTypesNames = map[ContactTypes]string{
+	ContactTypesPrimaryEscalationPoint:   "PrimaryEscalationPoint",
+	ContactTypesSecondaryEscalationPoint: "SecondaryEscalationPoint",
+}
@@ -0,0 +1,74 @@
+// Code generated by enumgen. DO NOT EDIT.
+
+package hr
+
+import (
+	"encoding/json"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/message"
+)
+
+// Has reports whether all the given flags are set.
+func (contactTypes ContactTypes) Has(flags ContactTypes) bool {
+	return contactTypes&flags == flags
+}
+
+func (contactTypes *ContactTypes) Set(flags ContactTypes) {
+	*contactTypes |= flags
+}
+
+func (contactTypes *ContactTypes) Clear(flags ContactTypes) {
+	*contactTypes &^= flags
+}
+
+func (contactTypes ContactTypes) List() []ContactTypes {
+	return message.ListFlags(contactTypes)
+}
+
+func (contactTypes ContactTypes) String() string {
+	return message.FlagsString(contactTypes, contactTypesNames)
+}
+
+func (contactTypes ContactTypes) Valid() bool {
+	return message.ValidFlags(contactTypes, contactTypesNames)
+}
+
+func (contactTypes ContactTypes) EnumValues() []any {
+	return message.FlagsValues(contactTypesNames)
+}
+
+func (contactTypes ContactTypes) FlagNames() []string {
+	return message.FlagNames(contactTypesNames)
+}
+
+func (contactTypes ContactTypes) MarshalText() ([]byte, error) {
+	return []byte(contactTypes.String()), nil
+}
+
+func (contactTypes *ContactTypes) UnmarshalText(data []byte) error {
+	value, err := message.ParseFlags(string(data), contactTypesNames)
+
+	if err != nil {
+		return err
+	}
+
+	*contactTypes = value
+
+	return nil
+}
+
+func (contactTypes ContactTypes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int(contactTypes))
+}
+
+func (contactTypes *ContactTypes) UnmarshalJSON(data []byte) error {
+	value, err := message.UnmarshalFlagsJSON(data, contactTypesNames)
+
+	if err != nil {
+		return err
+	}
+
+	*contactTypes = value
+
+	return nil
+}
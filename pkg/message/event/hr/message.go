@@ -1,7 +1,8 @@
 package hr
 
 import (
-	"github.com/scaleforce/synchronization-for-go/pkg/message"
+	"time"
+
 	"github.com/scaleforce/synchronization-for-go/pkg/message/event"
 	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
 )
@@ -12,33 +13,8 @@ const (
 	DiscriminatorRole     pubsub.Discriminator = "HR_Role"
 )
 
-const (
-	ScopeNone int = iota
-	ScopeTenantGroup
-	ScopePartnerGroup
-)
-
-const (
-	StatusNone int = iota
-	StatusActive
-	StatusInactive
-	StatusLeft
-)
-
-const (
-	QualificationNone int = iota
-	QualificationCertified
-)
-
-// Flags for ContactTypes
-const ContactTypesNone int = 0
-const (
-	ContactTypesPrimaryEscalationPoint int = 1 << iota
-	ContactTypesSecondaryEscalationPoint
-)
-
 type TenantModel struct {
-	Name         string   `json:"Name"`
+	Name         string   `json:"Name" schema:"required"`
 	CircleCodes  []string `json:"CircleCodes"`
 	ZoneCodes    []string `json:"ZoneCodes"`
 	StorageCodes []string `json:"StorageCodes"`
@@ -49,33 +25,33 @@ type EmployeeTenantGroupModel struct {
 }
 
 type EmployeePartnerGroupModel struct {
-	TenantName       string   `json:"TenantName"`
-	PartnerGroupCode string   `json:"PartnerGroupCode"`
-	PartnerGroupName string   `json:"PartnerGroupName"`
-	Qualification    int      `json:"Qualification"`
-	ContactTypes     int      `json:"ContactTypes"`
-	PartnerCodes     []string `json:"PartnerCodes"`
+	TenantName       string        `json:"TenantName"`
+	PartnerGroupCode string        `json:"PartnerGroupCode" schema:"required"`
+	PartnerGroupName string        `json:"PartnerGroupName"`
+	Qualification    Qualification `json:"Qualification"`
+	ContactTypes     ContactTypes  `json:"ContactTypes"`
+	PartnerCodes     []string      `json:"PartnerCodes"`
 }
 
 type EmployeeRelationModel struct {
-	Code     string `json:"Code"`
+	Code     string `json:"Code" schema:"required"`
 	UserId   string `json:"UserId"`
 	UserName string `json:"UserName"`
-	Scope    int    `json:"Scope"`
+	Scope    Scope  `json:"Scope"`
 }
 
 type EmployeeData struct {
-	Code               string                     `json:"Code"`
+	Code               string                     `json:"Code" schema:"required"`
 	UserId             string                     `json:"UserId"`
 	UserName           string                     `json:"UserName"`
-	Scope              int                        `json:"Scope"`
+	Scope              Scope                      `json:"Scope"`
 	FirstName          string                     `json:"FirstName"`
 	MiddleName         string                     `json:"MiddleName"`
 	LastName           string                     `json:"LastName"`
 	PrimaryPhone       string                     `json:"PrimaryPhone"`
 	SecondaryPhone     string                     `json:"SecondaryPhone"`
 	Email              string                     `json:"Email"`
-	Status             int                        `json:"Status"`
+	Status             Status                     `json:"Status"`
 	PositionCode       string                     `json:"PositionCode"`
 	PositionName       string                     `json:"PositionName"`
 	ReportsToEmployees []*EmployeeRelationModel   `json:"ReportsToEmployees"`
@@ -91,24 +67,17 @@ type EmployeeData struct {
 
 type EmployeeEvent struct {
 	event.TenantGroupEvent
-	Data *EmployeeData `json:"Data"`
-}
-
-func NewEmployeeEvent(version, operation, timestamp, tenantGroupName string, data *EmployeeData) *EmployeeEvent {
-	return &EmployeeEvent{
-		TenantGroupEvent: event.TenantGroupEvent{
-			Event: event.Event{
-				Message: message.Message{
-					Type: string(DiscriminatorEmployee),
-				},
-				Version:   version,
-				Operation: operation,
-				Timestamp: timestamp,
-			},
-			TenantGroupName: tenantGroupName,
-		},
-		Data: data,
-	}
+	Data *EmployeeData `json:"Data" schema:"required"`
+}
+
+// Deprecated: Use NewEmployeeEventWithOptions, which derives the Type and defaults the version to event.LatestVersion and
+// the timestamp to the current UTC time.
+func NewEmployeeEvent(version string, operation event.Operation, timestamp time.Time, tenantGroupName string, data *EmployeeData) *EmployeeEvent {
+	return NewEmployeeEventWithOptions(operation, data, event.WithVersion(version), event.WithTimestamp(timestamp), event.WithTenantGroupName(tenantGroupName))
+}
+
+func NewEmployeeEventWithOptions(operation event.Operation, data *EmployeeData, options ...event.Option) *EmployeeEvent {
+	return event.New(&EmployeeEvent{Data: data}, operation, options...)
 }
 
 func (message *EmployeeEvent) Discriminator() pubsub.Discriminator {
@@ -116,32 +85,25 @@ func (message *EmployeeEvent) Discriminator() pubsub.Discriminator {
 }
 
 type PositionData struct {
-	Code          string `json:"Code"`
+	Code          string `json:"Code" schema:"required"`
 	Name          string `json:"Name"`
-	Scope         int    `json:"Scope"`
+	Scope         Scope  `json:"Scope"`
 	ReportsToCode string `json:"ReportsToCode"`
 }
 
 type PositionEvent struct {
 	event.TenantGroupEvent
-	Data *PositionData `json:"Data"`
-}
-
-func NewPositionEvent(version, operation, timestamp, tenantGroupName string, data *PositionData) *PositionEvent {
-	return &PositionEvent{
-		TenantGroupEvent: event.TenantGroupEvent{
-			Event: event.Event{
-				Message: message.Message{
-					Type: string(DiscriminatorPosition),
-				},
-				Version:   version,
-				Operation: operation,
-				Timestamp: timestamp,
-			},
-			TenantGroupName: tenantGroupName,
-		},
-		Data: data,
-	}
+	Data *PositionData `json:"Data" schema:"required"`
+}
+
+// Deprecated: Use NewPositionEventWithOptions, which derives the Type and defaults the version to event.LatestVersion and
+// the timestamp to the current UTC time.
+func NewPositionEvent(version string, operation event.Operation, timestamp time.Time, tenantGroupName string, data *PositionData) *PositionEvent {
+	return NewPositionEventWithOptions(operation, data, event.WithVersion(version), event.WithTimestamp(timestamp), event.WithTenantGroupName(tenantGroupName))
+}
+
+func NewPositionEventWithOptions(operation event.Operation, data *PositionData, options ...event.Option) *PositionEvent {
+	return event.New(&PositionEvent{Data: data}, operation, options...)
 }
 
 func (message *PositionEvent) Discriminator() pubsub.Discriminator {
@@ -149,32 +111,25 @@ func (message *PositionEvent) Discriminator() pubsub.Discriminator {
 }
 
 type RoleData struct {
-	Code        string   `json:"Code"`
+	Code        string   `json:"Code" schema:"required"`
 	Name        string   `json:"Name"`
-	Scope       int      `json:"Scope"`
+	Scope       Scope    `json:"Scope"`
 	Permissions []string `json:"Permissions"`
 }
 
 type RoleEvent struct {
 	event.TenantGroupEvent
-	Data *RoleData `json:"Data"`
-}
-
-func NewRoleEvent(version, operation, timestamp, tenantGroupName string, data *RoleData) *RoleEvent {
-	return &RoleEvent{
-		TenantGroupEvent: event.TenantGroupEvent{
-			Event: event.Event{
-				Message: message.Message{
-					Type: string(DiscriminatorRole),
-				},
-				Version:   version,
-				Operation: operation,
-				Timestamp: timestamp,
-			},
-			TenantGroupName: tenantGroupName,
-		},
-		Data: data,
-	}
+	Data *RoleData `json:"Data" schema:"required"`
+}
+
+// Deprecated: Use NewRoleEventWithOptions, which derives the Type and defaults the version to event.LatestVersion and
+// the timestamp to the current UTC time.
+func NewRoleEvent(version string, operation event.Operation, timestamp time.Time, tenantGroupName string, data *RoleData) *RoleEvent {
+	return NewRoleEventWithOptions(operation, data, event.WithVersion(version), event.WithTimestamp(timestamp), event.WithTenantGroupName(tenantGroupName))
+}
+
+func NewRoleEventWithOptions(operation event.Operation, data *RoleData, options ...event.Option) *RoleEvent {
+	return event.New(&RoleEvent{Data: data}, operation, options...)
 }
 
 func (message *RoleEvent) Discriminator() pubsub.Discriminator {
@@ -0,0 +1,84 @@
+package hr
+
+import (
+	"fmt"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/validation"
+)
+
+func (message *EmployeeEvent) Validate() error {
+	checker := validation.NewChecker()
+
+	message.ValidateEvent(checker)
+
+	if checker.Check(message.Data != nil, "Data", "is required") {
+		message.Data.validate(checker.Nested("Data"))
+	}
+
+	return checker.Err()
+}
+
+func (data *EmployeeData) validate(checker *validation.Checker) {
+	checker.Required("Code", data.Code)
+	checker.Valid("Scope", data.Scope)
+	checker.Valid("Status", data.Status)
+
+	for i, employee := range data.ReportsToEmployees {
+		field := fmt.Sprintf("ReportsToEmployees[%d]", i)
+
+		if checker.Check(employee != nil, field, "is required") {
+			nestedChecker := checker.Nested(field)
+
+			nestedChecker.Required("Code", employee.Code)
+			nestedChecker.Valid("Scope", employee.Scope)
+		}
+	}
+
+	if data.TenantGroup != nil {
+		for i, tenant := range data.TenantGroup.Tenants {
+			field := fmt.Sprintf("TenantGroup.Tenants[%d]", i)
+
+			if checker.Check(tenant != nil, field, "is required") {
+				checker.Nested(field).Required("Name", tenant.Name)
+			}
+		}
+	}
+
+	if data.PartnerGroup != nil {
+		nestedChecker := checker.Nested("PartnerGroup")
+
+		nestedChecker.Required("PartnerGroupCode", data.PartnerGroup.PartnerGroupCode)
+		nestedChecker.Valid("Qualification", data.PartnerGroup.Qualification)
+		nestedChecker.Valid("ContactTypes", data.PartnerGroup.ContactTypes)
+	}
+}
+
+func (message *PositionEvent) Validate() error {
+	checker := validation.NewChecker()
+
+	message.ValidateEvent(checker)
+
+	if checker.Check(message.Data != nil, "Data", "is required") {
+		dataChecker := checker.Nested("Data")
+
+		dataChecker.Required("Code", message.Data.Code)
+		dataChecker.Valid("Scope", message.Data.Scope)
+	}
+
+	return checker.Err()
+}
+
+func (message *RoleEvent) Validate() error {
+	checker := validation.NewChecker()
+
+	message.ValidateEvent(checker)
+
+	if checker.Check(message.Data != nil, "Data", "is required") {
+		dataChecker := checker.Nested("Data")
+
+		dataChecker.Required("Code", message.Data.Code)
+		dataChecker.Valid("Scope", message.Data.Scope)
+	}
+
+	return checker.Err()
+}
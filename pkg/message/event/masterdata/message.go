@@ -1,7 +1,8 @@
 package masterdata
 
 import (
-	"github.com/scaleforce/synchronization-for-go/pkg/message"
+	"time"
+
 	"github.com/scaleforce/synchronization-for-go/pkg/message/event"
 	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
 )
@@ -13,7 +14,7 @@ const (
 )
 
 type CityData struct {
-	Code        string  `json:"Code"`
+	Code        string  `json:"Code" schema:"required"`
 	Name        string  `json:"Name"`
 	TenantName  string  `json:"TenantName"`
 	StateName   string  `json:"StateName"`
@@ -24,24 +25,17 @@ type CityData struct {
 
 type CityEvent struct {
 	event.TenantGroupEvent
-	Data *CityData `json:"Data"`
+	Data *CityData `json:"Data" schema:"required"`
+}
+
+// Deprecated: Use NewCityEventWithOptions, which derives the Type and defaults the version to event.LatestVersion and
+// the timestamp to the current UTC time.
+func NewCityEvent(version string, operation event.Operation, timestamp time.Time, tenantGroupName string, data *CityData) *CityEvent {
+	return NewCityEventWithOptions(operation, data, event.WithVersion(version), event.WithTimestamp(timestamp), event.WithTenantGroupName(tenantGroupName))
 }
 
-func NewCityEvent(version, operation, timestamp, tenantGroupName string, data *CityData) *CityEvent {
-	return &CityEvent{
-		TenantGroupEvent: event.TenantGroupEvent{
-			Event: event.Event{
-				Message: message.Message{
-					Type: string(DiscriminatorCity),
-				},
-				Version:   version,
-				Operation: operation,
-				Timestamp: timestamp,
-			},
-			TenantGroupName: tenantGroupName,
-		},
-		Data: data,
-	}
+func NewCityEventWithOptions(operation event.Operation, data *CityData, options ...event.Option) *CityEvent {
+	return event.New(&CityEvent{Data: data}, operation, options...)
 }
 
 func (message *CityEvent) Discriminator() pubsub.Discriminator {
@@ -49,31 +43,24 @@ func (message *CityEvent) Discriminator() pubsub.Discriminator {
 }
 
 type CircleData struct {
-	Code       string `json:"Code"`
+	Code       string `json:"Code" schema:"required"`
 	Name       string `json:"Name"`
 	TenantName string `json:"TenantName"`
 }
 
 type CircleEvent struct {
 	event.TenantGroupEvent
-	Data *CircleData `json:"Data"`
+	Data *CircleData `json:"Data" schema:"required"`
 }
 
-func NewCircleEvent(version, operation, timestamp, tenantGroupName string, data *CircleData) *CircleEvent {
-	return &CircleEvent{
-		TenantGroupEvent: event.TenantGroupEvent{
-			Event: event.Event{
-				Message: message.Message{
-					Type: string(DiscriminatorCircle),
-				},
-				Version:   version,
-				Operation: operation,
-				Timestamp: timestamp,
-			},
-			TenantGroupName: tenantGroupName,
-		},
-		Data: data,
-	}
+// Deprecated: Use NewCircleEventWithOptions, which derives the Type and defaults the version to event.LatestVersion and
+// the timestamp to the current UTC time.
+func NewCircleEvent(version string, operation event.Operation, timestamp time.Time, tenantGroupName string, data *CircleData) *CircleEvent {
+	return NewCircleEventWithOptions(operation, data, event.WithVersion(version), event.WithTimestamp(timestamp), event.WithTenantGroupName(tenantGroupName))
+}
+
+func NewCircleEventWithOptions(operation event.Operation, data *CircleData, options ...event.Option) *CircleEvent {
+	return event.New(&CircleEvent{Data: data}, operation, options...)
 }
 
 func (message *CircleEvent) Discriminator() pubsub.Discriminator {
@@ -81,7 +68,7 @@ func (message *CircleEvent) Discriminator() pubsub.Discriminator {
 }
 
 type ZoneData struct {
-	Code       string `json:"Code"`
+	Code       string `json:"Code" schema:"required"`
 	Name       string `json:"Name"`
 	TenantName string `json:"TenantName"`
 	CircleCode string `json:"CircleCode"`
@@ -90,24 +77,17 @@ type ZoneData struct {
 
 type ZoneEvent struct {
 	event.TenantGroupEvent
-	Data *ZoneData `json:"Data"`
+	Data *ZoneData `json:"Data" schema:"required"`
+}
+
+// Deprecated: Use NewZoneEventWithOptions, which derives the Type and defaults the version to event.LatestVersion and
+// the timestamp to the current UTC time.
+func NewZoneEvent(version string, operation event.Operation, timestamp time.Time, tenantGroupName string, data *ZoneData) *ZoneEvent {
+	return NewZoneEventWithOptions(operation, data, event.WithVersion(version), event.WithTimestamp(timestamp), event.WithTenantGroupName(tenantGroupName))
 }
 
-func NewZoneEvent(version, operation, timestamp, tenantGroupName string, data *ZoneData) *ZoneEvent {
-	return &ZoneEvent{
-		TenantGroupEvent: event.TenantGroupEvent{
-			Event: event.Event{
-				Message: message.Message{
-					Type: string(DiscriminatorZone),
-				},
-				Version:   version,
-				Operation: operation,
-				Timestamp: timestamp,
-			},
-			TenantGroupName: tenantGroupName,
-		},
-		Data: data,
-	}
+func NewZoneEventWithOptions(operation event.Operation, data *ZoneData, options ...event.Option) *ZoneEvent {
+	return event.New(&ZoneEvent{Data: data}, operation, options...)
 }
 
 func (message *ZoneEvent) Discriminator() pubsub.Discriminator {
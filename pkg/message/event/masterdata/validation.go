@@ -0,0 +1,41 @@
+package masterdata
+
+import (
+	"github.com/scaleforce/synchronization-for-go/pkg/validation"
+)
+
+func (message *CityEvent) Validate() error {
+	checker := validation.NewChecker()
+
+	message.ValidateEvent(checker)
+
+	if checker.Check(message.Data != nil, "Data", "is required") {
+		checker.Nested("Data").Required("Code", message.Data.Code)
+	}
+
+	return checker.Err()
+}
+
+func (message *CircleEvent) Validate() error {
+	checker := validation.NewChecker()
+
+	message.ValidateEvent(checker)
+
+	if checker.Check(message.Data != nil, "Data", "is required") {
+		checker.Nested("Data").Required("Code", message.Data.Code)
+	}
+
+	return checker.Err()
+}
+
+func (message *ZoneEvent) Validate() error {
+	checker := validation.NewChecker()
+
+	message.ValidateEvent(checker)
+
+	if checker.Check(message.Data != nil, "Data", "is required") {
+		checker.Nested("Data").Required("Code", message.Data.Code)
+	}
+
+	return checker.Err()
+}
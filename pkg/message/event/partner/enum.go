@@ -0,0 +1,53 @@
+package partner
+
+//go:generate go run ../../../../cmd/enumgen -output enum_gen.go Scope Status ContactType
+
+type Scope int
+
+const (
+	ScopeNone Scope = iota
+	ScopeTenantGroup
+	ScopePartnerGroup
+)
+
+var scopeNames = map[Scope]string{
+	ScopeNone:         "None",
+	ScopeTenantGroup:  "TenantGroup",
+	ScopePartnerGroup: "PartnerGroup",
+}
+
+type Status int
+
+const (
+	StatusNone Status = iota
+	StatusActive
+	StatusInactive
+	StatusTerminated
+)
+
+var statusNames = map[Status]string{
+	StatusNone:       "None",
+	StatusActive:     "Active",
+	StatusInactive:   "Inactive",
+	StatusTerminated: "Terminated",
+}
+
+type ContactType int
+
+const (
+	ContactTypeNone ContactType = iota
+	ContactTypeGeneral
+	ContactTypeTechnical
+	ContactTypeOwner
+	ContactTypeBilling
+	ContactTypeShipping
+)
+
+var contactTypeNames = map[ContactType]string{
+	ContactTypeNone:      "None",
+	ContactTypeGeneral:   "General",
+	ContactTypeTechnical: "Technical",
+	ContactTypeOwner:     "Owner",
+	ContactTypeBilling:   "Billing",
+	ContactTypeShipping:  "Shipping",
+}
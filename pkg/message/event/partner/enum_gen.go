@@ -0,0 +1,159 @@
+// Code generated by enumgen. DO NOT EDIT.
+
+package partner
+
+import (
+	"encoding/json"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/message"
+)
+
+func (scope Scope) String() string {
+	return message.EnumString(scope, scopeNames)
+}
+
+func (scope Scope) Valid() bool {
+	_, ok := scopeNames[scope]
+
+	return ok
+}
+
+func (scope Scope) EnumValues() []any {
+	return message.EnumValues(scopeNames)
+}
+
+func (scope Scope) EnumNames() []string {
+	return message.EnumNames(scopeNames)
+}
+
+func (scope Scope) MarshalText() ([]byte, error) {
+	return []byte(scope.String()), nil
+}
+
+func (scope *Scope) UnmarshalText(data []byte) error {
+	value, err := message.ParseEnum(string(data), scopeNames)
+
+	if err != nil {
+		return err
+	}
+
+	*scope = value
+
+	return nil
+}
+
+func (scope Scope) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int(scope))
+}
+
+func (scope *Scope) UnmarshalJSON(data []byte) error {
+	value, err := message.UnmarshalEnumJSON(data, scopeNames)
+
+	if err != nil {
+		return err
+	}
+
+	*scope = value
+
+	return nil
+}
+
+func (status Status) String() string {
+	return message.EnumString(status, statusNames)
+}
+
+func (status Status) Valid() bool {
+	_, ok := statusNames[status]
+
+	return ok
+}
+
+func (status Status) EnumValues() []any {
+	return message.EnumValues(statusNames)
+}
+
+func (status Status) EnumNames() []string {
+	return message.EnumNames(statusNames)
+}
+
+func (status Status) MarshalText() ([]byte, error) {
+	return []byte(status.String()), nil
+}
+
+func (status *Status) UnmarshalText(data []byte) error {
+	value, err := message.ParseEnum(string(data), statusNames)
+
+	if err != nil {
+		return err
+	}
+
+	*status = value
+
+	return nil
+}
+
+func (status Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int(status))
+}
+
+func (status *Status) UnmarshalJSON(data []byte) error {
+	value, err := message.UnmarshalEnumJSON(data, statusNames)
+
+	if err != nil {
+		return err
+	}
+
+	*status = value
+
+	return nil
+}
+
+func (contactType ContactType) String() string {
+	return message.EnumString(contactType, contactTypeNames)
+}
+
+func (contactType ContactType) Valid() bool {
+	_, ok := contactTypeNames[contactType]
+
+	return ok
+}
+
+func (contactType ContactType) EnumValues() []any {
+	return message.EnumValues(contactTypeNames)
+}
+
+func (contactType ContactType) EnumNames() []string {
+	return message.EnumNames(contactTypeNames)
+}
+
+func (contactType ContactType) MarshalText() ([]byte, error) {
+	return []byte(contactType.String()), nil
+}
+
+func (contactType *ContactType) UnmarshalText(data []byte) error {
+	value, err := message.ParseEnum(string(data), contactTypeNames)
+
+	if err != nil {
+		return err
+	}
+
+	*contactType = value
+
+	return nil
+}
+
+func (contactType ContactType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int(contactType))
+}
+
+func (contactType *ContactType) UnmarshalJSON(data []byte) error {
+	value, err := message.UnmarshalEnumJSON(data, contactTypeNames)
+
+	if err != nil {
+		return err
+	}
+
+	*contactType = value
+
+	return nil
+}
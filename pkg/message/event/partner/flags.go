@@ -0,0 +1,44 @@
+package partner
+
+//go:generate go run ../../../../cmd/enumgen -flags -output flags_gen.go Infrastructures Services PaymentTypes
+
+type Infrastructures int
+
+const InfrastructuresNone Infrastructures = 0
+const (
+	InfrastructuresLAN Infrastructures = 1 << iota
+	InfrastructuresExcitelFiber
+	InfrastructuresERPFiber
+)
+
+var infrastructuresNames = map[Infrastructures]string{
+	InfrastructuresLAN:          "LAN",
+	InfrastructuresExcitelFiber: "ExcitelFiber",
+	InfrastructuresERPFiber:     "ERPFiber",
+}
+
+type Services int
+
+const ServicesNone Services = 0
+const (
+	ServicesInternet Services = 1 << iota
+	ServicesCableTV
+)
+
+var servicesNames = map[Services]string{
+	ServicesInternet: "Internet",
+	ServicesCableTV:  "CableTV",
+}
+
+type PaymentTypes int
+
+const PaymentTypesNone PaymentTypes = 0
+const (
+	PaymentTypesCash PaymentTypes = 1 << iota
+	PaymentTypesDigital
+)
+
+var paymentTypesNames = map[PaymentTypes]string{
+	PaymentTypesCash:    "Cash",
+	PaymentTypesDigital: "Digital",
+}
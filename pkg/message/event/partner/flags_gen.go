@@ -0,0 +1,204 @@
+// Code generated by enumgen. DO NOT EDIT.
+
+package partner
+
+import (
+	"encoding/json"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/message"
+)
+
+// Has reports whether all the given flags are set.
+func (infrastructures Infrastructures) Has(flags Infrastructures) bool {
+	return infrastructures&flags == flags
+}
+
+func (infrastructures *Infrastructures) Set(flags Infrastructures) {
+	*infrastructures |= flags
+}
+
+func (infrastructures *Infrastructures) Clear(flags Infrastructures) {
+	*infrastructures &^= flags
+}
+
+func (infrastructures Infrastructures) List() []Infrastructures {
+	return message.ListFlags(infrastructures)
+}
+
+func (infrastructures Infrastructures) String() string {
+	return message.FlagsString(infrastructures, infrastructuresNames)
+}
+
+func (infrastructures Infrastructures) Valid() bool {
+	return message.ValidFlags(infrastructures, infrastructuresNames)
+}
+
+func (infrastructures Infrastructures) EnumValues() []any {
+	return message.FlagsValues(infrastructuresNames)
+}
+
+func (infrastructures Infrastructures) FlagNames() []string {
+	return message.FlagNames(infrastructuresNames)
+}
+
+func (infrastructures Infrastructures) MarshalText() ([]byte, error) {
+	return []byte(infrastructures.String()), nil
+}
+
+func (infrastructures *Infrastructures) UnmarshalText(data []byte) error {
+	value, err := message.ParseFlags(string(data), infrastructuresNames)
+
+	if err != nil {
+		return err
+	}
+
+	*infrastructures = value
+
+	return nil
+}
+
+func (infrastructures Infrastructures) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int(infrastructures))
+}
+
+func (infrastructures *Infrastructures) UnmarshalJSON(data []byte) error {
+	value, err := message.UnmarshalFlagsJSON(data, infrastructuresNames)
+
+	if err != nil {
+		return err
+	}
+
+	*infrastructures = value
+
+	return nil
+}
+
+// Has reports whether all the given flags are set.
+func (services Services) Has(flags Services) bool {
+	return services&flags == flags
+}
+
+func (services *Services) Set(flags Services) {
+	*services |= flags
+}
+
+func (services *Services) Clear(flags Services) {
+	*services &^= flags
+}
+
+func (services Services) List() []Services {
+	return message.ListFlags(services)
+}
+
+func (services Services) String() string {
+	return message.FlagsString(services, servicesNames)
+}
+
+func (services Services) Valid() bool {
+	return message.ValidFlags(services, servicesNames)
+}
+
+func (services Services) EnumValues() []any {
+	return message.FlagsValues(servicesNames)
+}
+
+func (services Services) FlagNames() []string {
+	return message.FlagNames(servicesNames)
+}
+
+func (services Services) MarshalText() ([]byte, error) {
+	return []byte(services.String()), nil
+}
+
+func (services *Services) UnmarshalText(data []byte) error {
+	value, err := message.ParseFlags(string(data), servicesNames)
+
+	if err != nil {
+		return err
+	}
+
+	*services = value
+
+	return nil
+}
+
+func (services Services) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int(services))
+}
+
+func (services *Services) UnmarshalJSON(data []byte) error {
+	value, err := message.UnmarshalFlagsJSON(data, servicesNames)
+
+	if err != nil {
+		return err
+	}
+
+	*services = value
+
+	return nil
+}
+
+// Has reports whether all the given flags are set.
+func (paymentTypes PaymentTypes) Has(flags PaymentTypes) bool {
+	return paymentTypes&flags == flags
+}
+
+func (paymentTypes *PaymentTypes) Set(flags PaymentTypes) {
+	*paymentTypes |= flags
+}
+
+func (paymentTypes *PaymentTypes) Clear(flags PaymentTypes) {
+	*paymentTypes &^= flags
+}
+
+func (paymentTypes PaymentTypes) List() []PaymentTypes {
+	return message.ListFlags(paymentTypes)
+}
+
+func (paymentTypes PaymentTypes) String() string {
+	return message.FlagsString(paymentTypes, paymentTypesNames)
+}
+
+func (paymentTypes PaymentTypes) Valid() bool {
+	return message.ValidFlags(paymentTypes, paymentTypesNames)
+}
+
+func (paymentTypes PaymentTypes) EnumValues() []any {
+	return message.FlagsValues(paymentTypesNames)
+}
+
+func (paymentTypes PaymentTypes) FlagNames() []string {
+	return message.FlagNames(paymentTypesNames)
+}
+
+func (paymentTypes PaymentTypes) MarshalText() ([]byte, error) {
+	return []byte(paymentTypes.String()), nil
+}
+
+func (paymentTypes *PaymentTypes) UnmarshalText(data []byte) error {
+	value, err := message.ParseFlags(string(data), paymentTypesNames)
+
+	if err != nil {
+		return err
+	}
+
+	*paymentTypes = value
+
+	return nil
+}
+
+func (paymentTypes PaymentTypes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int(paymentTypes))
+}
+
+func (paymentTypes *PaymentTypes) UnmarshalJSON(data []byte) error {
+	value, err := message.UnmarshalFlagsJSON(data, paymentTypesNames)
+
+	if err != nil {
+		return err
+	}
+
+	*paymentTypes = value
+
+	return nil
+}
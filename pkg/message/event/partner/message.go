@@ -1,6 +1,8 @@
 package partner
 
 import (
+	"time"
+
 	"github.com/scaleforce/synchronization-for-go/pkg/message"
 	"github.com/scaleforce/synchronization-for-go/pkg/message/event"
 	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
@@ -11,21 +13,15 @@ const (
 	DiscriminatorPartner      pubsub.Discriminator = "Partner_Partner"
 )
 
-const (
-	ScopeNone int = iota
-	ScopeTenantGroup
-	ScopePartnerGroup
-)
-
 type PartnerGroupData struct {
-	Code               string  `json:"Code"`
+	Code               string  `json:"Code" schema:"required"`
 	Name               string  `json:"Name"`
 	TenantName         string  `json:"TenantName"`
 	CreditLimit        float64 `json:"CreditLimit"`
 	GroupOwnerCode     string  `json:"GroupOwnerCode"`
 	GroupOwnerUserId   string  `json:"GroupOwnerUserId"`
 	GroupOwnerUserName string  `json:"GroupOwnerUserName"`
-	GroupOwnerScope    int     `json:"GroupOwnerScope"`
+	GroupOwnerScope    Scope   `json:"GroupOwnerScope"`
 	CreatedByUserId    string  `json:"CreatedByUserId"`
 	CreatedByUserName  string  `json:"CreatedByUserName"`
 	ModifiedByUserId   string  `json:"ModifiedByUserId"`
@@ -34,73 +30,28 @@ type PartnerGroupData struct {
 
 type PartnerGroupEvent struct {
 	event.TenantGroupEvent
-	Data *PartnerGroupData `json:"Data"`
+	Data *PartnerGroupData `json:"Data" schema:"required"`
+}
+
+// Deprecated: Use NewPartnerGroupEventWithOptions, which derives the Type and defaults the version to event.LatestVersion and
+// the timestamp to the current UTC time.
+func NewPartnerGroupEvent(version string, operation event.Operation, timestamp time.Time, tenantGroupName string, data *PartnerGroupData) *PartnerGroupEvent {
+	return NewPartnerGroupEventWithOptions(operation, data, event.WithVersion(version), event.WithTimestamp(timestamp), event.WithTenantGroupName(tenantGroupName))
 }
 
-func NewPartnerGroupEvent(version, operation, timestamp, tenantGroupName string, data *PartnerGroupData) *PartnerGroupEvent {
-	return &PartnerGroupEvent{
-		TenantGroupEvent: event.TenantGroupEvent{
-			Event: event.Event{
-				Message: message.Message{
-					Type: string(DiscriminatorPartnerGroup),
-				},
-				Version:   version,
-				Operation: operation,
-				Timestamp: timestamp,
-			},
-			TenantGroupName: tenantGroupName,
-		},
-		Data: data,
-	}
+func NewPartnerGroupEventWithOptions(operation event.Operation, data *PartnerGroupData, options ...event.Option) *PartnerGroupEvent {
+	return event.New(&PartnerGroupEvent{Data: data}, operation, options...)
 }
 
 func (message *PartnerGroupEvent) Discriminator() pubsub.Discriminator {
 	return DiscriminatorPartnerGroup
 }
 
-// Flags for Infrastructures
-const InfrastructuresNone int = 0
-const (
-	InfrastructuresLAN int = 1 << iota
-	InfrastructuresExcitelFiber
-	InfrastructuresERPFiber
-)
-
-// Flags for Services
-const ServicesNone int = 0
-const (
-	ServicesInternet int = 1 << iota
-	ServicesCableTV
-)
-
-const (
-	StatusNone int = iota
-	StatusActive
-	StatusInactive
-	StatusTerminated
-)
-
-const (
-	ContactTypeNone int = iota
-	ContactTypeGeneral
-	ContactTypeTechnical
-	ContactTypeOwner
-	ContactTypeBilling
-	ContactTypeShipping
-)
-
-// Flags for PaymentTypes
-const PaymentTypesNone int = 0
-const (
-	PaymentTypesCash int = 1 << iota
-	PaymentTypesDigital
-)
-
 type EmployeeRelationModel struct {
 	UserId       string `json:"UserId"`
 	UserName     string `json:"UserName"`
-	Code         string `json:"Code"`
-	Scope        int    `json:"Scope"`
+	Code         string `json:"Code" schema:"required"`
+	Scope        Scope  `json:"Scope"`
 	PositionCode string `json:"PositionCode"`
 	PositionName string `json:"PositionName"`
 }
@@ -112,37 +63,37 @@ type PartnerTenantGroupEmployeeModel struct {
 }
 
 type ContactModel struct {
-	ContactType    int     `json:"ContactType"`
-	Name           string  `json:"Name"`
-	PrimaryPhone   string  `json:"PrimaryPhone"`
-	SecondaryPhone string  `json:"SecondaryPhone"`
-	LandlinePhone  string  `json:"LandlinePhone"`
-	Email          string  `json:"Email"`
-	CityCode       string  `json:"CityCode"`
-	CityName       string  `json:"CityName"`
-	StateName      string  `json:"StateName"`
-	SubareaName    string  `json:"SubareaName"`
-	PostalCode     string  `json:"PostalCode"`
-	Address        string  `json:"Address"`
-	Latitude       float64 `json:"Latitude"`
-	Longitude      float64 `json:"Longitude"`
+	ContactType    ContactType `json:"ContactType"`
+	Name           string      `json:"Name"`
+	PrimaryPhone   string      `json:"PrimaryPhone"`
+	SecondaryPhone string      `json:"SecondaryPhone"`
+	LandlinePhone  string      `json:"LandlinePhone"`
+	Email          string      `json:"Email"`
+	CityCode       string      `json:"CityCode"`
+	CityName       string      `json:"CityName"`
+	StateName      string      `json:"StateName"`
+	SubareaName    string      `json:"SubareaName"`
+	PostalCode     string      `json:"PostalCode"`
+	Address        string      `json:"Address"`
+	Latitude       float64     `json:"Latitude"`
+	Longitude      float64     `json:"Longitude"`
 }
 
 type PartnerData struct {
-	Code                      string                             `json:"Code"`
+	Code                      string                             `json:"Code" schema:"required"`
 	Name                      string                             `json:"Name"`
 	TenantName                string                             `json:"TenantName"`
 	PartnerGroupCode          string                             `json:"PartnerGroupCode"`
 	PartnerGroupName          string                             `json:"PartnerGroupName"`
-	CreatedTime               string                             `json:"CreatedTime"`
+	CreatedTime               message.Time                       `json:"CreatedTime"`
 	Nickname                  string                             `json:"Nickname"`
-	Infrastructures           int                                `json:"Infrastructures"`
-	Services                  int                                `json:"Services"`
-	Status                    int                                `json:"Status"`
+	Infrastructures           Infrastructures                    `json:"Infrastructures"`
+	Services                  Services                           `json:"Services"`
+	Status                    Status                             `json:"Status"`
 	Exclusive                 bool                               `json:"Exclusive"`
 	ActiveForSales            bool                               `json:"ActiveForSales"`
-	MigrationStartDate        string                             `json:"MigrationStartDate"`
-	MigrationEndDate          string                             `json:"MigrationEndDate"`
+	MigrationStartDate        message.Time                       `json:"MigrationStartDate"`
+	MigrationEndDate          message.Time                       `json:"MigrationEndDate"`
 	PlanBookCode              string                             `json:"PlanBookCode"`
 	PlanBookName              string                             `json:"PlanBookName"`
 	GSTIN                     string                             `json:"GSTIN"`
@@ -172,30 +123,23 @@ type PartnerData struct {
 	CreatedByUserName         string                             `json:"CreatedByUserName"`
 	ModifiedByUserId          string                             `json:"ModifiedByUserId"`
 	ModifiedByUserName        string                             `json:"ModifiedByUserName"`
-	SubscriberPaymentTypes    int                                `json:"SubscriberPaymentTypes"`
-	NewSubscriberPaymentTypes int                                `json:"NewSubscriberPaymentTypes"`
+	SubscriberPaymentTypes    PaymentTypes                       `json:"SubscriberPaymentTypes"`
+	NewSubscriberPaymentTypes PaymentTypes                       `json:"NewSubscriberPaymentTypes"`
 }
 
 type PartnerEvent struct {
 	event.TenantGroupEvent
-	Data *PartnerData `json:"Data"`
+	Data *PartnerData `json:"Data" schema:"required"`
+}
+
+// Deprecated: Use NewPartnerEventWithOptions, which derives the Type and defaults the version to event.LatestVersion and
+// the timestamp to the current UTC time.
+func NewPartnerEvent(version string, operation event.Operation, timestamp time.Time, tenantGroupName string, data *PartnerData) *PartnerEvent {
+	return NewPartnerEventWithOptions(operation, data, event.WithVersion(version), event.WithTimestamp(timestamp), event.WithTenantGroupName(tenantGroupName))
 }
 
-func NewPartnerEvent(version, operation, timestamp, tenantGroupName string, data *PartnerData) *PartnerEvent {
-	return &PartnerEvent{
-		TenantGroupEvent: event.TenantGroupEvent{
-			Event: event.Event{
-				Message: message.Message{
-					Type: string(DiscriminatorPartner),
-				},
-				Version:   version,
-				Operation: operation,
-				Timestamp: timestamp,
-			},
-			TenantGroupName: tenantGroupName,
-		},
-		Data: data,
-	}
+func NewPartnerEventWithOptions(operation event.Operation, data *PartnerData, options ...event.Option) *PartnerEvent {
+	return event.New(&PartnerEvent{Data: data}, operation, options...)
 }
 
 func (message *PartnerEvent) Discriminator() pubsub.Discriminator {
@@ -0,0 +1,66 @@
+package partner
+
+import (
+	"fmt"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/validation"
+)
+
+func (message *PartnerGroupEvent) Validate() error {
+	checker := validation.NewChecker()
+
+	message.ValidateEvent(checker)
+
+	if checker.Check(message.Data != nil, "Data", "is required") {
+		dataChecker := checker.Nested("Data")
+
+		dataChecker.Required("Code", message.Data.Code)
+		dataChecker.Valid("GroupOwnerScope", message.Data.GroupOwnerScope)
+	}
+
+	return checker.Err()
+}
+
+func (message *PartnerEvent) Validate() error {
+	checker := validation.NewChecker()
+
+	message.ValidateEvent(checker)
+
+	if checker.Check(message.Data != nil, "Data", "is required") {
+		message.Data.validate(checker.Nested("Data"))
+	}
+
+	return checker.Err()
+}
+
+func (data *PartnerData) validate(checker *validation.Checker) {
+	checker.Required("Code", data.Code)
+	checker.Valid("Infrastructures", data.Infrastructures)
+	checker.Valid("Services", data.Services)
+	checker.Valid("Status", data.Status)
+	checker.Valid("SubscriberPaymentTypes", data.SubscriberPaymentTypes)
+	checker.Valid("NewSubscriberPaymentTypes", data.NewSubscriberPaymentTypes)
+
+	if !data.MigrationStartDate.IsZero() && !data.MigrationEndDate.IsZero() {
+		checker.Check(!data.MigrationEndDate.Before(data.MigrationStartDate.Time), "MigrationEndDate", "is before MigrationStartDate")
+	}
+
+	for i, employee := range data.TenantGroupEmployees {
+		field := fmt.Sprintf("TenantGroupEmployees[%d]", i)
+
+		if checker.Check(employee != nil, field, "is required") {
+			nestedChecker := checker.Nested(field)
+
+			nestedChecker.Required("Code", employee.Code)
+			nestedChecker.Valid("Scope", employee.Scope)
+		}
+	}
+
+	for i, contact := range data.Contacts {
+		field := fmt.Sprintf("Contacts[%d]", i)
+
+		if checker.Check(contact != nil, field, "is required") {
+			checker.Nested(field).Valid("ContactType", contact.ContactType)
+		}
+	}
+}
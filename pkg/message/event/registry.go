@@ -0,0 +1,159 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+var (
+	ErrUnknownVersion = errors.New("unknown version")
+)
+
+type CreateMessageFunc func() pubsub.Message
+
+// UpcastFunc converts a message of an older version into a message of the next version.
+type UpcastFunc func(message pubsub.Message) (pubsub.Message, error)
+
+type registryKey struct {
+	discriminator pubsub.Discriminator
+	version       string
+}
+
+type registryEntry struct {
+	createMessageFunc CreateMessageFunc
+	// Empty for the current version.
+	toVersion  string
+	upcastFunc UpcastFunc
+}
+
+// Registry maps the discriminator and version of the messages to their types. The messages of the current version
+// are unmarshaled into their type, and the messages of older versions are unmarshaled into the type of their version,
+// then upcast version by version into the current version, so that the handlers only deal with the current types.
+//
+// E.g. once PartnerData has a version 2, the version 1 types are kept (e.g. as PartnerEventV1) and registered with
+// an upcaster into version 2, and the subscribers are deployed before the publishers start publishing version 2.
+type Registry struct {
+	entries         map[registryKey]*registryEntry
+	currentVersions map[pubsub.Discriminator]string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		entries:         map[registryKey]*registryEntry{},
+		currentVersions: map[pubsub.Discriminator]string{},
+	}
+}
+
+// Register registers the type of the current version of the messages of discriminator.
+func (registry *Registry) Register(discriminator pubsub.Discriminator, version string, createMessageFunc CreateMessageFunc) {
+	registry.entries[registryKey{discriminator: discriminator, version: version}] = &registryEntry{
+		createMessageFunc: createMessageFunc,
+	}
+
+	registry.currentVersions[discriminator] = version
+}
+
+// RegisterUpcaster registers the type of an older version of the messages of discriminator, and the function that
+// upcasts its messages into toVersion, which must be registered too.
+func (registry *Registry) RegisterUpcaster(discriminator pubsub.Discriminator, version string, toVersion string, createMessageFunc CreateMessageFunc, upcastFunc UpcastFunc) {
+	registry.entries[registryKey{discriminator: discriminator, version: version}] = &registryEntry{
+		createMessageFunc: createMessageFunc,
+		toVersion:         toVersion,
+		upcastFunc:        upcastFunc,
+	}
+}
+
+// Registered reports whether any version of the messages of discriminator is registered.
+func (registry *Registry) Registered(discriminator pubsub.Discriminator) bool {
+	_, ok := registry.currentVersions[discriminator]
+
+	return ok
+}
+
+// Discriminators returns the registered discriminators, sorted.
+func (registry *Registry) Discriminators() []pubsub.Discriminator {
+	discriminators := make([]pubsub.Discriminator, 0, len(registry.currentVersions))
+
+	for discriminator := range registry.currentVersions {
+		discriminators = append(discriminators, discriminator)
+	}
+
+	slices.Sort(discriminators)
+
+	return discriminators
+}
+
+func (registry *Registry) CurrentVersion(discriminator pubsub.Discriminator) (string, bool) {
+	version, ok := registry.currentVersions[discriminator]
+
+	return version, ok
+}
+
+// CreateMessage returns a message of the current version of discriminator, or nil if discriminator is not registered.
+func (registry *Registry) CreateMessage(discriminator pubsub.Discriminator) pubsub.Message {
+	version, ok := registry.currentVersions[discriminator]
+
+	if !ok {
+		return nil
+	}
+
+	return registry.entries[registryKey{discriminator: discriminator, version: version}].createMessageFunc()
+}
+
+// Unmarshal unmarshals data into the type of version and upcasts it into the current version. An empty version is
+// Version1, for the publishers that do not set it. It returns an error wrapping ErrUnknownVersion for the versions
+// that are not registered, e.g. newer versions than the current version of the subscriber.
+func (registry *Registry) Unmarshal(discriminator pubsub.Discriminator, version string, data []byte) (pubsub.Message, error) {
+	if version == "" {
+		version = Version1
+	}
+
+	entry, ok := registry.entries[registryKey{discriminator: discriminator, version: version}]
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s version %q", ErrUnknownVersion, discriminator, version)
+	}
+
+	message := entry.createMessageFunc()
+
+	if err := json.Unmarshal(data, message); err != nil {
+		return nil, err
+	}
+
+	// The versions that were already upcast, to detect the upcasters that loop.
+	versions := map[string]struct{}{version: {}}
+
+	for entry.upcastFunc != nil {
+		if _, ok := versions[entry.toVersion]; ok {
+			return nil, fmt.Errorf("%w: %s version %q is upcast in a loop", ErrUnknownVersion, discriminator, entry.toVersion)
+		}
+
+		upcastMessage, err := entry.upcastFunc(message)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s version %q was not upcast: %w", discriminator, version, err)
+		}
+
+		version = entry.toVersion
+
+		versions[version] = struct{}{}
+
+		if eventMessage, ok := upcastMessage.(EventMessage); ok {
+			eventMessage.GetEvent().Version = version
+		}
+
+		entry, ok = registry.entries[registryKey{discriminator: discriminator, version: version}]
+
+		if !ok {
+			return nil, fmt.Errorf("%w: %s version %q", ErrUnknownVersion, discriminator, version)
+		}
+
+		message = upcastMessage
+	}
+
+	return message, nil
+}
@@ -0,0 +1,126 @@
+package event
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+const testDiscriminator pubsub.Discriminator = "Test_Person"
+
+type testPersonEventV1 struct {
+	Event
+	Name string `json:"Name"`
+}
+
+func (event *testPersonEventV1) Discriminator() pubsub.Discriminator {
+	return testDiscriminator
+}
+
+type testPersonEvent struct {
+	Event
+	FirstName string `json:"FirstName"`
+}
+
+func (event *testPersonEvent) Discriminator() pubsub.Discriminator {
+	return testDiscriminator
+}
+
+func upcastTestPersonEventV1(message pubsub.Message) (pubsub.Message, error) {
+	eventV1 := message.(*testPersonEventV1)
+
+	return &testPersonEvent{
+		Event:     eventV1.Event,
+		FirstName: eventV1.Name,
+	}, nil
+}
+
+func newTestRegistry() *Registry {
+	registry := NewRegistry()
+
+	registry.Register(testDiscriminator, "2", func() pubsub.Message { return &testPersonEvent{} })
+	registry.RegisterUpcaster(testDiscriminator, Version1, "2", func() pubsub.Message { return &testPersonEventV1{} }, upcastTestPersonEventV1)
+
+	return registry
+}
+
+func TestUnmarshalUpcastsTheOlderVersions(t *testing.T) {
+	registry := newTestRegistry()
+
+	// The messages without version are version 1.
+	for _, version := range []string{Version1, ""} {
+		message, err := registry.Unmarshal(testDiscriminator, version, []byte(`{"Version":"1","Operation":"Add","Name":"Jane"}`))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		event, ok := message.(*testPersonEvent)
+
+		if !ok {
+			t.Fatalf("message is %T, expected *testPersonEvent", message)
+		}
+
+		if event.FirstName != "Jane" || event.Operation != OperationAdd {
+			t.Fatalf("unexpected event %+v", event)
+		}
+
+		if event.Version != "2" {
+			t.Fatalf("version is %q, expected \"2\"", event.Version)
+		}
+	}
+
+	message, err := registry.Unmarshal(testDiscriminator, "2", []byte(`{"Version":"2","Operation":"Add","FirstName":"John"}`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event := message.(*testPersonEvent); event.FirstName != "John" || event.Version != "2" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+}
+
+func TestUnmarshalRejectsTheUnknownVersions(t *testing.T) {
+	registry := newTestRegistry()
+
+	if _, err := registry.Unmarshal(testDiscriminator, "3", []byte(`{}`)); !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("error is %v, expected %v", err, ErrUnknownVersion)
+	}
+
+	if _, err := registry.Unmarshal("Unknown", Version1, []byte(`{}`)); !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("error is %v, expected %v", err, ErrUnknownVersion)
+	}
+}
+
+func TestUnmarshalDetectsTheUpcastersThatLoop(t *testing.T) {
+	registry := NewRegistry()
+
+	upcastFunc := func(message pubsub.Message) (pubsub.Message, error) {
+		return message, nil
+	}
+
+	registry.Register(testDiscriminator, "3", func() pubsub.Message { return &testPersonEvent{} })
+	registry.RegisterUpcaster(testDiscriminator, Version1, "2", func() pubsub.Message { return &testPersonEvent{} }, upcastFunc)
+	registry.RegisterUpcaster(testDiscriminator, "2", Version1, func() pubsub.Message { return &testPersonEvent{} }, upcastFunc)
+
+	if _, err := registry.Unmarshal(testDiscriminator, Version1, []byte(`{}`)); !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("error is %v, expected %v", err, ErrUnknownVersion)
+	}
+}
+
+func TestUnmarshalReportsTheUpcastErrors(t *testing.T) {
+	registry := NewRegistry()
+
+	errUpcast := errors.New("upcast failed")
+
+	registry.Register(testDiscriminator, "2", func() pubsub.Message { return &testPersonEvent{} })
+	registry.RegisterUpcaster(testDiscriminator, Version1, "2", func() pubsub.Message { return &testPersonEventV1{} }, func(message pubsub.Message) (pubsub.Message, error) {
+		return nil, errUpcast
+	})
+
+	if _, err := registry.Unmarshal(testDiscriminator, Version1, []byte(`{}`)); !errors.Is(err, errUpcast) {
+		t.Fatalf("error is %v, expected %v", err, errUpcast)
+	}
+}
@@ -0,0 +1,23 @@
+package event
+
+import (
+	"github.com/scaleforce/synchronization-for-go/pkg/validation"
+)
+
+// The methods below check the common fields of the events, they are called by the Validate methods of the event models.
+
+func (event *Event) ValidateEvent(checker *validation.Checker) {
+	checker.Valid("Operation", event.Operation)
+}
+
+func (event *TenantGroupEvent) ValidateEvent(checker *validation.Checker) {
+	event.Event.ValidateEvent(checker)
+
+	checker.Required("TenantGroupName", event.TenantGroupName)
+}
+
+func (event *TenantEvent) ValidateEvent(checker *validation.Checker) {
+	event.TenantGroupEvent.ValidateEvent(checker)
+
+	checker.Required("TenantName", event.TenantName)
+}
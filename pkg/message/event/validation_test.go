@@ -0,0 +1,108 @@
+package event_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/message"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event/hr"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event/masterdata"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event/partner"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event/xnms"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+	"github.com/scaleforce/synchronization-for-go/pkg/validation"
+)
+
+func fieldsOf(t *testing.T, err error) []string {
+	t.Helper()
+
+	if err == nil {
+		return nil
+	}
+
+	var validationErr *validation.ValidationError
+
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("error is %v, expected a *validation.ValidationError", err)
+	}
+
+	fields := make([]string, 0, len(validationErr.Errs))
+
+	for _, fieldErr := range validationErr.Errs {
+		fields = append(fields, fieldErr.Field)
+	}
+
+	return fields
+}
+
+func TestValidateChecksTheMessageModels(t *testing.T) {
+	group := event.WithTenantGroupName("group")
+
+	later := message.NewTime(time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC))
+	earlier := message.NewTime(time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name     string
+		message  pubsub.Message
+		expected []string
+	}{
+		{"valid employee", hr.NewEmployeeEventWithOptions(event.OperationAddOrSet, &hr.EmployeeData{Code: "1", Scope: hr.ScopeTenantGroup, Status: hr.StatusActive}, group), nil},
+		{"employee without tenant group name", hr.NewEmployeeEventWithOptions(event.OperationAddOrSet, &hr.EmployeeData{Code: "1"}), []string{"TenantGroupName"}},
+		{"employee with blank tenant group name", hr.NewEmployeeEventWithOptions(event.OperationAddOrSet, &hr.EmployeeData{Code: "1"}, event.WithTenantGroupName(" ")), []string{"TenantGroupName"}},
+		{"employee with invalid operation", hr.NewEmployeeEventWithOptions(event.Operation("Unknown"), &hr.EmployeeData{Code: "1"}, group), []string{"Operation"}},
+		{"employee without data", hr.NewEmployeeEventWithOptions(event.OperationAddOrSet, nil, group), []string{"Data"}},
+		{"employee without code", hr.NewEmployeeEventWithOptions(event.OperationAddOrSet, &hr.EmployeeData{}, group), []string{"Data.Code"}},
+		{"employee with enums out of range", hr.NewEmployeeEventWithOptions(event.OperationAddOrSet, &hr.EmployeeData{Code: "1", Scope: hr.Scope(9), Status: hr.Status(-1)}, group), []string{"Data.Scope", "Data.Status"}},
+		{"employee with invalid relations", hr.NewEmployeeEventWithOptions(event.OperationAddOrSet, &hr.EmployeeData{
+			Code:               "1",
+			ReportsToEmployees: []*hr.EmployeeRelationModel{{Code: "2"}, nil, {Scope: hr.Scope(9)}},
+			TenantGroup:        &hr.EmployeeTenantGroupModel{Tenants: []*hr.TenantModel{{Name: ""}}},
+			PartnerGroup:       &hr.EmployeePartnerGroupModel{Qualification: hr.Qualification(9), ContactTypes: hr.ContactTypes(1 << 10)},
+		}, group), []string{"Data.ReportsToEmployees[1]", "Data.ReportsToEmployees[2].Code", "Data.ReportsToEmployees[2].Scope", "Data.TenantGroup.Tenants[0].Name", "Data.PartnerGroup.PartnerGroupCode", "Data.PartnerGroup.Qualification", "Data.PartnerGroup.ContactTypes"}},
+		{"position with scope out of range", hr.NewPositionEventWithOptions(event.OperationAddOrSet, &hr.PositionData{Code: "1", Scope: hr.Scope(9)}, group), []string{"Data.Scope"}},
+		{"role without data", hr.NewRoleEventWithOptions(event.OperationRemove, nil, group), []string{"Data"}},
+		{"valid city", masterdata.NewCityEventWithOptions(event.OperationAddOrSet, &masterdata.CityData{Code: "1"}, group), nil},
+		{"circle without code", masterdata.NewCircleEventWithOptions(event.OperationAddOrSet, &masterdata.CircleData{Code: " "}, group), []string{"Data.Code"}},
+		{"zone without tenant group name and data", masterdata.NewZoneEventWithOptions(event.OperationAddOrSet, nil), []string{"TenantGroupName", "Data"}},
+		{"partner group with scope out of range", partner.NewPartnerGroupEventWithOptions(event.OperationAddOrSet, &partner.PartnerGroupData{Code: "1", GroupOwnerScope: partner.Scope(9)}, group), []string{"Data.GroupOwnerScope"}},
+		{"valid partner", partner.NewPartnerEventWithOptions(event.OperationAddOrSet, &partner.PartnerData{Code: "1", Infrastructures: partner.InfrastructuresLAN | partner.InfrastructuresERPFiber, MigrationStartDate: earlier, MigrationEndDate: later}, group), nil},
+		{"partner with flags out of range", partner.NewPartnerEventWithOptions(event.OperationAddOrSet, &partner.PartnerData{Code: "1", Infrastructures: partner.Infrastructures(1 << 10), Services: partner.Services(1 << 10), Status: partner.Status(9)}, group), []string{"Data.Infrastructures", "Data.Services", "Data.Status"}},
+		{"partner with migration end before start", partner.NewPartnerEventWithOptions(event.OperationAddOrSet, &partner.PartnerData{Code: "1", MigrationStartDate: later, MigrationEndDate: earlier}, group), []string{"Data.MigrationEndDate"}},
+		{"partner with invalid relations", partner.NewPartnerEventWithOptions(event.OperationAddOrSet, &partner.PartnerData{
+			Code:                 "1",
+			TenantGroupEmployees: []*partner.PartnerTenantGroupEmployeeModel{nil, {EmployeeRelationModel: partner.EmployeeRelationModel{Code: "2", Scope: partner.Scope(9)}}},
+			Contacts:             []*partner.ContactModel{{ContactType: partner.ContactType(9)}, nil},
+		}, group), []string{"Data.TenantGroupEmployees[0]", "Data.TenantGroupEmployees[1].Scope", "Data.Contacts[0].ContactType", "Data.Contacts[1]"}},
+		{"valid device", xnms.NewDeviceEventWithOptions(event.OperationAddOrSet, &xnms.DeviceData{Code: "1", Status: xnms.StatusOnline}, group), nil},
+		{"device with status out of range", xnms.NewDeviceEventWithOptions(event.OperationAddOrSet, &xnms.DeviceData{Code: "1", Status: xnms.Status(9)}, group), []string{"Data.Status"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validation.Validate(test.message)
+
+			if fields := fieldsOf(t, err); !slices.Equal(fields, test.expected) {
+				t.Fatalf("fields are %v, expected %v", fields, test.expected)
+			}
+
+			if test.expected != nil && !errors.Is(err, validation.ErrInvalidMessage) {
+				t.Fatalf("error is %v, expected %v", err, validation.ErrInvalidMessage)
+			}
+		})
+	}
+}
+
+func TestValidateEventChecksTheTenantName(t *testing.T) {
+	checker := validation.NewChecker()
+
+	tenantEvent := event.New(&testTenantEvent{}, event.OperationAddOrSet, event.WithTenantGroupName("group"))
+
+	tenantEvent.ValidateEvent(checker)
+
+	if fields := fieldsOf(t, checker.Err()); !slices.Equal(fields, []string{"TenantName"}) {
+		t.Fatalf("fields are %v, expected [TenantName]", fields)
+	}
+}
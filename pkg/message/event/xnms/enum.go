@@ -0,0 +1,17 @@
+package xnms
+
+//go:generate go run ../../../../cmd/enumgen -output enum_gen.go Status
+
+type Status int
+
+const (
+	StatusNone Status = iota
+	StatusOffline
+	StatusOnline
+)
+
+var statusNames = map[Status]string{
+	StatusNone:    "None",
+	StatusOffline: "Offline",
+	StatusOnline:  "Online",
+}
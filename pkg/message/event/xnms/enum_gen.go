@@ -0,0 +1,59 @@
+// Code generated by enumgen. DO NOT EDIT.
+
+package xnms
+
+import (
+	"encoding/json"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/message"
+)
+
+func (status Status) String() string {
+	return message.EnumString(status, statusNames)
+}
+
+func (status Status) Valid() bool {
+	_, ok := statusNames[status]
+
+	return ok
+}
+
+func (status Status) EnumValues() []any {
+	return message.EnumValues(statusNames)
+}
+
+func (status Status) EnumNames() []string {
+	return message.EnumNames(statusNames)
+}
+
+func (status Status) MarshalText() ([]byte, error) {
+	return []byte(status.String()), nil
+}
+
+func (status *Status) UnmarshalText(data []byte) error {
+	value, err := message.ParseEnum(string(data), statusNames)
+
+	if err != nil {
+		return err
+	}
+
+	*status = value
+
+	return nil
+}
+
+func (status Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int(status))
+}
+
+func (status *Status) UnmarshalJSON(data []byte) error {
+	value, err := message.UnmarshalEnumJSON(data, statusNames)
+
+	if err != nil {
+		return err
+	}
+
+	*status = value
+
+	return nil
+}
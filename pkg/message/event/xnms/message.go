@@ -1,7 +1,8 @@
 package xnms
 
 import (
-	"github.com/scaleforce/synchronization-for-go/pkg/message"
+	"time"
+
 	"github.com/scaleforce/synchronization-for-go/pkg/message/event"
 	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
 )
@@ -10,40 +11,27 @@ const (
 	DiscriminatorDevice pubsub.Discriminator = "XNMS_Device"
 )
 
-const (
-	StatusNone int = iota
-	StatusOffline
-	StatusOnline
-)
-
 type DeviceData struct {
-	Code         string `json:"Code"`
+	Code         string `json:"Code" schema:"required"`
 	SerialNumber string `json:"SerialNumber"`
 	TenantName   string `json:"TenantName"`
-	Status       int    `json:"Status"`
+	Status       Status `json:"Status"`
 }
 
 // Fictitious XNMS_Device event for demonstration purposes.
 type DeviceEvent struct {
 	event.TenantGroupEvent
-	Data *DeviceData `json:"Data"`
+	Data *DeviceData `json:"Data" schema:"required"`
+}
+
+// Deprecated: Use NewDeviceEventWithOptions, which derives the Type and defaults the version to event.LatestVersion and
+// the timestamp to the current UTC time.
+func NewDeviceEvent(version string, operation event.Operation, timestamp time.Time, tenantGroupName string, data *DeviceData) *DeviceEvent {
+	return NewDeviceEventWithOptions(operation, data, event.WithVersion(version), event.WithTimestamp(timestamp), event.WithTenantGroupName(tenantGroupName))
 }
 
-func NewDeviceEvent(version, operation, timestamp, tenantGroupName string, data *DeviceData) *DeviceEvent {
-	return &DeviceEvent{
-		TenantGroupEvent: event.TenantGroupEvent{
-			Event: event.Event{
-				Message: message.Message{
-					Type: string(DiscriminatorDevice),
-				},
-				Version:   version,
-				Operation: operation,
-				Timestamp: timestamp,
-			},
-			TenantGroupName: tenantGroupName,
-		},
-		Data: data,
-	}
+func NewDeviceEventWithOptions(operation event.Operation, data *DeviceData, options ...event.Option) *DeviceEvent {
+	return event.New(&DeviceEvent{Data: data}, operation, options...)
 }
 
 func (message *DeviceEvent) Discriminator() pubsub.Discriminator {
@@ -0,0 +1,20 @@
+package xnms
+
+import (
+	"github.com/scaleforce/synchronization-for-go/pkg/validation"
+)
+
+func (message *DeviceEvent) Validate() error {
+	checker := validation.NewChecker()
+
+	message.ValidateEvent(checker)
+
+	if checker.Check(message.Data != nil, "Data", "is required") {
+		dataChecker := checker.Nested("Data")
+
+		dataChecker.Required("Code", message.Data.Code)
+		dataChecker.Valid("Status", message.Data.Status)
+	}
+
+	return checker.Err()
+}
@@ -0,0 +1,150 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// The functions below implement the flag sets of the message models, which are integer bit masks on the wire, but can
+// also be parsed from the names of their flags separated by "|" or ",", e.g. in configuration. names must not contain
+// the zero value.
+
+// ListFlags returns the flags set in value, in ascending order, including the flags that have no name.
+func ListFlags[T ~int](value T) []T {
+	flags := make([]T, 0, bits.OnesCount(uint(value)))
+
+	for remaining := uint(value); remaining != 0; remaining &= remaining - 1 {
+		flags = append(flags, T(remaining&-remaining))
+	}
+
+	return flags
+}
+
+// FlagsString returns the names of the flags set in value separated by "|", the numbers of the flags that have no name,
+// or "None" if no flag is set.
+func FlagsString[T ~int](value T, names map[T]string) string {
+	if value == 0 {
+		return "None"
+	}
+
+	flagNames := make([]string, 0, bits.OnesCount(uint(value)))
+
+	for _, flag := range ListFlags(value) {
+		if name, ok := names[flag]; ok {
+			flagNames = append(flagNames, name)
+		} else {
+			flagNames = append(flagNames, strconv.Itoa(int(flag)))
+		}
+	}
+
+	return strings.Join(flagNames, "|")
+}
+
+// ValidFlags reports whether all the flags set in value have a name.
+func ValidFlags[T ~int](value T, names map[T]string) bool {
+	for _, flag := range ListFlags(value) {
+		if _, ok := names[flag]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParseFlags accepts the number of the bit mask, or the names of the flags, case-insensitive, separated by "|" or ",".
+// "None" and an empty string are parsed as no flag. Only the flags that have a name are accepted.
+func ParseFlags[T ~int](value string, names map[T]string) (T, error) {
+	value = strings.TrimSpace(value)
+
+	if number, err := strconv.Atoi(value); err == nil {
+		if !ValidFlags(T(number), names) {
+			return 0, fmt.Errorf("%w: %d", ErrInvalidEnum, number)
+		}
+
+		return T(number), nil
+	}
+
+	var flags T
+
+	for _, flagName := range strings.FieldsFunc(value, func(r rune) bool { return r == '|' || r == ',' }) {
+		flagName = strings.TrimSpace(flagName)
+
+		if strings.EqualFold(flagName, "None") {
+			continue
+		}
+
+		found := false
+
+		for flag, name := range names {
+			if strings.EqualFold(name, flagName) {
+				flags |= flag
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidEnum, flagName)
+		}
+	}
+
+	return flags, nil
+}
+
+// UnmarshalFlagsJSON accepts a JSON number, or a JSON string with a number or the names accepted by ParseFlags. The bit
+// masks with flags that have no name are accepted too, so that they are reported with the other fields by the validation
+// of the message.
+func UnmarshalFlagsJSON[T ~int](data []byte, names map[T]string) (T, error) {
+	data = bytes.TrimSpace(data)
+
+	if bytes.HasPrefix(data, []byte(`"`)) {
+		var value string
+
+		if err := json.Unmarshal(data, &value); err != nil {
+			return 0, err
+		}
+
+		if number, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+			return T(number), nil
+		}
+
+		return ParseFlags(value, names)
+	}
+
+	var number int
+
+	if err := json.Unmarshal(data, &number); err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidEnum, data)
+	}
+
+	return T(number), nil
+}
+
+// FlagsValues returns the numbers of all the combinations of the flags that have a name, in ascending order, e.g. for
+// JSON Schemas.
+func FlagsValues[T ~int](names map[T]string) []any {
+	var mask T
+
+	for flag := range names {
+		mask |= flag
+	}
+
+	values := []any{0}
+
+	// Iterates over the subsets of mask in ascending order.
+	for subset := mask & -mask; subset != 0; subset = (subset - mask) & mask {
+		values = append(values, int(subset))
+	}
+
+	return values
+}
+
+// FlagNames returns the names of the flags, in the ascending order of the flags, e.g. for JSON Schemas.
+func FlagNames[T ~int](names map[T]string) []string {
+	return EnumNames(names)
+}
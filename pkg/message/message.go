@@ -1,5 +1,5 @@
 package message
 
 type Message struct {
-	Type string `json:"Type"`
+	Type string `json:"Type" schema:"required"`
 }
@@ -0,0 +1,180 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type ChangeKind string
+
+const (
+	// The messages of one of the versions are rejected by the subscribers of the other version.
+	ChangeKindBreaking ChangeKind = "Breaking"
+	// The messages of both versions are accepted by the subscribers of both versions.
+	ChangeKindAdditive ChangeKind = "Additive"
+)
+
+type Change struct {
+	// Path of the property, e.g. "Data.Contacts[].ContactType", or "$" for the root.
+	Path        string
+	Kind        ChangeKind
+	Description string
+}
+
+func (change *Change) String() string {
+	return fmt.Sprintf("%s %s: %s", change.Kind, change.Path, change.Description)
+}
+
+type Changes []*Change
+
+// Breaking reports whether any of the changes is breaking.
+func (changes Changes) Breaking() bool {
+	return slices.ContainsFunc(changes, func(change *Change) bool { return change.Kind == ChangeKindBreaking })
+}
+
+func (changes Changes) String() string {
+	changeStrs := make([]string, 0, len(changes))
+
+	for _, change := range changes {
+		changeStrs = append(changeStrs, change.String())
+	}
+
+	return strings.Join(changeStrs, "\n")
+}
+
+// Compare returns the changes from oldSchema to newSchema, in the order of the paths. The compatibility is checked in
+// both directions, because the publishers and the subscribers of the messages are not deployed at the same time:
+//   - Adding an optional property is additive.
+//   - Adding a required property, removing a property or making a property required or optional is breaking.
+//   - Changing the type of a property, including making it nullable, is breaking.
+//   - Changing the values of an enum is breaking, because the subscribers reject the values they do not know.
+//   - Changing the minimum length or the pattern of a string, or the schemas of an anyOf, is breaking.
+func Compare(oldSchema *Schema, newSchema *Schema) Changes {
+	changes := Changes{}
+
+	compare(&changes, rootField, oldSchema, newSchema)
+
+	slices.SortStableFunc(changes, func(change1 *Change, change2 *Change) int {
+		return strings.Compare(change1.Path, change2.Path)
+	})
+
+	return changes
+}
+
+func compare(changes *Changes, path string, oldSchema *Schema, newSchema *Schema) {
+	addChange := func(path string, kind ChangeKind, description string) {
+		*changes = append(*changes, &Change{
+			Path:        path,
+			Kind:        kind,
+			Description: description,
+		})
+	}
+
+	if !sameSet(oldSchema.Type, newSchema.Type) {
+		addChange(path, ChangeKindBreaking, fmt.Sprintf("type was changed from %s to %s", key(oldSchema.Type), key(newSchema.Type)))
+	}
+
+	if key(oldSchema.Const) != key(newSchema.Const) {
+		addChange(path, ChangeKindBreaking, fmt.Sprintf("const was changed from %s to %s", key(oldSchema.Const), key(newSchema.Const)))
+	}
+
+	if !sameSet(oldSchema.Enum, newSchema.Enum) {
+		addChange(path, ChangeKindBreaking, fmt.Sprintf("enum was changed from %s to %s", key(oldSchema.Enum), key(newSchema.Enum)))
+	}
+
+	if key(oldSchema.Minimum) != key(newSchema.Minimum) {
+		addChange(path, ChangeKindBreaking, fmt.Sprintf("minimum was changed from %s to %s", key(oldSchema.Minimum), key(newSchema.Minimum)))
+	}
+
+	if key(oldSchema.MinLength) != key(newSchema.MinLength) {
+		addChange(path, ChangeKindBreaking, fmt.Sprintf("minimum length was changed from %s to %s", key(oldSchema.MinLength), key(newSchema.MinLength)))
+	}
+
+	if oldSchema.Pattern != newSchema.Pattern {
+		addChange(path, ChangeKindBreaking, fmt.Sprintf("pattern was changed from %q to %q", oldSchema.Pattern, newSchema.Pattern))
+	}
+
+	if len(oldSchema.AnyOf) == len(newSchema.AnyOf) {
+		for i := range oldSchema.AnyOf {
+			compare(changes, path, oldSchema.AnyOf[i], newSchema.AnyOf[i])
+		}
+	} else {
+		addChange(path, ChangeKindBreaking, "anyOf schemas were changed")
+	}
+
+	if oldSchema.Items != nil && newSchema.Items != nil {
+		compare(changes, path+"[]", oldSchema.Items, newSchema.Items)
+	} else if oldSchema.Items != nil || newSchema.Items != nil {
+		addChange(path, ChangeKindBreaking, "items were changed")
+	}
+
+	if oldSchema.AdditionalProperties != nil && newSchema.AdditionalProperties != nil {
+		compare(changes, path+"{}", oldSchema.AdditionalProperties, newSchema.AdditionalProperties)
+	} else if oldSchema.AdditionalProperties != nil || newSchema.AdditionalProperties != nil {
+		addChange(path, ChangeKindBreaking, "additional properties were changed")
+	}
+
+	for name, oldPropertySchema := range oldSchema.Properties {
+		propertyPath := propertyPath(path, name)
+
+		newPropertySchema, ok := newSchema.Properties[name]
+
+		if !ok {
+			addChange(propertyPath, ChangeKindBreaking, "property was removed")
+
+			continue
+		}
+
+		oldRequired := slices.Contains(oldSchema.Required, name)
+		newRequired := slices.Contains(newSchema.Required, name)
+
+		if !oldRequired && newRequired {
+			addChange(propertyPath, ChangeKindBreaking, "property was made required")
+		} else if oldRequired && !newRequired {
+			addChange(propertyPath, ChangeKindBreaking, "property was made optional")
+		}
+
+		compare(changes, propertyPath, oldPropertySchema, newPropertySchema)
+	}
+
+	for name := range newSchema.Properties {
+		if _, ok := oldSchema.Properties[name]; ok {
+			continue
+		}
+
+		if slices.Contains(newSchema.Required, name) {
+			addChange(propertyPath(path, name), ChangeKindBreaking, "required property was added")
+		} else {
+			addChange(propertyPath(path, name), ChangeKindAdditive, "optional property was added")
+		}
+	}
+}
+
+func propertyPath(path string, name string) string {
+	if path == rootField {
+		return name
+	}
+
+	return path + "." + name
+}
+
+// sameSet reports whether values1 and values2 contain the same values, regardless of their order and Go type.
+func sameSet[T any](values1 []T, values2 []T) bool {
+	keys1 := make([]string, 0, len(values1))
+
+	for _, value := range values1 {
+		keys1 = append(keys1, key(value))
+	}
+
+	keys2 := make([]string, 0, len(values2))
+
+	for _, value := range values2 {
+		keys2 = append(keys2, key(value))
+	}
+
+	slices.Sort(keys1)
+	slices.Sort(keys2)
+
+	return slices.Equal(slices.Compact(keys1), slices.Compact(keys2))
+}
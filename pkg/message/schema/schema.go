@@ -0,0 +1,373 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/message"
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+const (
+	Draft string = "https://json-schema.org/draft/2020-12/schema"
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported type")
+)
+
+const (
+	typeNull    = "null"
+	typeBoolean = "boolean"
+	typeInteger = "integer"
+	typeNumber  = "number"
+	typeString  = "string"
+	typeArray   = "array"
+	typeObject  = "object"
+)
+
+// Patterns of the strings that are not blank, which are required by the validation of the messages, and of the times.
+const (
+	patternNotBlank = `\S`
+	patternTime     = `^(\d{4}-\d{2}-\d{2}([T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?)?)?$`
+)
+
+// Enum is implemented by the named enum types and flag sets of the messages, to list their values in the schemas.
+type Enum interface {
+	EnumValues() []any
+}
+
+// NamedEnum is implemented by the named enum types whose values are also unmarshaled from their names.
+type NamedEnum interface {
+	EnumNames() []string
+}
+
+// FlagSet is implemented by the flag sets whose values are also unmarshaled from the names of their flags.
+type FlagSet interface {
+	FlagNames() []string
+}
+
+// Types is marshaled as a single JSON string when it has one type, and unmarshaled from a JSON string or array.
+type Types []string
+
+func (types Types) MarshalJSON() ([]byte, error) {
+	if len(types) == 1 {
+		return json.Marshal(types[0])
+	}
+
+	return json.Marshal([]string(types))
+}
+
+func (types *Types) UnmarshalJSON(data []byte) error {
+	var value string
+
+	if err := json.Unmarshal(data, &value); err == nil {
+		*types = Types{value}
+
+		return nil
+	}
+
+	return json.Unmarshal(data, (*[]string)(types))
+}
+
+// Schema is the subset of JSON Schema (draft 2020-12) generated from the message models.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 Types              `json:"type,omitempty"`
+	Const                any                `json:"const,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// GenerateMessage generates the schema of the JSON of message. The Type of the message is constrained to its discriminator.
+func GenerateMessage(message pubsub.Message) (*Schema, error) {
+	schema, err := Generate(message)
+
+	if err != nil {
+		return nil, err
+	}
+
+	discriminator := string(message.Discriminator())
+
+	schema.Title = discriminator
+
+	if typeSchema, ok := schema.Properties["Type"]; ok {
+		typeSchema.Const = discriminator
+	}
+
+	return schema, nil
+}
+
+// Generate generates the schema of the JSON of value from its type. The properties are named after their json tag, the fields
+// tagged with `schema:"required"` are required and not nullable, and the other pointers, slices and maps are nullable. The
+// required strings must not be blank, and the enum types and flag sets are either their numbers or their names.
+func Generate(value any) (*Schema, error) {
+	generator := &generator{
+		visiting: map[reflect.Type]struct{}{},
+	}
+
+	valueType := reflect.TypeOf(value)
+
+	// The message models are generated from their struct, so that the root of the schema is not nullable.
+	if valueType != nil && valueType.Kind() == reflect.Pointer {
+		valueType = valueType.Elem()
+	}
+
+	schema, err := generator.generate(valueType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	schema.Schema = Draft
+
+	return schema, nil
+}
+
+type generator struct {
+	// Struct types being generated, to reject recursive types.
+	visiting map[reflect.Type]struct{}
+}
+
+var (
+	timeType = reflect.TypeFor[message.Time]()
+	enumType = reflect.TypeFor[Enum]()
+)
+
+func (generator *generator) generate(valueType reflect.Type) (*Schema, error) {
+	if valueType == nil {
+		return nil, fmt.Errorf("%w: nil", ErrUnsupportedType)
+	}
+
+	if valueType == timeType {
+		// The times are strings in RFC 3339 format with or without a time zone, or dates, and null and the empty string are
+		// the zero time.
+		return &Schema{Type: Types{typeString, typeNull}, Pattern: patternTime}, nil
+	}
+
+	if valueType.Kind() != reflect.Pointer && valueType.Implements(enumType) {
+		schema, err := generator.generateKind(valueType)
+
+		if err != nil {
+			return nil, err
+		}
+
+		value := reflect.Zero(valueType).Interface()
+
+		schema.Enum = value.(Enum).EnumValues()
+		schema.Minimum = nil
+
+		// The names are listed as they are marshaled, although they are unmarshaled case-insensitive.
+		switch value := value.(type) {
+		case NamedEnum:
+			names := make([]any, 0, len(value.EnumNames()))
+
+			for _, name := range value.EnumNames() {
+				names = append(names, name)
+			}
+
+			return &Schema{AnyOf: []*Schema{schema, {Type: Types{typeString}, Enum: names}}}, nil
+		case FlagSet:
+			return &Schema{AnyOf: []*Schema{schema, {Type: Types{typeString}, Pattern: flagsPattern(value.FlagNames())}}}, nil
+		}
+
+		return schema, nil
+	}
+
+	return generator.generateKind(valueType)
+}
+
+func (generator *generator) generateKind(valueType reflect.Type) (*Schema, error) {
+	switch valueType.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: Types{typeBoolean}}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: Types{typeInteger}}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		minimum := 0.0
+
+		return &Schema{Type: Types{typeInteger}, Minimum: &minimum}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{typeNumber}}, nil
+	case reflect.String:
+		return &Schema{Type: Types{typeString}}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Pointer:
+		schema, err := generator.generate(valueType.Elem())
+
+		if err != nil {
+			return nil, err
+		}
+
+		nullable(schema)
+
+		return schema, nil
+	case reflect.Slice:
+		// []byte is marshaled as a base64 string.
+		if valueType.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: Types{typeString, typeNull}}, nil
+		}
+
+		items, err := generator.generate(valueType.Elem())
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &Schema{Type: Types{typeArray, typeNull}, Items: items}, nil
+	case reflect.Array:
+		items, err := generator.generate(valueType.Elem())
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &Schema{Type: Types{typeArray}, Items: items}, nil
+	case reflect.Map:
+		if valueType.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, valueType)
+		}
+
+		additionalProperties, err := generator.generate(valueType.Elem())
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &Schema{Type: Types{typeObject, typeNull}, AdditionalProperties: additionalProperties}, nil
+	case reflect.Struct:
+		if _, ok := generator.visiting[valueType]; ok {
+			return nil, fmt.Errorf("%w: recursive type %s", ErrUnsupportedType, valueType)
+		}
+
+		generator.visiting[valueType] = struct{}{}
+
+		defer delete(generator.visiting, valueType)
+
+		schema := &Schema{
+			Type:       Types{typeObject},
+			Properties: map[string]*Schema{},
+		}
+
+		if err := generator.generateFields(valueType, schema); err != nil {
+			return nil, err
+		}
+
+		return schema, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, valueType)
+	}
+}
+
+// generateFields adds the properties of the fields of valueType to schema. The fields of the embedded structs are added
+// to schema too, as they are marshaled.
+func (generator *generator) generateFields(valueType reflect.Type, schema *Schema) error {
+	for i := range valueType.NumField() {
+		field := valueType.Field(i)
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			fieldType := field.Type
+
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+
+			if fieldType.Kind() == reflect.Struct {
+				if err := generator.generateFields(fieldType, schema); err != nil {
+					return err
+				}
+
+				continue
+			}
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldSchema, err := generator.generate(field.Type)
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		if field.Tag.Get("schema") == "required" {
+			required(fieldSchema)
+
+			schema.Required = append(schema.Required, name)
+		}
+
+		schema.Properties[name] = fieldSchema
+	}
+
+	return nil
+}
+
+// flagsPattern matches the names of the flags separated by "|" or ",", as accepted by message.ParseFlags.
+func flagsPattern(flagNames []string) string {
+	quotedNames := []string{"None"}
+
+	for _, flagName := range flagNames {
+		quotedNames = append(quotedNames, regexp.QuoteMeta(flagName))
+	}
+
+	name := "(" + strings.Join(quotedNames, "|") + ")"
+
+	return `^\s*(` + name + `(\s*[|,]\s*` + name + `)*)?\s*$`
+}
+
+func nullable(schema *Schema) {
+	if len(schema.Type) > 0 && !slices.Contains(schema.Type, typeNull) {
+		schema.Type = append(schema.Type, typeNull)
+	}
+
+	if schema.Enum != nil && !slices.Contains(schema.Enum, nil) {
+		schema.Enum = append(schema.Enum, nil)
+	}
+
+	if schema.AnyOf != nil && !slices.ContainsFunc(schema.AnyOf, isNull) {
+		schema.AnyOf = append(schema.AnyOf, &Schema{Type: Types{typeNull}})
+	}
+}
+
+func required(schema *Schema) {
+	if len(schema.Type) > 1 {
+		schema.Type = slices.DeleteFunc(schema.Type, func(schemaType string) bool { return schemaType == typeNull })
+	}
+
+	schema.Enum = slices.DeleteFunc(schema.Enum, func(value any) bool { return value == nil })
+
+	schema.AnyOf = slices.DeleteFunc(schema.AnyOf, isNull)
+
+	// The required strings are checked with validation.Checker.Required, which rejects the blank strings.
+	if slices.Equal(schema.Type, Types{typeString}) && schema.Enum == nil && schema.Pattern == "" {
+		minLength := 1
+
+		schema.MinLength = &minLength
+		schema.Pattern = patternNotBlank
+	}
+}
+
+func isNull(schema *Schema) bool {
+	return slices.Equal(schema.Type, Types{typeNull})
+}
@@ -0,0 +1,145 @@
+package schema
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event/hr"
+	"github.com/scaleforce/synchronization-for-go/pkg/message/event/xnms"
+	"github.com/scaleforce/synchronization-for-go/pkg/validation"
+)
+
+func fields(t *testing.T, err error) []string {
+	t.Helper()
+
+	if err == nil {
+		return nil
+	}
+
+	var validationErr *validation.ValidationError
+
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("error is %v, expected a *validation.ValidationError", err)
+	}
+
+	fields := make([]string, 0, len(validationErr.Errs))
+
+	for _, fieldErr := range validationErr.Errs {
+		fields = append(fields, fieldErr.Field)
+	}
+
+	return fields
+}
+
+func TestValidateIsConsistentWithTheValidationOfTheMessages(t *testing.T) {
+	deviceSchema, err := GenerateMessage(&xnms.DeviceEvent{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := `{"Type":"XNMS_Device","Operation":"AddOrSet","Timestamp":"2024-01-02T03:04:05.123Z","TenantGroupName":"group","Data":{"Code":"1","Status":"Online"}}`
+
+	if err := deviceSchema.Validate([]byte(valid)); err != nil {
+		t.Fatal(err)
+	}
+
+	for data, expectedFields := range map[string][]string{
+		// The statuses are numbers or names.
+		`{"Type":"XNMS_Device","Operation":"AddOrSet","Timestamp":"2024-01-02","TenantGroupName":"group","Data":{"Code":"1","Status":1}}`: nil,
+		`{"Type":"XNMS_Device","Operation":"AddOrSet","Timestamp":"","TenantGroupName":"group","Data":{"Code":"1","Status":"Unknown"}}`:   {"Data.Status"},
+		`{"Type":"XNMS_Device","Operation":"AddOrSet","Timestamp":null,"TenantGroupName":"group","Data":{"Code":"1","Status":9}}`:         {"Data.Status"},
+		// The required strings must not be blank, as checked by Validate of the message.
+		`{"Type":"XNMS_Device","Operation":"AddOrSet","TenantGroupName":"","Data":{"Code":" "}}`:                              {"Data.Code", "TenantGroupName"},
+		`{"Type":"XNMS_Device","Operation":"AddOrSet","Timestamp":"yesterday","TenantGroupName":"group","Data":{"Code":"1"}}`: {"Timestamp"},
+	} {
+		if actualFields := fields(t, deviceSchema.Validate([]byte(data))); !slices.Equal(actualFields, expectedFields) {
+			t.Fatalf("%s: fields are %v, expected %v", data, actualFields, expectedFields)
+		}
+	}
+
+	if fields := fields(t, validation.Validate(xnms.NewDeviceEventWithOptions(event.OperationAddOrSet, &xnms.DeviceData{Code: "1"}))); !slices.Equal(fields, []string{"TenantGroupName"}) {
+		t.Fatalf("fields are %v, expected [TenantGroupName]", fields)
+	}
+}
+
+func TestValidateAcceptsTheNamesOfTheFlags(t *testing.T) {
+	employeeSchema, err := GenerateMessage(&hr.EmployeeEvent{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for contactTypes, valid := range map[string]bool{
+		`3`: true,
+		`"PrimaryEscalationPoint|SecondaryEscalationPoint"`:  true,
+		`"PrimaryEscalationPoint, SecondaryEscalationPoint"`: true,
+		`"None"`:                           true,
+		`4`:                                false,
+		`"PrimaryEscalationPoint|Unknown"`: false,
+	} {
+		data := `{"Type":"HR_Employee","Operation":"Add","TenantGroupName":"group","Data":{"Code":"1","PartnerGroup":{"PartnerGroupCode":"1","ContactTypes":` + contactTypes + `}}}`
+
+		if err := employeeSchema.Validate([]byte(data)); (err == nil) != valid {
+			t.Fatalf("%s: error is %v", contactTypes, err)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	oldSchema, err := GenerateMessage(&xnms.DeviceEvent{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changes := Compare(oldSchema, oldSchema); len(changes) != 0 {
+		t.Fatalf("changes are %v, expected none", changes)
+	}
+
+	newSchema, err := GenerateMessage(&xnms.DeviceEvent{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataSchema := newSchema.Properties["Data"]
+
+	dataSchema.Properties["Model"] = &Schema{Type: Types{typeString}}
+
+	changes := Compare(oldSchema, newSchema)
+
+	if len(changes) != 1 || changes[0].Path != "Data.Model" || changes[0].Kind != ChangeKindAdditive {
+		t.Fatalf("changes are %v, expected an additive change of Data.Model", changes)
+	}
+
+	dataSchema.Properties["Code"].MinLength = nil
+	dataSchema.Properties["Status"].AnyOf = dataSchema.Properties["Status"].AnyOf[:1]
+	dataSchema.Required = append(dataSchema.Required, "Model")
+
+	delete(newSchema.Properties, "Timestamp")
+
+	changes = Compare(oldSchema, newSchema)
+
+	expectedPaths := []string{"Data.Code", "Data.Model", "Data.Status", "Timestamp"}
+
+	paths := make([]string, 0, len(changes))
+
+	for _, change := range changes {
+		if change.Kind != ChangeKindBreaking {
+			t.Fatalf("change %v is not breaking", change)
+		}
+
+		paths = append(paths, change.Path)
+	}
+
+	if !slices.Equal(paths, expectedPaths) {
+		t.Fatalf("paths are %v, expected %v", paths, expectedPaths)
+	}
+
+	if !changes.Breaking() {
+		t.Fatal("changes are not breaking")
+	}
+}
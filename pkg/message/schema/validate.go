@@ -0,0 +1,161 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/validation"
+)
+
+// rootField is the field of the errors of the root of the JSON.
+const rootField = "$"
+
+// Validate validates the raw JSON of a message, e.g. the body of a Service Bus message published from another stack,
+// against the schema. It returns a *validation.ValidationError with all the fields that do not match the schema, or an
+// error if data is not JSON. The properties that are not in the schema are accepted.
+func (schema *Schema) Validate(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	decoder.UseNumber()
+
+	var value any
+
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+
+	checker := validation.NewChecker()
+
+	schema.validate(checker, rootField, value)
+
+	return checker.Err()
+}
+
+func (schema *Schema) validate(checker *validation.Checker, field string, value any) {
+	valueType := typeOf(value)
+
+	if len(schema.Type) > 0 && !slices.Contains(schema.Type, valueType) && !(valueType == typeInteger && slices.Contains(schema.Type, typeNumber)) {
+		checker.Add(field, fmt.Sprintf("must be %s, not %s", strings.Join(schema.Type, " or "), valueType))
+
+		return
+	}
+
+	if schema.Const != nil && key(schema.Const) != key(value) {
+		checker.Add(field, fmt.Sprintf("must be %s", key(schema.Const)))
+
+		return
+	}
+
+	if schema.Enum != nil && !slices.ContainsFunc(schema.Enum, func(enum any) bool { return key(enum) == key(value) }) {
+		checker.Add(field, fmt.Sprintf("is not one of the values of the enum: %s", key(value)))
+
+		return
+	}
+
+	if schema.AnyOf != nil && !slices.ContainsFunc(schema.AnyOf, func(anyOfSchema *Schema) bool { return anyOfSchema.matches(value) }) {
+		checker.Add(field, fmt.Sprintf("does not match any of the schemas: %s", key(value)))
+
+		return
+	}
+
+	switch value := value.(type) {
+	case string:
+		if schema.MinLength != nil && utf8.RuneCountInString(value) < *schema.MinLength {
+			checker.Add(field, fmt.Sprintf("must have at least %d characters", *schema.MinLength))
+		} else if schema.Pattern != "" {
+			if matched, err := regexp.MatchString(schema.Pattern, value); err == nil && !matched {
+				checker.Add(field, fmt.Sprintf("does not match the pattern %s", schema.Pattern))
+			}
+		}
+	case json.Number:
+		if schema.Minimum != nil {
+			if number, err := value.Float64(); err == nil && number < *schema.Minimum {
+				checker.Add(field, fmt.Sprintf("must be at least %v", *schema.Minimum))
+			}
+		}
+	case []any:
+		if schema.Items != nil {
+			for i, item := range value {
+				schema.Items.validate(checker, fmt.Sprintf("%s[%d]", field, i), item)
+			}
+		}
+	case map[string]any:
+		nestedChecker := checker
+
+		if field != rootField {
+			nestedChecker = checker.Nested(field)
+		}
+
+		for _, name := range schema.Required {
+			if _, ok := value[name]; !ok {
+				nestedChecker.Add(name, "is required")
+			}
+		}
+
+		names := make([]string, 0, len(value))
+
+		for name := range value {
+			names = append(names, name)
+		}
+
+		// The errors are reported in a stable order.
+		slices.Sort(names)
+
+		for _, name := range names {
+			if propertySchema, ok := schema.Properties[name]; ok {
+				propertySchema.validate(nestedChecker, name, value[name])
+			} else if schema.AdditionalProperties != nil {
+				schema.AdditionalProperties.validate(nestedChecker, name, value[name])
+			}
+		}
+	}
+}
+
+// matches reports whether value is valid against the schema, without reporting its errors.
+func (schema *Schema) matches(value any) bool {
+	checker := validation.NewChecker()
+
+	schema.validate(checker, rootField, value)
+
+	return checker.Err() == nil
+}
+
+func typeOf(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return typeNull
+	case bool:
+		return typeBoolean
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return typeInteger
+		}
+
+		return typeNumber
+	case string:
+		return typeString
+	case []any:
+		return typeArray
+	case map[string]any:
+		return typeObject
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// key returns the JSON of value, to compare the values of the schemas and of the messages regardless of their Go type,
+// e.g. 1, float64(1) and json.Number("1").
+func key(value any) string {
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(data)
+}
@@ -0,0 +1,110 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidTime = errors.New("invalid time")
+)
+
+// Layouts accepted by ParseTime, in the order they are tried. The layouts without a time zone are parsed in UTC.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	time.DateOnly,
+}
+
+// Time is a time in the messages. It is marshaled with the layout it was parsed with, so that the wire format of the
+// publishers is preserved, and with time.RFC3339 otherwise. The zero Time is marshaled as an empty string.
+//
+// Times must be compared with Equal, e.g. t.Equal(u.Time), and not with ==, which also compares the layouts, so that
+// the same instant parsed from different layouts is not equal.
+type Time struct {
+	time.Time
+	layout string
+}
+
+func NewTime(t time.Time) Time {
+	return Time{
+		Time: t,
+	}
+}
+
+// ParseTime accepts RFC 3339 times, with or without a time zone, with a space instead of the "T", and dates. An empty
+// string is parsed as the zero Time.
+func ParseTime(value string) (Time, error) {
+	value = strings.TrimSpace(value)
+
+	if value == "" {
+		return Time{}, nil
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return Time{
+				Time:   t,
+				layout: layout,
+			}, nil
+		}
+	}
+
+	return Time{}, fmt.Errorf("%w: %q", ErrInvalidTime, value)
+}
+
+func (t Time) String() string {
+	if t.IsZero() {
+		return ""
+	}
+
+	layout := t.layout
+
+	if layout == "" {
+		layout = time.RFC3339
+	}
+
+	return t.Format(layout)
+}
+
+func (t Time) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *Time) UnmarshalText(data []byte) error {
+	parsedTime, err := ParseTime(string(data))
+
+	if err != nil {
+		return err
+	}
+
+	*t = parsedTime
+
+	return nil
+}
+
+func (t Time) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (t *Time) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*t = Time{}
+
+		return nil
+	}
+
+	var value string
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		return ErrInvalidTime
+	}
+
+	return t.UnmarshalText([]byte(value))
+}
@@ -0,0 +1,99 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUnmarshalTimeJSONAcceptsTheLayouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected time.Time
+	}{
+		{"RFC3339Nano", `"2024-05-06T07:08:09.123+02:00"`, time.Date(2024, 5, 6, 5, 8, 9, 123000000, time.UTC)},
+		{"RFC3339", `"2024-05-06T07:08:09Z"`, time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)},
+		{"without zone", `"2024-05-06T07:08:09.5"`, time.Date(2024, 5, 6, 7, 8, 9, 500000000, time.UTC)},
+		{"space with zone", `"2024-05-06 07:08:09+02:00"`, time.Date(2024, 5, 6, 5, 8, 9, 0, time.UTC)},
+		{"space without zone", `"2024-05-06 07:08:09"`, time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)},
+		{"date only", `"2024-05-06"`, time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)},
+		{"surrounding spaces", `" 2024-05-06 "`, time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)},
+		{"null", `null`, time.Time{}},
+		{"empty", `""`, time.Time{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var value Time
+
+			if err := json.Unmarshal([]byte(test.data), &value); err != nil {
+				t.Fatal(err)
+			}
+
+			if !value.Equal(test.expected) {
+				t.Fatalf("time is %v, expected %v", value.Time, test.expected)
+			}
+		})
+	}
+}
+
+func TestUnmarshalTimeJSONRejectsTheInvalidValues(t *testing.T) {
+	for _, data := range []string{`"yesterday"`, `"2024-13-01"`, `"2024-05-06T25:00:00Z"`, `"06/05/2024"`, `1714979289`, `true`} {
+		var value Time
+
+		if err := json.Unmarshal([]byte(data), &value); !errors.Is(err, ErrInvalidTime) {
+			t.Fatalf("%s: error is %v, expected %v", data, err, ErrInvalidTime)
+		}
+	}
+}
+
+func TestMarshalTimeJSONKeepsTheLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    Time
+		expected string
+	}{
+		{"zero", Time{}, `""`},
+		{"without layout", NewTime(time.Date(2024, 5, 6, 7, 8, 9, 123000000, time.UTC)), `"2024-05-06T07:08:09Z"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := json.Marshal(test.value)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(data) != test.expected {
+				t.Fatalf("JSON is %s, expected %s", data, test.expected)
+			}
+		})
+	}
+
+	for _, data := range []string{
+		`"2024-05-06T07:08:09.123+02:00"`,
+		`"2024-05-06T07:08:09.5"`,
+		`"2024-05-06 07:08:09+02:00"`,
+		`"2024-05-06 07:08:09"`,
+		`"2024-05-06"`,
+	} {
+		var value Time
+
+		if err := json.Unmarshal([]byte(data), &value); err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+
+		roundTripData, err := json.Marshal(value)
+
+		if err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+
+		if string(roundTripData) != data {
+			t.Fatalf("JSON is %s, expected %s", roundTripData, data)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+	"github.com/scaleforce/synchronization-for-go/pkg/validation"
+)
+
+// SchemaSQLite creates the outbox table with the default name in SQLite. Other databases need an equivalent table,
+// with an auto-incremented id that follows the insertion order. Large outboxes benefit from an index on (partition_key, id),
+// which the relay uses to find the messages that wait for a preceding message.
+const SchemaSQLite = `CREATE TABLE IF NOT EXISTS outbox (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	discriminator TEXT NOT NULL,
+	partition_key TEXT NOT NULL,
+	payload BLOB NOT NULL,
+	created_time INTEGER NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_time INTEGER NOT NULL,
+	last_error TEXT,
+	published_time INTEGER,
+	failed_time INTEGER
+)`
+
+const (
+	defaultTableName = "outbox"
+)
+
+type MarshalFunc func(message pubsub.Message) ([]byte, error)
+
+type UnmarshalFunc func(discriminator pubsub.Discriminator, payload []byte) (pubsub.Message, error)
+
+type CreateMessageFunc func(discriminator pubsub.Discriminator) pubsub.Message
+
+type GetPartitionKeyFunc func(message pubsub.Message) (string, error)
+
+// PlaceholderFunc returns the placeholder of the query parameter with the given index, starting at 1.
+type PlaceholderFunc func(index int) string
+
+// PlaceholderQuestion is used by SQLite and MySQL.
+func PlaceholderQuestion(index int) string {
+	return "?"
+}
+
+// PlaceholderDollar is used by PostgreSQL and SQLite.
+func PlaceholderDollar(index int) string {
+	return "$" + strconv.Itoa(index)
+}
+
+func MarshalJSON(message pubsub.Message) ([]byte, error) {
+	return json.Marshal(message)
+}
+
+func NewUnmarshalJSONFunc(createMessageFunc CreateMessageFunc) UnmarshalFunc {
+	return func(discriminator pubsub.Discriminator, payload []byte) (pubsub.Message, error) {
+		message := createMessageFunc(discriminator)
+
+		if err := json.Unmarshal(payload, &message); err != nil {
+			return nil, err
+		}
+
+		return message, nil
+	}
+}
+
+type OutboxOptions struct {
+	// Defaults to "outbox".
+	TableName string
+	// Defaults to PlaceholderQuestion.
+	PlaceholderFunc PlaceholderFunc
+	// Optional partition key of the messages. The relay publishes the messages with the same partition key in order.
+	GetPartitionKeyFunc GetPartitionKeyFunc
+}
+
+// Outbox stores the messages in the same transaction as the changes they describe, so that a message is published
+// by the relay if and only if the transaction is committed.
+type Outbox struct {
+	marshalFunc MarshalFunc
+	options     *OutboxOptions
+}
+
+func NewOutbox(marshalFunc MarshalFunc, options *OutboxOptions) *Outbox {
+	return &Outbox{
+		marshalFunc: marshalFunc,
+		options:     options,
+	}
+}
+
+// Add returns a *validation.ValidationError if the message is not valid, so that the transaction can be rolled back
+// instead of the relay failing to publish the message later.
+func (outbox *Outbox) Add(ctx context.Context, tx *sql.Tx, message pubsub.Message) error {
+	if err := validation.Validate(message); err != nil {
+		return err
+	}
+
+	tableName := defaultTableName
+	placeholderFunc := PlaceholderQuestion
+
+	partitionKey := ""
+
+	if outbox.options != nil {
+		if outbox.options.TableName != "" {
+			tableName = outbox.options.TableName
+		}
+
+		if outbox.options.PlaceholderFunc != nil {
+			placeholderFunc = outbox.options.PlaceholderFunc
+		}
+
+		if outbox.options.GetPartitionKeyFunc != nil {
+			var err error
+
+			partitionKey, err = outbox.options.GetPartitionKeyFunc(message)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	payload, err := outbox.marshalFunc(message)
+
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UnixMilli()
+
+	query := fmt.Sprintf("INSERT INTO %s (discriminator, partition_key, payload, created_time, next_attempt_time) VALUES (%s, %s, %s, %s, %s)",
+		tableName, placeholderFunc(1), placeholderFunc(2), placeholderFunc(3), placeholderFunc(4), placeholderFunc(5))
+
+	if _, err := tx.ExecContext(ctx, query, string(message.Discriminator()), partitionKey, payload, now, now); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,274 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+
+	_ "modernc.org/sqlite"
+)
+
+type testMessage struct {
+	Key  string
+	Name string
+}
+
+func (message *testMessage) Discriminator() pubsub.Discriminator {
+	return "Test"
+}
+
+type testPublisher struct {
+	published []string
+	// Number of attempts that fail per name, -1 for all attempts.
+	failures map[string]int
+}
+
+func (publisher *testPublisher) publish(ctx context.Context, message pubsub.Message) error {
+	name := message.(*testMessage).Name
+
+	if failures := publisher.failures[name]; failures != 0 {
+		publisher.failures[name] = failures - 1
+
+		return errors.New("publish failed")
+	}
+
+	publisher.published = append(publisher.published, name)
+
+	return nil
+}
+
+func newTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", ":memory:")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every connection to :memory: has its own database.
+	db.SetMaxOpenConns(1)
+
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	if _, err := db.Exec(SchemaSQLite); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func add(t *testing.T, db *sql.DB, messages ...*testMessage) {
+	outboxOptions := &OutboxOptions{
+		GetPartitionKeyFunc: func(message pubsub.Message) (string, error) {
+			return message.(*testMessage).Key, nil
+		},
+	}
+
+	outbox := NewOutbox(MarshalJSON, outboxOptions)
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, message := range messages {
+		if err := outbox.Add(context.Background(), tx, message); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestRelay(db *sql.DB, publisher *testPublisher, options *RelayOptions) (*Relay, *relayOptions) {
+	unmarshalFunc := NewUnmarshalJSONFunc(func(discriminator pubsub.Discriminator) pubsub.Message {
+		return &testMessage{}
+	})
+
+	relay := NewRelay(db, unmarshalFunc, publisher.publish, slog.New(slog.NewTextHandler(io.Discard, nil)), options)
+
+	return relay, relay.relayOptions()
+}
+
+func countRows(t *testing.T, db *sql.DB, condition string) int {
+	var count int
+
+	if err := db.QueryRow("SELECT COUNT(*) FROM outbox WHERE " + condition).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+
+	return count
+}
+
+func TestRelayPublishesInOrder(t *testing.T) {
+	db := newTestDB(t)
+
+	add(t, db, &testMessage{Key: "a", Name: "a1"}, &testMessage{Name: "1"}, &testMessage{Key: "b", Name: "b1"}, &testMessage{Key: "a", Name: "a2"})
+
+	publisher := &testPublisher{}
+
+	relay, options := newTestRelay(db, publisher, &RelayOptions{Retention: time.Hour})
+
+	if err := relay.relay(context.Background(), options); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"a1", "1", "b1", "a2"}
+
+	if !slices.Equal(publisher.published, expected) {
+		t.Fatalf("published messages are %v, expected %v", publisher.published, expected)
+	}
+
+	if count := countRows(t, db, "published_time IS NOT NULL"); count != 4 {
+		t.Fatalf("%d messages are marked as published, expected 4", count)
+	}
+}
+
+func TestRelayRetriesInOrder(t *testing.T) {
+	db := newTestDB(t)
+
+	add(t, db, &testMessage{Key: "a", Name: "a1"}, &testMessage{Key: "a", Name: "a2"}, &testMessage{Key: "b", Name: "b1"}, &testMessage{Name: "1"}, &testMessage{Name: "2"})
+
+	publisher := &testPublisher{failures: map[string]int{"a1": 1, "1": 1}}
+
+	relay, options := newTestRelay(db, publisher, &RelayOptions{Backoff: time.Millisecond})
+
+	if err := relay.relay(context.Background(), options); err != nil {
+		t.Fatal(err)
+	}
+
+	// The messages without partition key are not ordered.
+	expected := []string{"b1", "2"}
+
+	if !slices.Equal(publisher.published, expected) {
+		t.Fatalf("published messages are %v, expected %v", publisher.published, expected)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	if err := relay.relay(context.Background(), options); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = []string{"b1", "2", "a1", "a2", "1"}
+
+	if !slices.Equal(publisher.published, expected) {
+		t.Fatalf("published messages are %v, expected %v", publisher.published, expected)
+	}
+}
+
+func TestRelayDoesNotFillTheBatchesWithWaitingMessages(t *testing.T) {
+	db := newTestDB(t)
+
+	add(t, db, &testMessage{Key: "a", Name: "a1"}, &testMessage{Key: "a", Name: "a2"}, &testMessage{Key: "a", Name: "a3"}, &testMessage{Key: "b", Name: "b1"})
+
+	publisher := &testPublisher{failures: map[string]int{"a1": 1}}
+
+	relay, options := newTestRelay(db, publisher, &RelayOptions{BatchSize: 2, Backoff: time.Hour})
+
+	if err := relay.relay(context.Background(), options); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(publisher.published) != 0 {
+		t.Fatalf("published messages are %v, expected none", publisher.published)
+	}
+
+	// The messages that wait for a1 are not read, so b1 is.
+	if err := relay.relay(context.Background(), options); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"b1"}
+
+	if !slices.Equal(publisher.published, expected) {
+		t.Fatalf("published messages are %v, expected %v", publisher.published, expected)
+	}
+}
+
+func TestRelayKeepsThePartitionKeyOfAFailedMessageBlocked(t *testing.T) {
+	db := newTestDB(t)
+
+	add(t, db, &testMessage{Key: "a", Name: "a1"}, &testMessage{Key: "a", Name: "a2"}, &testMessage{Key: "b", Name: "b1"})
+
+	publisher := &testPublisher{failures: map[string]int{"a1": -1}}
+
+	relay, options := newTestRelay(db, publisher, &RelayOptions{MaxAttempts: 1})
+
+	for range 2 {
+		if err := relay.relay(context.Background(), options); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{"b1"}
+
+	if !slices.Equal(publisher.published, expected) {
+		t.Fatalf("published messages are %v, expected %v", publisher.published, expected)
+	}
+
+	if count := countRows(t, db, "failed_time IS NOT NULL"); count != 1 {
+		t.Fatalf("%d messages are marked as failed, expected 1", count)
+	}
+
+	// The failed message is retried manually, then the waiting message is published after it.
+	if _, err := db.Exec("UPDATE outbox SET failed_time = NULL, attempts = 0 WHERE failed_time IS NOT NULL"); err != nil {
+		t.Fatal(err)
+	}
+
+	publisher.failures = nil
+
+	if err := relay.relay(context.Background(), options); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = []string{"b1", "a1", "a2"}
+
+	if !slices.Equal(publisher.published, expected) {
+		t.Fatalf("published messages are %v, expected %v", publisher.published, expected)
+	}
+}
+
+func TestRelayDeletesThePublishedMessagesAfterTheRetention(t *testing.T) {
+	db := newTestDB(t)
+
+	add(t, db, &testMessage{Key: "a", Name: "a1"}, &testMessage{Key: "b", Name: "b1"})
+
+	publisher := &testPublisher{failures: map[string]int{"b1": -1}}
+
+	relay, options := newTestRelay(db, publisher, &RelayOptions{MaxAttempts: 1, Retention: time.Hour})
+
+	if err := relay.relay(context.Background(), options); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := relay.cleanup(context.Background(), options); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := countRows(t, db, "1 = 1"); count != 2 {
+		t.Fatalf("%d messages are kept, expected 2", count)
+	}
+
+	options.retention = 0
+
+	if err := relay.cleanup(context.Background(), options); err != nil {
+		t.Fatal(err)
+	}
+
+	// The failed messages are never deleted.
+	if count := countRows(t, db, "failed_time IS NOT NULL"); count != 1 || count != countRows(t, db, "1 = 1") {
+		t.Fatal("published message was not deleted or failed message was deleted")
+	}
+}
@@ -0,0 +1,301 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+type RelayOptions struct {
+	// Defaults to "outbox".
+	TableName string
+	// Defaults to PlaceholderQuestion.
+	PlaceholderFunc PlaceholderFunc
+	// Time interval to read the pending messages, defaults to 1 second.
+	Interval time.Duration
+	// Maximum number of pending messages read at once, defaults to 100.
+	BatchSize int
+	// Number of attempts after which a message is marked as failed and no longer published, defaults to 10. The following
+	// messages with the same partition key wait until the failed message is published manually or deleted.
+	MaxAttempts int
+	// Delay before the second attempt, doubled after each attempt up to MaxBackoff, defaults to 1 second.
+	Backoff time.Duration
+	// Defaults to 5 minutes.
+	MaxBackoff time.Duration
+	// Time the published messages are kept before they are deleted. By default they are deleted right away.
+	// The failed messages are never deleted, so that they can be inspected and retried manually.
+	Retention time.Duration
+}
+
+// Relay publishes the pending messages of the outbox in the order they were added. Once a message with a partition key
+// fails, the following messages with the same partition key wait until it is published, also when it is marked as failed,
+// so that they are never published out of order. The failed messages are retried manually by resetting their failed_time,
+// attempts and next_attempt_time, or deleted. Only one relay must run per outbox table.
+//
+// To publish envelopes (e.g. with their message ID and session ID), the outbox must store the whole envelope, e.g. with
+// util.MarshalEnvelopeJSON and util.NewUnmarshalEnvelopeJSONFunc, because the marshal function of the publisher rejects
+// the messages that are not envelopes.
+type Relay struct {
+	db            *sql.DB
+	unmarshalFunc UnmarshalFunc
+	publishFunc   pubsub.PublishFunc
+	logger        *slog.Logger
+	options       *RelayOptions
+}
+
+func NewRelay(db *sql.DB, unmarshalFunc UnmarshalFunc, publishFunc pubsub.PublishFunc, logger *slog.Logger, options *RelayOptions) *Relay {
+	return &Relay{
+		db:            db,
+		unmarshalFunc: unmarshalFunc,
+		publishFunc:   publishFunc,
+		logger:        logger,
+		options:       options,
+	}
+}
+
+type relayOptions struct {
+	interval        time.Duration
+	tableName       string
+	placeholderFunc PlaceholderFunc
+	batchSize       int
+	maxAttempts     int
+	backoff         time.Duration
+	maxBackoff      time.Duration
+	retention       time.Duration
+}
+
+type record struct {
+	id              int64
+	discriminator   string
+	partitionKey    string
+	payload         []byte
+	attempts        int
+	nextAttemptTime int64
+}
+
+func (relay *Relay) Run(ctx context.Context) error {
+	options := relay.relayOptions()
+
+	tick := time.Tick(options.interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick:
+			if err := relay.relay(ctx, options); err != nil {
+				return err
+			}
+
+			if err := relay.cleanup(ctx, options); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (relay *Relay) relayOptions() *relayOptions {
+	options := &relayOptions{
+		interval:        1 * time.Second,
+		tableName:       defaultTableName,
+		placeholderFunc: PlaceholderQuestion,
+		batchSize:       100,
+		maxAttempts:     10,
+		backoff:         1 * time.Second,
+		maxBackoff:      5 * time.Minute,
+	}
+
+	if relay.options != nil {
+		if relay.options.TableName != "" {
+			options.tableName = relay.options.TableName
+		}
+
+		if relay.options.PlaceholderFunc != nil {
+			options.placeholderFunc = relay.options.PlaceholderFunc
+		}
+
+		if relay.options.Interval > 0 {
+			options.interval = relay.options.Interval
+		}
+
+		if relay.options.BatchSize > 0 {
+			options.batchSize = relay.options.BatchSize
+		}
+
+		if relay.options.MaxAttempts > 0 {
+			options.maxAttempts = relay.options.MaxAttempts
+		}
+
+		if relay.options.Backoff > 0 {
+			options.backoff = relay.options.Backoff
+		}
+
+		if relay.options.MaxBackoff > 0 {
+			options.maxBackoff = relay.options.MaxBackoff
+		}
+
+		if relay.options.Retention > 0 {
+			options.retention = relay.options.Retention
+		}
+	}
+
+	return options
+}
+
+func (relay *Relay) relay(ctx context.Context, options *relayOptions) error {
+	records, err := relay.read(ctx, options)
+
+	if err != nil {
+		return err
+	}
+
+	// Partition keys of the messages of the batch that must wait for a preceding message.
+	blockedPartitionKeys := map[string]struct{}{}
+
+	block := func(record *record) {
+		if record.partitionKey != "" {
+			blockedPartitionKeys[record.partitionKey] = struct{}{}
+		}
+	}
+
+	for _, record := range records {
+		if _, ok := blockedPartitionKeys[record.partitionKey]; ok {
+			continue
+		}
+
+		message, err := relay.unmarshalFunc(pubsub.Discriminator(record.discriminator), record.payload)
+
+		if err != nil {
+			// The message cannot be published by any attempt.
+			if err := relay.fail(ctx, options, record, err); err != nil {
+				return err
+			}
+
+			block(record)
+
+			continue
+		}
+
+		if err := relay.publishFunc(ctx, message); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			record.attempts++
+
+			if record.attempts >= options.maxAttempts {
+				if err := relay.fail(ctx, options, record, err); err != nil {
+					return err
+				}
+			} else if err := relay.retry(ctx, options, record, err); err != nil {
+				return err
+			}
+
+			block(record)
+
+			continue
+		}
+
+		query := fmt.Sprintf("UPDATE %s SET published_time = %s WHERE id = %s", options.tableName, options.placeholderFunc(1), options.placeholderFunc(2))
+
+		if _, err := relay.db.ExecContext(ctx, query, time.Now().UnixMilli(), record.id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// read returns the pending messages that are due, except the messages that follow a message with the same partition key
+// that is not due or that failed, so that the messages that wait do not fill the batches.
+func (relay *Relay) read(ctx context.Context, options *relayOptions) ([]*record, error) {
+	query := fmt.Sprintf(`SELECT id, discriminator, partition_key, payload, attempts, next_attempt_time FROM %[1]s AS message
+WHERE published_time IS NULL AND failed_time IS NULL AND next_attempt_time <= %[2]s
+AND (partition_key = '' OR NOT EXISTS (SELECT 1 FROM %[1]s AS preceding
+	WHERE preceding.partition_key = message.partition_key AND preceding.id < message.id AND preceding.published_time IS NULL
+	AND (preceding.failed_time IS NOT NULL OR preceding.next_attempt_time > %[3]s)))
+ORDER BY id LIMIT %[4]s`,
+		options.tableName, options.placeholderFunc(1), options.placeholderFunc(2), options.placeholderFunc(3))
+
+	now := time.Now().UnixMilli()
+
+	rows, err := relay.db.QueryContext(ctx, query, now, now, options.batchSize)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	records := make([]*record, 0, options.batchSize)
+
+	for rows.Next() {
+		record := &record{}
+
+		if err := rows.Scan(&record.id, &record.discriminator, &record.partitionKey, &record.payload, &record.attempts, &record.nextAttemptTime); err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (relay *Relay) retry(ctx context.Context, options *relayOptions, record *record, err error) error {
+	backoff := options.backoff
+
+	for i := 1; i < record.attempts && backoff < options.maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	backoff = min(backoff, options.maxBackoff)
+
+	relay.logger.Warn("outbox message was not published", "id", record.id, "attempts", record.attempts, "backoff", backoff, "error", err)
+
+	query := fmt.Sprintf("UPDATE %s SET attempts = %s, next_attempt_time = %s, last_error = %s WHERE id = %s",
+		options.tableName, options.placeholderFunc(1), options.placeholderFunc(2), options.placeholderFunc(3), options.placeholderFunc(4))
+
+	if _, err := relay.db.ExecContext(ctx, query, record.attempts, time.Now().Add(backoff).UnixMilli(), err.Error(), record.id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (relay *Relay) fail(ctx context.Context, options *relayOptions, record *record, err error) error {
+	relay.logger.Error("outbox message was marked as failed", "id", record.id, "attempts", record.attempts, "error", err)
+
+	query := fmt.Sprintf("UPDATE %s SET attempts = %s, failed_time = %s, last_error = %s WHERE id = %s",
+		options.tableName, options.placeholderFunc(1), options.placeholderFunc(2), options.placeholderFunc(3), options.placeholderFunc(4))
+
+	if _, err := relay.db.ExecContext(ctx, query, record.attempts, time.Now().UnixMilli(), err.Error(), record.id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (relay *Relay) cleanup(ctx context.Context, options *relayOptions) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE published_time IS NOT NULL AND published_time <= %s", options.tableName, options.placeholderFunc(1))
+
+	result, err := relay.db.ExecContext(ctx, query, time.Now().Add(-options.retention).UnixMilli())
+
+	if err != nil {
+		return err
+	}
+
+	if count, err := result.RowsAffected(); err == nil && count > 0 {
+		relay.logger.Debug("published outbox messages were deleted", "count", count)
+	}
+
+	return nil
+}
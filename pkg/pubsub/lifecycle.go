@@ -0,0 +1,190 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+type Settlement string
+
+const (
+	SettlementCompleted    Settlement = "Completed"
+	SettlementAbandoned    Settlement = "Abandoned"
+	SettlementDeadLettered Settlement = "DeadLettered"
+	// The message was completed without being dispatched, because it was filtered out.
+	SettlementSkipped Settlement = "Skipped"
+	// The message failed, but it was already deleted when it was received, so it is lost.
+	SettlementDropped Settlement = "Dropped"
+)
+
+// Hooks are called by the subscribers at the stages of their lifecycle. All hooks are optional and must not block.
+type Hooks struct {
+	OnStarted        func()
+	OnBatchReceived  func(count int)
+	OnIdle           func()
+	OnMessageSettled func(discriminator Discriminator, settlement Settlement, err error)
+	OnStopped        func(err error)
+}
+
+type HealthReport struct {
+	Running bool
+	Paused  bool
+	// Time of the last receive that did not fail, including receives that returned no messages and receives that were skipped
+	// because of backpressure.
+	LastReceiveTime time.Time
+	// Time of the last receive that returned messages.
+	LastMessageTime time.Time
+	// Number of messages in a row that were abandoned, dead lettered or dropped.
+	ConsecutiveFailures int
+	// Ratio between the buffered messages and the size of the partitions, 0 for subscribers without partitions.
+	PartitionSaturation float64
+	Err                 error
+}
+
+// Live reports whether the subscriber is running and has received within maxReceiveAge, or is paused.
+func (report HealthReport) Live(maxReceiveAge time.Duration) bool {
+	return report.Running && (report.Paused || time.Since(report.LastReceiveTime) <= maxReceiveAge)
+}
+
+// Ready reports whether the subscriber is running, is not paused and less than maxConsecutiveFailures messages in a row have failed.
+func (report HealthReport) Ready(maxConsecutiveFailures int) bool {
+	return report.Running && !report.Paused && report.ConsecutiveFailures < maxConsecutiveFailures
+}
+
+type HealthReporter interface {
+	Health() HealthReport
+}
+
+// Pauser is implemented by subscribers that can stop receiving new messages without stopping Run.
+type Pauser interface {
+	Pause()
+	Resume()
+}
+
+// Lifecycle calls the hooks and keeps the health report of a subscriber.
+type Lifecycle struct {
+	hooks  *Hooks
+	mutex  sync.Mutex
+	report HealthReport
+}
+
+func NewLifecycle(hooks *Hooks) *Lifecycle {
+	if hooks == nil {
+		hooks = &Hooks{}
+	}
+
+	return &Lifecycle{
+		hooks: hooks,
+	}
+}
+
+func (lifecycle *Lifecycle) Report() HealthReport {
+	lifecycle.mutex.Lock()
+
+	defer lifecycle.mutex.Unlock()
+
+	return lifecycle.report
+}
+
+func (lifecycle *Lifecycle) Started() {
+	lifecycle.mutex.Lock()
+
+	lifecycle.report = HealthReport{
+		Running:         true,
+		Paused:          lifecycle.report.Paused,
+		LastReceiveTime: time.Now(),
+	}
+
+	lifecycle.mutex.Unlock()
+
+	if lifecycle.hooks.OnStarted != nil {
+		lifecycle.hooks.OnStarted()
+	}
+}
+
+// Received is called after each receive that did not fail.
+func (lifecycle *Lifecycle) Received(count int) {
+	now := time.Now()
+
+	lifecycle.mutex.Lock()
+
+	lifecycle.report.LastReceiveTime = now
+
+	if count > 0 {
+		lifecycle.report.LastMessageTime = now
+	}
+
+	lifecycle.mutex.Unlock()
+
+	if count > 0 {
+		if lifecycle.hooks.OnBatchReceived != nil {
+			lifecycle.hooks.OnBatchReceived(count)
+		}
+	} else {
+		if lifecycle.hooks.OnIdle != nil {
+			lifecycle.hooks.OnIdle()
+		}
+	}
+}
+
+// Skipped is called when a receive is skipped or delayed because of backpressure, so that a subscriber that waits for its handlers is
+// still live. Backpressure is not a settlement, so it neither counts as a failure nor resets the consecutive failures.
+func (lifecycle *Lifecycle) Skipped() {
+	now := time.Now()
+
+	lifecycle.mutex.Lock()
+
+	lifecycle.report.LastReceiveTime = now
+
+	lifecycle.mutex.Unlock()
+}
+
+func (lifecycle *Lifecycle) Paused(paused bool) {
+	lifecycle.mutex.Lock()
+
+	lifecycle.report.Paused = paused
+
+	if !paused {
+		// The time spent paused does not count against liveness.
+		lifecycle.report.LastReceiveTime = time.Now()
+	}
+
+	lifecycle.mutex.Unlock()
+}
+
+func (lifecycle *Lifecycle) Saturated(partitionSaturation float64) {
+	lifecycle.mutex.Lock()
+
+	lifecycle.report.PartitionSaturation = partitionSaturation
+
+	lifecycle.mutex.Unlock()
+}
+
+func (lifecycle *Lifecycle) Settled(discriminator Discriminator, settlement Settlement, err error) {
+	lifecycle.mutex.Lock()
+
+	if settlement == SettlementAbandoned || settlement == SettlementDeadLettered || settlement == SettlementDropped {
+		lifecycle.report.ConsecutiveFailures++
+	} else {
+		lifecycle.report.ConsecutiveFailures = 0
+	}
+
+	lifecycle.mutex.Unlock()
+
+	if lifecycle.hooks.OnMessageSettled != nil {
+		lifecycle.hooks.OnMessageSettled(discriminator, settlement, err)
+	}
+}
+
+func (lifecycle *Lifecycle) Stopped(err error) {
+	lifecycle.mutex.Lock()
+
+	lifecycle.report.Running = false
+	lifecycle.report.Err = err
+
+	lifecycle.mutex.Unlock()
+
+	if lifecycle.hooks.OnStopped != nil {
+		lifecycle.hooks.OnStopped(err)
+	}
+}
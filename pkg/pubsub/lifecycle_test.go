@@ -0,0 +1,61 @@
+package pubsub
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSkippedKeepsTheSubscriberLive(t *testing.T) {
+	idle := false
+
+	lifecycle := NewLifecycle(&Hooks{
+		OnIdle: func() {
+			idle = true
+		},
+	})
+
+	lifecycle.Started()
+
+	lastReceiveTime := lifecycle.Report().LastReceiveTime
+
+	time.Sleep(time.Millisecond)
+
+	lifecycle.Skipped()
+
+	report := lifecycle.Report()
+
+	if !report.LastReceiveTime.After(lastReceiveTime) {
+		t.Fatal("last receive time was not updated")
+	}
+
+	if !report.LastMessageTime.IsZero() {
+		t.Fatal("last message time was updated")
+	}
+
+	if idle {
+		t.Fatal("subscriber is idle")
+	}
+}
+
+func TestSkippedIsNotAFailure(t *testing.T) {
+	lifecycle := NewLifecycle(nil)
+
+	lifecycle.Started()
+
+	lifecycle.Settled("Test", SettlementAbandoned, errors.New("failed"))
+
+	for range 10 {
+		lifecycle.Skipped()
+	}
+
+	report := lifecycle.Report()
+
+	if report.ConsecutiveFailures != 1 {
+		t.Fatalf("consecutive failures are %d, expected 1", report.ConsecutiveFailures)
+	}
+
+	if !report.Ready(2) {
+		t.Fatal("subscriber is not ready under backpressure")
+	}
+}
@@ -3,6 +3,7 @@ package pubsub
 import (
 	"context"
 	"errors"
+	"slices"
 )
 
 const (
@@ -11,6 +12,7 @@ const (
 
 var (
 	ErrInvalidDiscriminator = errors.New("invalid discriminator")
+	ErrInvalidBatchResults  = errors.New("invalid batch results")
 )
 
 type Discriminator string
@@ -28,6 +30,30 @@ type Handler interface {
 	Handle(message Message) error
 }
 
+// BatchHandler is optionally implemented by handlers that can process several messages of their discriminator at once.
+// HandleBatch returns the result of handling each message, in the order of the messages, so that the messages are settled individually.
+// A nil result means that all messages were handled.
+type BatchHandler interface {
+	Handler
+	HandleBatch(messages []Message) []error
+}
+
+// WaitFunc is called before each handler invocation with the messages of the invocation, e.g. to wait for a rate limit.
+// The messages are not handled if it returns an error.
+type WaitFunc func(messages []Message) error
+
+type DispatchOptions struct {
+	// Optional function called before each handler invocation.
+	WaitFunc WaitFunc
+}
+
+type DispatchResult struct {
+	Found bool
+	// The message was not dispatched, because it was filtered out.
+	Skipped bool
+	Err     error
+}
+
 type Dispatcher struct {
 	handlers map[Discriminator]Handler
 }
@@ -48,12 +74,125 @@ func (dispatcher *Dispatcher) Unregister(discriminator Discriminator) {
 	delete(dispatcher.handlers, discriminator)
 }
 
+// Discriminators returns the discriminators of the registered handlers, sorted.
+func (dispatcher *Dispatcher) Discriminators() []Discriminator {
+	discriminators := make([]Discriminator, 0, len(dispatcher.handlers))
+
+	for discriminator := range dispatcher.handlers {
+		discriminators = append(discriminators, discriminator)
+	}
+
+	slices.Sort(discriminators)
+
+	return discriminators
+}
+
 func (dispatcher *Dispatcher) Dispatch(discriminator Discriminator) (Handler, bool) {
 	handler, ok := dispatcher.handlers[discriminator]
 
 	return handler, ok
 }
 
+// DispatchBatch handles the messages with their handlers, in the order of the messages, and returns the result of handling each message.
+// The consecutive messages of a handler that implements BatchHandler are handled with a single call, so that the messages are never
+// handled out of order, e.g. when the messages of different discriminators share a partition.
+func (dispatcher *Dispatcher) DispatchBatch(messages []Message) []*DispatchResult {
+	return dispatcher.DispatchBatchWithOptions(messages, nil)
+}
+
+// DispatchBatchWithOptions is DispatchBatch with optional options.
+func (dispatcher *Dispatcher) DispatchBatchWithOptions(messages []Message, options *DispatchOptions) []*DispatchResult {
+	var waitFunc WaitFunc
+
+	if options != nil {
+		waitFunc = options.WaitFunc
+	}
+
+	results := make([]*DispatchResult, len(messages))
+
+	for i := 0; i < len(messages); {
+		discriminator := messages[i].Discriminator()
+
+		handler, ok := dispatcher.Dispatch(discriminator)
+
+		if !ok {
+			results[i] = &DispatchResult{Found: false}
+
+			i++
+
+			continue
+		}
+
+		batchHandler, ok := handler.(BatchHandler)
+
+		if !ok {
+			results[i] = dispatcher.handle(handler, messages[i], waitFunc)
+
+			i++
+
+			continue
+		}
+
+		j := i + 1
+
+		for j < len(messages) && messages[j].Discriminator() == discriminator {
+			j++
+		}
+
+		copy(results[i:j], dispatcher.handleBatch(batchHandler, messages[i:j], waitFunc))
+
+		i = j
+	}
+
+	return results
+}
+
+func (dispatcher *Dispatcher) handle(handler Handler, message Message, waitFunc WaitFunc) *DispatchResult {
+	if waitFunc != nil {
+		if err := waitFunc([]Message{message}); err != nil {
+			return &DispatchResult{Found: true, Err: err}
+		}
+	}
+
+	return &DispatchResult{Found: true, Err: handler.Handle(message)}
+}
+
+func (dispatcher *Dispatcher) handleBatch(batchHandler BatchHandler, messages []Message, waitFunc WaitFunc) []*DispatchResult {
+	var errs []error
+
+	if waitFunc != nil {
+		if err := waitFunc(messages); err != nil {
+			errs = make([]error, len(messages))
+
+			for i := range errs {
+				errs[i] = err
+			}
+		}
+	}
+
+	if errs == nil {
+		errs = batchHandler.HandleBatch(messages)
+	}
+
+	results := make([]*DispatchResult, 0, len(messages))
+
+	for i := range messages {
+		result := &DispatchResult{Found: true}
+
+		switch {
+		case errs == nil:
+		case len(errs) != len(messages):
+			result.Err = ErrInvalidBatchResults
+		default:
+			result.Err = errs[i]
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
 type Subscriber interface {
 	Run(ctx context.Context) error
 }
@@ -0,0 +1,129 @@
+package pubsub
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+type testMessage struct {
+	discriminator Discriminator
+	id            int
+}
+
+func (message *testMessage) Discriminator() Discriminator {
+	return message.discriminator
+}
+
+type testHandler struct {
+	discriminator Discriminator
+	calls         *[][]int
+	err           error
+}
+
+func (handler *testHandler) Discriminator() Discriminator {
+	return handler.discriminator
+}
+
+func (handler *testHandler) Handle(message Message) error {
+	*handler.calls = append(*handler.calls, []int{message.(*testMessage).id})
+
+	return handler.err
+}
+
+type testBatchHandler struct {
+	testHandler
+}
+
+func (handler *testBatchHandler) HandleBatch(messages []Message) []error {
+	ids := make([]int, 0, len(messages))
+
+	for _, message := range messages {
+		ids = append(ids, message.(*testMessage).id)
+	}
+
+	*handler.calls = append(*handler.calls, ids)
+
+	return nil
+}
+
+func TestDispatchBatchKeepsTheOrderOfTheMessages(t *testing.T) {
+	calls := [][]int{}
+
+	dispatcher := NewDispatcher()
+
+	dispatcher.Register(&testBatchHandler{testHandler{discriminator: "Batch", calls: &calls}})
+	dispatcher.Register(&testHandler{discriminator: "Single", calls: &calls, err: errors.New("failed")})
+
+	messages := []Message{
+		&testMessage{discriminator: "Batch", id: 1},
+		&testMessage{discriminator: "Batch", id: 2},
+		&testMessage{discriminator: "Single", id: 3},
+		&testMessage{discriminator: "Unknown", id: 4},
+		&testMessage{discriminator: "Batch", id: 5},
+	}
+
+	results := dispatcher.DispatchBatch(messages)
+
+	expectedCalls := [][]int{{1, 2}, {3}, {5}}
+
+	if !slices.EqualFunc(calls, expectedCalls, slices.Equal) {
+		t.Fatalf("handlers were called with %v, expected %v", calls, expectedCalls)
+	}
+
+	if !results[0].Found || results[0].Err != nil || results[2].Err == nil || results[3].Found || results[4].Err != nil {
+		t.Fatalf("unexpected results %+v %+v %+v %+v %+v", results[0], results[1], results[2], results[3], results[4])
+	}
+}
+
+func TestDispatchBatchWithOptionsWaitsBeforeEachInvocation(t *testing.T) {
+	calls := [][]int{}
+
+	dispatcher := NewDispatcher()
+
+	dispatcher.Register(&testBatchHandler{testHandler{discriminator: "Batch", calls: &calls}})
+	dispatcher.Register(&testHandler{discriminator: "Single", calls: &calls})
+
+	messages := []Message{
+		&testMessage{discriminator: "Batch", id: 1},
+		&testMessage{discriminator: "Batch", id: 2},
+		&testMessage{discriminator: "Single", id: 3},
+	}
+
+	waits := [][]int{}
+
+	errWait := errors.New("wait failed")
+
+	results := dispatcher.DispatchBatchWithOptions(messages, &DispatchOptions{
+		WaitFunc: func(messages []Message) error {
+			ids := make([]int, 0, len(messages))
+
+			for _, message := range messages {
+				ids = append(ids, message.(*testMessage).id)
+			}
+
+			waits = append(waits, ids)
+
+			if ids[0] == 3 {
+				return errWait
+			}
+
+			return nil
+		},
+	})
+
+	expectedWaits := [][]int{{1, 2}, {3}}
+
+	if !slices.EqualFunc(waits, expectedWaits, slices.Equal) {
+		t.Fatalf("wait function was called with %v, expected %v", waits, expectedWaits)
+	}
+
+	// The message is not handled when the wait fails.
+	if !slices.EqualFunc(calls, [][]int{{1, 2}}, slices.Equal) {
+		t.Fatalf("handlers were called with %v, expected [[1 2]]", calls)
+	}
+
+	if !errors.Is(results[2].Err, errWait) {
+		t.Fatalf("error is %v, expected %v", results[2].Err, errWait)
+	}
+}
@@ -0,0 +1,146 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	ErrScheduledMessageNotFound = errors.New("scheduled message not found")
+)
+
+// Scheduler is implemented by publishers that can publish messages at a later time. Schedule returns the sequence number
+// of the scheduled message, which is passed to CancelScheduled to cancel it before it is published.
+type Scheduler interface {
+	Schedule(ctx context.Context, message Message, scheduledTime time.Time) (int64, error)
+	CancelScheduled(ctx context.Context, sequenceNumber int64) error
+}
+
+type PublishFunc func(ctx context.Context, message Message) error
+
+// FallbackScheduler schedules with a publisher that schedules natively, or with a TimerScheduler if there is none, so that
+// the callers close it the same way in both cases.
+type FallbackScheduler struct {
+	Scheduler
+	timerScheduler *TimerScheduler
+}
+
+// NewScheduler schedules with scheduler, e.g. a publisher that schedules natively, or with a TimerScheduler that publishes
+// with publishFunc if scheduler is nil. The FallbackScheduler must be closed.
+func NewScheduler(scheduler Scheduler, publishFunc PublishFunc, logger *slog.Logger) *FallbackScheduler {
+	if scheduler != nil {
+		return &FallbackScheduler{
+			Scheduler: scheduler,
+		}
+	}
+
+	timerScheduler := NewTimerScheduler(publishFunc, logger)
+
+	return &FallbackScheduler{
+		Scheduler:      timerScheduler,
+		timerScheduler: timerScheduler,
+	}
+}
+
+// Close cancels the messages of the TimerScheduler that are not published yet and returns their number. The messages
+// that are scheduled natively are kept.
+func (scheduler *FallbackScheduler) Close() int {
+	if scheduler.timerScheduler == nil {
+		return 0
+	}
+
+	return scheduler.timerScheduler.Close()
+}
+
+// TimerScheduler is the fallback for transports without native scheduling. The messages are kept in memory until their
+// scheduled time, so the messages that are scheduled when the process stops are lost.
+type TimerScheduler struct {
+	publishFunc    PublishFunc
+	logger         *slog.Logger
+	mutex          sync.Mutex
+	sequenceNumber int64
+	timers         map[int64]*time.Timer
+}
+
+func NewTimerScheduler(publishFunc PublishFunc, logger *slog.Logger) *TimerScheduler {
+	return &TimerScheduler{
+		publishFunc: publishFunc,
+		logger:      logger,
+		timers:      map[int64]*time.Timer{},
+	}
+}
+
+func (scheduler *TimerScheduler) Schedule(ctx context.Context, message Message, scheduledTime time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	// The message is published after Schedule returns, so it must not be canceled with the context of the caller.
+	publishCtx := context.WithoutCancel(ctx)
+
+	scheduler.mutex.Lock()
+
+	defer scheduler.mutex.Unlock()
+
+	scheduler.sequenceNumber++
+
+	sequenceNumber := scheduler.sequenceNumber
+
+	scheduler.timers[sequenceNumber] = time.AfterFunc(time.Until(scheduledTime), func() {
+		scheduler.mutex.Lock()
+
+		_, ok := scheduler.timers[sequenceNumber]
+
+		delete(scheduler.timers, sequenceNumber)
+
+		scheduler.mutex.Unlock()
+
+		if !ok {
+			return
+		}
+
+		if err := scheduler.publishFunc(publishCtx, message); err != nil {
+			scheduler.logger.Error("scheduled message was not published", "sequenceNumber", sequenceNumber, "discriminator", message.Discriminator(), "error", err)
+		}
+	})
+
+	return sequenceNumber, nil
+}
+
+func (scheduler *TimerScheduler) CancelScheduled(ctx context.Context, sequenceNumber int64) error {
+	scheduler.mutex.Lock()
+
+	defer scheduler.mutex.Unlock()
+
+	timer, ok := scheduler.timers[sequenceNumber]
+
+	if !ok {
+		return ErrScheduledMessageNotFound
+	}
+
+	timer.Stop()
+
+	delete(scheduler.timers, sequenceNumber)
+
+	return nil
+}
+
+// Close cancels the messages that are not published yet and returns their number.
+func (scheduler *TimerScheduler) Close() int {
+	scheduler.mutex.Lock()
+
+	defer scheduler.mutex.Unlock()
+
+	count := len(scheduler.timers)
+
+	for sequenceNumber, timer := range scheduler.timers {
+		timer.Stop()
+
+		delete(scheduler.timers, sequenceNumber)
+	}
+
+	return count
+}
@@ -0,0 +1,96 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestNewSchedulerFallsBackToTimers(t *testing.T) {
+	published := make(chan Message, 1)
+
+	publishFunc := func(ctx context.Context, message Message) error {
+		published <- message
+
+		return nil
+	}
+
+	scheduler := NewScheduler(nil, publishFunc, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if _, ok := scheduler.Scheduler.(*TimerScheduler); !ok {
+		t.Fatalf("scheduler is %T, expected *TimerScheduler", scheduler.Scheduler)
+	}
+
+	message := &testMessage{discriminator: "Scheduled", id: 1}
+
+	if _, err := scheduler.Schedule(context.Background(), message, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case publishedMessage := <-published:
+		if publishedMessage != message {
+			t.Fatalf("message is %v, expected %v", publishedMessage, message)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message was not published")
+	}
+
+	canceledSequenceNumber, err := scheduler.Schedule(context.Background(), message, time.Now().Add(time.Hour))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scheduler.CancelScheduled(context.Background(), canceledSequenceNumber); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scheduler.CancelScheduled(context.Background(), canceledSequenceNumber); !errors.Is(err, ErrScheduledMessageNotFound) {
+		t.Fatalf("error is %v, expected %v", err, ErrScheduledMessageNotFound)
+	}
+
+	if _, err := scheduler.Schedule(context.Background(), message, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := scheduler.Close(); count != 1 {
+		t.Fatalf("%d messages were dropped, expected 1", count)
+	}
+}
+
+type testScheduler struct {
+	scheduled int
+}
+
+func (scheduler *testScheduler) Schedule(ctx context.Context, message Message, scheduledTime time.Time) (int64, error) {
+	scheduler.scheduled++
+
+	return int64(scheduler.scheduled), nil
+}
+
+func (scheduler *testScheduler) CancelScheduled(ctx context.Context, sequenceNumber int64) error {
+	return nil
+}
+
+func TestNewSchedulerUsesTheNativeScheduler(t *testing.T) {
+	nativeScheduler := &testScheduler{}
+
+	scheduler := NewScheduler(nativeScheduler, nil, nil)
+
+	if _, err := scheduler.Schedule(context.Background(), &testMessage{discriminator: "Scheduled", id: 1}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if nativeScheduler.scheduled != 1 {
+		t.Fatalf("%d messages were scheduled natively, expected 1", nativeScheduler.scheduled)
+	}
+
+	// The messages scheduled natively are kept by the transport.
+	if count := scheduler.Close(); count != 0 {
+		t.Fatalf("%d messages were dropped, expected 0", count)
+	}
+}
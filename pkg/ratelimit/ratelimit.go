@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+type Limit struct {
+	// Number of messages handled per second.
+	Rate float64
+	// Number of messages allowed at once. A batch larger than the burst is allowed once the bucket is full, and the
+	// following messages wait until the tokens it took in advance are refilled.
+	Burst int
+}
+
+type bucket struct {
+	limit  *Limit
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(limit *Limit, now time.Time) *bucket {
+	return &bucket{
+		limit:  limit,
+		tokens: float64(max(limit.Burst, 1)),
+		last:   now,
+	}
+}
+
+// reserve takes count tokens and returns how long to wait until the tokens are available. Tokens can be taken in advance,
+// so that the invocations that wait are spread according to the rate.
+func (bucket *bucket) reserve(now time.Time, count int) time.Duration {
+	burst := float64(max(bucket.limit.Burst, 1))
+
+	bucket.tokens = min(bucket.tokens+now.Sub(bucket.last).Seconds()*bucket.limit.Rate, burst)
+	bucket.last = now
+
+	bucket.tokens -= float64(count)
+
+	if bucket.tokens >= 0 || bucket.limit.Rate <= 0 {
+		return 0
+	}
+
+	return time.Duration(-bucket.tokens / bucket.limit.Rate * float64(time.Second))
+}
+
+type GetTenantGroupNameFunc func(message pubsub.Message) (string, error)
+
+type LimiterOptions struct {
+	Global         *Limit
+	Discriminators map[pubsub.Discriminator]*Limit
+	TenantGroups   map[string]*Limit
+}
+
+// Limiter limits the handled messages with token buckets, globally, per discriminator and per tenant group.
+// Each message takes a token from every bucket that applies to it, so that a batch handler invocation takes as many
+// tokens as it has messages.
+type Limiter struct {
+	getTenantGroupNameFunc GetTenantGroupNameFunc
+	options                *LimiterOptions
+	mutex                  sync.Mutex
+	buckets                map[string]*bucket
+}
+
+func NewLimiter(getTenantGroupNameFunc GetTenantGroupNameFunc, options *LimiterOptions) *Limiter {
+	return &Limiter{
+		getTenantGroupNameFunc: getTenantGroupNameFunc,
+		options:                options,
+		buckets:                map[string]*bucket{},
+	}
+}
+
+func (limiter *Limiter) take(key string, limit *Limit, now time.Time, count int) time.Duration {
+	if limit == nil {
+		return 0
+	}
+
+	bucket, ok := limiter.buckets[key]
+
+	if !ok {
+		bucket = newBucket(limit, now)
+
+		limiter.buckets[key] = bucket
+	}
+
+	return bucket.reserve(now, count)
+}
+
+// Reserve takes the tokens of the messages of a single handler invocation and returns how long to wait before the invocation.
+func (limiter *Limiter) Reserve(messages []pubsub.Message) (time.Duration, error) {
+	if limiter.options == nil || len(messages) == 0 {
+		return 0, nil
+	}
+
+	// Number of messages of each discriminator and tenant group.
+	discriminators := map[pubsub.Discriminator]int{}
+	tenantGroupNames := map[string]int{}
+
+	for _, message := range messages {
+		discriminators[message.Discriminator()]++
+
+		if limiter.getTenantGroupNameFunc != nil && len(limiter.options.TenantGroups) != 0 {
+			tenantGroupName, err := limiter.getTenantGroupNameFunc(message)
+
+			if err != nil {
+				return 0, err
+			}
+
+			tenantGroupNames[tenantGroupName]++
+		}
+	}
+
+	now := time.Now()
+
+	limiter.mutex.Lock()
+
+	defer limiter.mutex.Unlock()
+
+	delay := limiter.take("global", limiter.options.Global, now, len(messages))
+
+	for discriminator, count := range discriminators {
+		delay = max(delay, limiter.take("discriminator~"+string(discriminator), limiter.options.Discriminators[discriminator], now, count))
+	}
+
+	for tenantGroupName, count := range tenantGroupNames {
+		delay = max(delay, limiter.take("tenantgroup~"+tenantGroupName, limiter.options.TenantGroups[tenantGroupName], now, count))
+	}
+
+	return delay, nil
+}
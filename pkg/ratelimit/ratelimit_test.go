@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+type testMessage struct {
+	discriminator   pubsub.Discriminator
+	tenantGroupName string
+}
+
+func (message *testMessage) Discriminator() pubsub.Discriminator {
+	return message.discriminator
+}
+
+func getTestTenantGroupName(message pubsub.Message) (string, error) {
+	return message.(*testMessage).tenantGroupName, nil
+}
+
+// isAbout reports whether delay is expected, within the time elapsed since the tokens were reserved.
+func isAbout(delay time.Duration, expected time.Duration) bool {
+	return delay <= expected && delay > expected-100*time.Millisecond
+}
+
+func TestBucketReservesTheTokens(t *testing.T) {
+	now := time.Now()
+
+	bucket := newBucket(&Limit{Rate: 2, Burst: 2}, now)
+
+	tests := []struct {
+		name     string
+		after    time.Duration
+		count    int
+		expected time.Duration
+	}{
+		{"within the burst", 0, 1, 0},
+		{"up to the burst", 0, 1, 0},
+		{"over the burst", 0, 1, 500 * time.Millisecond},
+		{"taken in advance", 0, 2, 1500 * time.Millisecond},
+		{"refilled", 2 * time.Second, 1, 0},
+		{"refilled up to the burst", 10 * time.Second, 3, 500 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		now = now.Add(test.after)
+
+		if delay := bucket.reserve(now, test.count); delay != test.expected {
+			t.Fatalf("%s: delay is %v, expected %v", test.name, delay, test.expected)
+		}
+	}
+}
+
+func TestBucketWithoutRateDoesNotWait(t *testing.T) {
+	now := time.Now()
+
+	bucket := newBucket(&Limit{}, now)
+
+	for range 3 {
+		if delay := bucket.reserve(now, 5); delay != 0 {
+			t.Fatalf("delay is %v, expected 0", delay)
+		}
+	}
+}
+
+func TestReserveTakesOneTokenPerMessage(t *testing.T) {
+	limiter := NewLimiter(nil, &LimiterOptions{
+		Global: &Limit{Rate: 1, Burst: 3},
+	})
+
+	messages := []pubsub.Message{&testMessage{discriminator: "A"}, &testMessage{discriminator: "A"}, &testMessage{discriminator: "A"}}
+
+	if delay, err := limiter.Reserve(messages); err != nil || delay != 0 {
+		t.Fatalf("delay is %v and error is %v, expected no delay", delay, err)
+	}
+
+	delay, err := limiter.Reserve(messages[:1])
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !isAbout(delay, time.Second) {
+		t.Fatalf("delay is %v, expected %v", delay, time.Second)
+	}
+}
+
+func TestReserveTakesTheTokensOfEachDiscriminatorAndTenantGroup(t *testing.T) {
+	limiter := NewLimiter(getTestTenantGroupName, &LimiterOptions{
+		Discriminators: map[pubsub.Discriminator]*Limit{"A": {Rate: 1, Burst: 2}},
+		TenantGroups:   map[string]*Limit{"group": {Rate: 1, Burst: 3}},
+	})
+
+	messages := []pubsub.Message{
+		&testMessage{discriminator: "A", tenantGroupName: "group"},
+		&testMessage{discriminator: "B", tenantGroupName: "group"},
+		&testMessage{discriminator: "A", tenantGroupName: "other"},
+	}
+
+	if delay, err := limiter.Reserve(messages); err != nil || delay != 0 {
+		t.Fatalf("delay is %v and error is %v, expected no delay", delay, err)
+	}
+
+	tests := []struct {
+		name     string
+		message  pubsub.Message
+		expected time.Duration
+	}{
+		{"discriminator without limit", &testMessage{discriminator: "B", tenantGroupName: "other"}, 0},
+		{"tenant group within the burst", &testMessage{discriminator: "B", tenantGroupName: "group"}, 0},
+		{"discriminator over the burst", &testMessage{discriminator: "A", tenantGroupName: "other"}, time.Second},
+		{"tenant group over the burst", &testMessage{discriminator: "B", tenantGroupName: "group"}, time.Second},
+	}
+
+	for _, test := range tests {
+		delay, err := limiter.Reserve([]pubsub.Message{test.message})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if (test.expected == 0 && delay != 0) || (test.expected != 0 && !isAbout(delay, test.expected)) {
+			t.Fatalf("%s: delay is %v, expected %v", test.name, delay, test.expected)
+		}
+	}
+}
+
+func TestReserveWithoutOptionsDoesNotWait(t *testing.T) {
+	limiter := NewLimiter(nil, nil)
+
+	if delay, err := limiter.Reserve([]pubsub.Message{&testMessage{discriminator: "A"}}); err != nil || delay != 0 {
+		t.Fatalf("delay is %v and error is %v, expected no delay", delay, err)
+	}
+}
@@ -0,0 +1,509 @@
+package spool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+var (
+	ErrSpoolFull = errors.New("spool full")
+)
+
+const (
+	recordExtension       = ".msg"
+	failedRecordExtension = ".failed"
+	tempRecordExtension   = ".tmp"
+)
+
+type MarshalFunc func(message pubsub.Message) ([]byte, error)
+
+type UnmarshalFunc func(discriminator pubsub.Discriminator, payload []byte) (pubsub.Message, error)
+
+type CreateMessageFunc func(discriminator pubsub.Discriminator) pubsub.Message
+
+func MarshalJSON(message pubsub.Message) ([]byte, error) {
+	return json.Marshal(message)
+}
+
+func NewUnmarshalJSONFunc(createMessageFunc CreateMessageFunc) UnmarshalFunc {
+	return func(discriminator pubsub.Discriminator, payload []byte) (pubsub.Message, error) {
+		message := createMessageFunc(discriminator)
+
+		if err := json.Unmarshal(payload, &message); err != nil {
+			return nil, err
+		}
+
+		return message, nil
+	}
+}
+
+// RetryableFunc reports whether publishing can succeed later after it failed with err, e.g. when the broker is unreachable.
+type RetryableFunc func(err error) bool
+
+type SpoolOptions struct {
+	// Number of attempts to publish a message before it is spooled, defaults to 3.
+	MaxAttempts int
+	// Delay before the second attempt, doubled after each attempt up to MaxBackoff, defaults to 1 second.
+	Backoff time.Duration
+	// Defaults to 30 seconds.
+	MaxBackoff time.Duration
+	// Time interval to replay the spooled messages, defaults to 10 seconds.
+	Interval time.Duration
+	// Maximum size in bytes of the spooled messages, 0 (unbounded) by default. Publish fails with ErrSpoolFull when
+	// a message does not fit.
+	MaxBytes int64
+	// Optional, by default all errors are retryable. The messages that fail with an error that is not retryable are not
+	// spooled, and the spooled messages that fail with such an error are set aside in a ".failed" file.
+	RetryableFunc RetryableFunc
+}
+
+type SpoolMetrics struct {
+	// Messages published without being spooled.
+	Published int64
+	// Failed attempts to publish a message, both before it is spooled and when it is replayed.
+	Retried int64
+	Spooled int64
+	// Spooled messages that were published.
+	Replayed int64
+	// Messages that were not spooled because the spool was full.
+	Rejected int64
+	// Spooled messages that were set aside.
+	Failed          int64
+	PendingMessages int64
+	PendingBytes    int64
+}
+
+type record struct {
+	Discriminator pubsub.Discriminator `json:"Discriminator"`
+	Payload       []byte               `json:"Payload"`
+}
+
+// Spool publishes messages with retries and, when publishing keeps failing, stores them in a journal in directory,
+// one file per message, until Run replays them in order. Once a message is spooled, the following messages are spooled
+// too until the journal is replayed, so that the order of the messages is preserved.
+type Spool struct {
+	directory     string
+	marshalFunc   MarshalFunc
+	unmarshalFunc UnmarshalFunc
+	publishFunc   pubsub.PublishFunc
+	logger        *slog.Logger
+	options       *SpoolOptions
+	// Serializes Publish, so that a message is not published directly while a preceding message is being spooled.
+	publishMutex sync.Mutex
+	mutex        sync.Mutex
+	// Sequence number of the next spooled message.
+	sequenceNumber int64
+	metrics        SpoolMetrics
+}
+
+// NewSpool creates directory if needed and loads the messages spooled by a previous process, which are replayed first.
+func NewSpool(directory string, marshalFunc MarshalFunc, unmarshalFunc UnmarshalFunc, publishFunc pubsub.PublishFunc, logger *slog.Logger, options *SpoolOptions) (*Spool, error) {
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return nil, err
+	}
+
+	spool := &Spool{
+		directory:      directory,
+		marshalFunc:    marshalFunc,
+		unmarshalFunc:  unmarshalFunc,
+		publishFunc:    publishFunc,
+		logger:         logger,
+		options:        options,
+		sequenceNumber: 1,
+	}
+
+	sequenceNumbers, err := spool.list(recordExtension)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sequenceNumber := range sequenceNumbers {
+		fileInfo, err := os.Stat(spool.path(sequenceNumber, recordExtension))
+
+		if err != nil {
+			return nil, err
+		}
+
+		spool.metrics.PendingMessages++
+		spool.metrics.PendingBytes += fileInfo.Size()
+	}
+
+	// The sequence numbers of the records that were set aside or not completely written are not reused, so that
+	// a record is never renamed over another one.
+	allSequenceNumbers, err := spool.list(recordExtension, failedRecordExtension, tempRecordExtension)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(allSequenceNumbers) > 0 {
+		spool.sequenceNumber = allSequenceNumbers[len(allSequenceNumbers)-1] + 1
+	}
+
+	if spool.metrics.PendingMessages > 0 {
+		logger.Info("spooled messages were found", "messages", spool.metrics.PendingMessages, "bytes", spool.metrics.PendingBytes)
+	}
+
+	return spool, nil
+}
+
+func (spool *Spool) Metrics() SpoolMetrics {
+	spool.mutex.Lock()
+
+	defer spool.mutex.Unlock()
+
+	return spool.metrics
+}
+
+// Publish returns nil once the message is either published or spooled. The calls are serialized to preserve the order
+// of the messages, so a call waits while the retries of the preceding call are in progress.
+func (spool *Spool) Publish(ctx context.Context, message pubsub.Message) error {
+	spool.publishMutex.Lock()
+
+	defer spool.publishMutex.Unlock()
+
+	spool.mutex.Lock()
+
+	pending := spool.metrics.PendingMessages > 0
+
+	spool.mutex.Unlock()
+
+	if pending {
+		return spool.append(message)
+	}
+
+	err := spool.publish(ctx, message)
+
+	if err == nil {
+		spool.mutex.Lock()
+
+		spool.metrics.Published++
+
+		spool.mutex.Unlock()
+
+		return nil
+	}
+
+	if ctx.Err() != nil || !spool.retryable(err) {
+		return err
+	}
+
+	spool.logger.Warn("message was not published and is spooled", "discriminator", message.Discriminator(), "error", err)
+
+	return spool.append(message)
+}
+
+// Run replays the spooled messages at each interval until ctx is done. A replay stops at the first message that fails,
+// and the errors of the journal are logged, so that the replay is attempted again at the next interval.
+func (spool *Spool) Run(ctx context.Context) error {
+	interval := 10 * time.Second
+
+	if spool.options != nil && spool.options.Interval > 0 {
+		interval = spool.options.Interval
+	}
+
+	tick := time.Tick(interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick:
+			if err := spool.replay(ctx); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				spool.logger.Error("spooled messages were not replayed", "error", err)
+			}
+		}
+	}
+}
+
+func (spool *Spool) publish(ctx context.Context, message pubsub.Message) error {
+	maxAttempts := 3
+	backoff := 1 * time.Second
+	maxBackoff := 30 * time.Second
+
+	if spool.options != nil {
+		if spool.options.MaxAttempts > 0 {
+			maxAttempts = spool.options.MaxAttempts
+		}
+
+		if spool.options.Backoff > 0 {
+			backoff = spool.options.Backoff
+		}
+
+		if spool.options.MaxBackoff > 0 {
+			maxBackoff = spool.options.MaxBackoff
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := spool.publishFunc(ctx, message)
+
+		if err == nil {
+			return nil
+		}
+
+		spool.mutex.Lock()
+
+		spool.metrics.Retried++
+
+		spool.mutex.Unlock()
+
+		if attempt >= maxAttempts || !spool.retryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+func (spool *Spool) retryable(err error) bool {
+	if spool.options == nil || spool.options.RetryableFunc == nil {
+		return true
+	}
+
+	return spool.options.RetryableFunc(err)
+}
+
+func (spool *Spool) append(message pubsub.Message) error {
+	payload, err := spool.marshalFunc(message)
+
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(&record{
+		Discriminator: message.Discriminator(),
+		Payload:       payload,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	spool.mutex.Lock()
+
+	defer spool.mutex.Unlock()
+
+	if spool.options != nil && spool.options.MaxBytes > 0 && spool.metrics.PendingBytes+int64(len(data)) > spool.options.MaxBytes {
+		spool.metrics.Rejected++
+
+		return ErrSpoolFull
+	}
+
+	sequenceNumber := spool.sequenceNumber
+
+	// The record is written to a temporary file that is renamed once it is synced, so that a crash does not leave
+	// a partial record in the journal.
+	tempPath := spool.path(sequenceNumber, tempRecordExtension)
+
+	if err := writeFile(tempPath, data); err != nil {
+		os.Remove(tempPath)
+
+		return err
+	}
+
+	if err := os.Rename(tempPath, spool.path(sequenceNumber, recordExtension)); err != nil {
+		os.Remove(tempPath)
+
+		return err
+	}
+
+	spool.sequenceNumber++
+
+	spool.metrics.Spooled++
+	spool.metrics.PendingMessages++
+	spool.metrics.PendingBytes += int64(len(data))
+
+	return nil
+}
+
+func (spool *Spool) replay(ctx context.Context) error {
+	spool.mutex.Lock()
+
+	sequenceNumbers, err := spool.list(recordExtension)
+
+	spool.mutex.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	replayed := 0
+
+	for _, sequenceNumber := range sequenceNumbers {
+		path := spool.path(sequenceNumber, recordExtension)
+
+		data, err := os.ReadFile(path)
+
+		if err != nil {
+			return err
+		}
+
+		size := int64(len(data))
+
+		record := &record{}
+
+		if err := json.Unmarshal(data, record); err != nil {
+			if err := spool.setAside(sequenceNumber, size, err); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		message, err := spool.unmarshalFunc(record.Discriminator, record.Payload)
+
+		if err != nil {
+			if err := spool.setAside(sequenceNumber, size, err); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := spool.publishFunc(ctx, message); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			spool.mutex.Lock()
+
+			spool.metrics.Retried++
+
+			spool.mutex.Unlock()
+
+			if !spool.retryable(err) {
+				if err := spool.setAside(sequenceNumber, size, err); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			spool.logger.Warn("spooled message was not replayed", "sequenceNumber", sequenceNumber, "error", err)
+
+			break
+		}
+
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+
+		spool.mutex.Lock()
+
+		spool.metrics.Replayed++
+		spool.metrics.PendingMessages--
+		spool.metrics.PendingBytes -= size
+
+		spool.mutex.Unlock()
+
+		replayed++
+	}
+
+	if replayed > 0 {
+		spool.logger.Info("spooled messages were replayed", "messages", replayed)
+	}
+
+	return nil
+}
+
+// setAside renames the record of a message that cannot be published, so that it does not block the following messages.
+func (spool *Spool) setAside(sequenceNumber int64, size int64, err error) error {
+	spool.logger.Error("spooled message was set aside", "sequenceNumber", sequenceNumber, "error", err)
+
+	if err := os.Rename(spool.path(sequenceNumber, recordExtension), spool.path(sequenceNumber, failedRecordExtension)); err != nil {
+		return err
+	}
+
+	spool.mutex.Lock()
+
+	spool.metrics.Failed++
+	spool.metrics.PendingMessages--
+	spool.metrics.PendingBytes -= size
+
+	spool.mutex.Unlock()
+
+	return nil
+}
+
+// list returns the sequence numbers of the records with any of extensions, in order.
+func (spool *Spool) list(extensions ...string) ([]int64, error) {
+	dirEntries, err := os.ReadDir(spool.directory)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sequenceNumbers := make([]int64, 0, len(dirEntries))
+
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+
+		extension := filepath.Ext(name)
+
+		if dirEntry.IsDir() || !slices.Contains(extensions, extension) {
+			continue
+		}
+
+		sequenceNumber, err := strconv.ParseInt(strings.TrimSuffix(name, extension), 10, 64)
+
+		if err != nil {
+			continue
+		}
+
+		sequenceNumbers = append(sequenceNumbers, sequenceNumber)
+	}
+
+	slices.Sort(sequenceNumbers)
+
+	return sequenceNumbers, nil
+}
+
+func (spool *Spool) path(sequenceNumber int64, extension string) string {
+	return filepath.Join(spool.directory, fmt.Sprintf("%020d%s", sequenceNumber, extension))
+}
+
+func writeFile(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	return file.Close()
+}
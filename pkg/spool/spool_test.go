@@ -0,0 +1,246 @@
+package spool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/scaleforce/synchronization-for-go/pkg/pubsub"
+)
+
+type testMessage struct {
+	ID int `json:"ID"`
+}
+
+func (message *testMessage) Discriminator() pubsub.Discriminator {
+	return "Test"
+}
+
+type testPublisher struct {
+	mutex sync.Mutex
+	ids   []int
+	err   error
+}
+
+func (publisher *testPublisher) publish(ctx context.Context, message pubsub.Message) error {
+	publisher.mutex.Lock()
+
+	defer publisher.mutex.Unlock()
+
+	if publisher.err != nil {
+		return publisher.err
+	}
+
+	publisher.ids = append(publisher.ids, message.(*testMessage).ID)
+
+	return nil
+}
+
+func (publisher *testPublisher) setErr(err error) {
+	publisher.mutex.Lock()
+
+	defer publisher.mutex.Unlock()
+
+	publisher.err = err
+}
+
+func (publisher *testPublisher) published() []int {
+	publisher.mutex.Lock()
+
+	defer publisher.mutex.Unlock()
+
+	return slices.Clone(publisher.ids)
+}
+
+func newTestSpool(t *testing.T, directory string, publisher *testPublisher, options *SpoolOptions) *Spool {
+	t.Helper()
+
+	unmarshalFunc := NewUnmarshalJSONFunc(func(discriminator pubsub.Discriminator) pubsub.Message {
+		return &testMessage{}
+	})
+
+	spool, err := NewSpool(directory, MarshalJSON, unmarshalFunc, publisher.publish, slog.New(slog.NewTextHandler(io.Discard, nil)), options)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return spool
+}
+
+func TestPublishSpoolsTheFollowingMessagesUntilTheyAreReplayed(t *testing.T) {
+	publisher := &testPublisher{err: errors.New("unreachable")}
+
+	spool := newTestSpool(t, t.TempDir(), publisher, &SpoolOptions{MaxAttempts: 1})
+
+	for id := 1; id <= 2; id++ {
+		if err := spool.Publish(context.Background(), &testMessage{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	publisher.setErr(nil)
+
+	// The message is spooled behind the pending messages, although it could be published.
+	if err := spool.Publish(context.Background(), &testMessage{ID: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := publisher.published(); len(ids) != 0 {
+		t.Fatalf("published messages are %v, expected none before the replay", ids)
+	}
+
+	if err := spool.replay(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := spool.Publish(context.Background(), &testMessage{ID: 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := publisher.published(); !slices.Equal(ids, []int{1, 2, 3, 4}) {
+		t.Fatalf("published messages are %v, expected [1 2 3 4]", ids)
+	}
+
+	metrics := spool.Metrics()
+
+	if metrics.Spooled != 3 || metrics.Replayed != 3 || metrics.Published != 1 || metrics.PendingMessages != 0 || metrics.PendingBytes != 0 {
+		t.Fatalf("unexpected metrics %+v", metrics)
+	}
+}
+
+func TestReplaySetsAsideTheMessagesThatAreNotRetryable(t *testing.T) {
+	errInvalid := errors.New("invalid")
+
+	publisher := &testPublisher{err: errors.New("unreachable")}
+
+	directory := t.TempDir()
+
+	spool := newTestSpool(t, directory, publisher, &SpoolOptions{
+		MaxAttempts: 1,
+		RetryableFunc: func(err error) bool {
+			return !errors.Is(err, errInvalid)
+		},
+	})
+
+	if err := spool.Publish(context.Background(), &testMessage{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	publisher.setErr(errInvalid)
+
+	if err := spool.replay(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(spool.path(1, failedRecordExtension)); err != nil {
+		t.Fatal(err)
+	}
+
+	if metrics := spool.Metrics(); metrics.Failed != 1 || metrics.PendingMessages != 0 {
+		t.Fatalf("unexpected metrics %+v", metrics)
+	}
+}
+
+func TestNewSpoolDoesNotReuseTheSequenceNumbersOfTheOtherRecords(t *testing.T) {
+	directory := t.TempDir()
+
+	data, err := json.Marshal(&record{Discriminator: "Test", Payload: []byte(`{"ID":1}`)})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range map[string][]byte{
+		"00000000000000000001.msg":    data,
+		"00000000000000000002.failed": data,
+		"00000000000000000003.tmp":    nil,
+	} {
+		if err := os.WriteFile(filepath.Join(directory, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	spool := newTestSpool(t, directory, &testPublisher{}, nil)
+
+	if spool.sequenceNumber != 4 {
+		t.Fatalf("sequence number is %d, expected 4", spool.sequenceNumber)
+	}
+
+	if metrics := spool.Metrics(); metrics.PendingMessages != 1 || metrics.PendingBytes != int64(len(data)) {
+		t.Fatalf("unexpected metrics %+v", metrics)
+	}
+}
+
+func TestPublishRejectsTheMessagesThatDoNotFit(t *testing.T) {
+	publisher := &testPublisher{err: errors.New("unreachable")}
+
+	spool := newTestSpool(t, t.TempDir(), publisher, &SpoolOptions{MaxAttempts: 1, MaxBytes: 1})
+
+	if err := spool.Publish(context.Background(), &testMessage{ID: 1}); !errors.Is(err, ErrSpoolFull) {
+		t.Fatalf("error is %v, expected %v", err, ErrSpoolFull)
+	}
+
+	if metrics := spool.Metrics(); metrics.Rejected != 1 || metrics.PendingMessages != 0 {
+		t.Fatalf("unexpected metrics %+v", metrics)
+	}
+}
+
+func TestRunKeepsReplayingAfterAnError(t *testing.T) {
+	publisher := &testPublisher{err: errors.New("unreachable")}
+
+	directory := filepath.Join(t.TempDir(), "spool")
+
+	spool := newTestSpool(t, directory, publisher, &SpoolOptions{MaxAttempts: 1, Interval: 10 * time.Millisecond})
+
+	if err := spool.Publish(context.Background(), &testMessage{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The journal cannot be read while the directory is moved away.
+	if err := os.Rename(directory, directory+".moved"); err != nil {
+		t.Fatal(err)
+	}
+
+	publisher.setErr(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- spool.Run(ctx)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+
+	if err := os.Rename(directory+".moved", directory); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for len(publisher.published()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("error is %v, expected %v", err, context.Canceled)
+	}
+
+	if ids := publisher.published(); !slices.Equal(ids, []int{1}) {
+		t.Fatalf("published messages are %v, expected [1]", ids)
+	}
+}
@@ -0,0 +1,130 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidMessage = errors.New("invalid message")
+)
+
+// Validator is optionally implemented by messages that can check their content. The publishers reject the messages
+// that are not valid, and the subscribers dead letter them before they are dispatched.
+type Validator interface {
+	Validate() error
+}
+
+// Validate returns the error of message if it implements Validator, or nil.
+func Validate(message any) error {
+	validator, ok := message.(Validator)
+
+	if !ok {
+		return nil
+	}
+
+	return validator.Validate()
+}
+
+type FieldError struct {
+	// Path of the field in the JSON of the message, e.g. "Data.Contacts[0].ContactType".
+	Field   string `json:"Field"`
+	Message string `json:"Message"`
+}
+
+func (fieldErr *FieldError) Error() string {
+	return fmt.Sprintf("%s %s", fieldErr.Field, fieldErr.Message)
+}
+
+// ValidationError contains all the fields of a message that are not valid. It matches ErrInvalidMessage with errors.Is.
+type ValidationError struct {
+	Errs []*FieldError
+}
+
+func (validationErr *ValidationError) Error() string {
+	errMsgs := make([]string, 0, len(validationErr.Errs))
+
+	for _, err := range validationErr.Errs {
+		errMsgs = append(errMsgs, err.Error())
+	}
+
+	errMsg := strings.Join(errMsgs, "\n")
+
+	return errMsg
+}
+
+func (validationErr *ValidationError) Is(target error) bool {
+	return target == ErrInvalidMessage
+}
+
+// Checker collects the field errors of a message. The checkers returned by Nested share the errors of their parent.
+type Checker struct {
+	prefix string
+	errs   *[]*FieldError
+}
+
+func NewChecker() *Checker {
+	return &Checker{
+		errs: &[]*FieldError{},
+	}
+}
+
+// Nested returns a checker of the fields of field, e.g. "Data" or "Contacts[0]".
+func (checker *Checker) Nested(field string) *Checker {
+	return &Checker{
+		prefix: checker.path(field),
+		errs:   checker.errs,
+	}
+}
+
+func (checker *Checker) Add(field string, message string) {
+	*checker.errs = append(*checker.errs, &FieldError{
+		Field:   checker.path(field),
+		Message: message,
+	})
+}
+
+// Check adds an error with message if ok is false, and returns ok.
+func (checker *Checker) Check(ok bool, field string, message string) bool {
+	if !ok {
+		checker.Add(field, message)
+	}
+
+	return ok
+}
+
+// Required checks that value is not empty or blank.
+func (checker *Checker) Required(field string, value string) bool {
+	return checker.Check(strings.TrimSpace(value) != "", field, "is required")
+}
+
+// Valid checks the named enum types and flag sets of the messages.
+func (checker *Checker) Valid(field string, value interface {
+	Valid() bool
+}) bool {
+	return checker.Check(value.Valid(), field, fmt.Sprintf("is invalid: %v", value))
+}
+
+// Err returns a *ValidationError with the collected errors, or nil.
+func (checker *Checker) Err() error {
+	if len(*checker.errs) == 0 {
+		return nil
+	}
+
+	return &ValidationError{
+		Errs: *checker.errs,
+	}
+}
+
+func (checker *Checker) path(field string) string {
+	if checker.prefix == "" {
+		return field
+	}
+
+	if strings.HasPrefix(field, "[") {
+		return checker.prefix + field
+	}
+
+	return checker.prefix + "." + field
+}
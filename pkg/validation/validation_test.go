@@ -0,0 +1,105 @@
+package validation
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+type testEnum int
+
+func (value testEnum) Valid() bool {
+	return value >= 0 && value <= 2
+}
+
+type testMessage struct {
+	err error
+}
+
+func (message *testMessage) Validate() error {
+	return message.err
+}
+
+func fieldsOf(t *testing.T, err error) []string {
+	t.Helper()
+
+	if err == nil {
+		return nil
+	}
+
+	var validationErr *ValidationError
+
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("error is %v, expected a *ValidationError", err)
+	}
+
+	fields := make([]string, 0, len(validationErr.Errs))
+
+	for _, fieldErr := range validationErr.Errs {
+		fields = append(fields, fieldErr.Field)
+	}
+
+	return fields
+}
+
+func TestChecker(t *testing.T) {
+	tests := []struct {
+		name     string
+		check    func(checker *Checker)
+		expected []string
+	}{
+		{"no errors", func(checker *Checker) { checker.Required("Code", "1"); checker.Valid("Scope", testEnum(2)) }, nil},
+		{"empty", func(checker *Checker) { checker.Required("Code", "") }, []string{"Code"}},
+		{"blank", func(checker *Checker) { checker.Required("Code", " \t") }, []string{"Code"}},
+		{"enum out of range", func(checker *Checker) { checker.Valid("Scope", testEnum(3)) }, []string{"Scope"}},
+		{"negative enum", func(checker *Checker) { checker.Valid("Scope", testEnum(-1)) }, []string{"Scope"}},
+		{"check", func(checker *Checker) { checker.Check(false, "Data", "is required") }, []string{"Data"}},
+		{"nested", func(checker *Checker) { checker.Nested("Data").Required("Code", "") }, []string{"Data.Code"}},
+		{"nested index", func(checker *Checker) { checker.Nested("Data").Nested("Contacts[0]").Valid("ContactType", testEnum(9)) }, []string{"Data.Contacts[0].ContactType"}},
+		{"index", func(checker *Checker) { checker.Nested("Contacts").Add("[1]", "is required") }, []string{"Contacts[1]"}},
+		{"all errors", func(checker *Checker) { checker.Required("Code", ""); checker.Nested("Data").Required("Name", "") }, []string{"Code", "Data.Name"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checker := NewChecker()
+
+			test.check(checker)
+
+			if fields := fieldsOf(t, checker.Err()); !slices.Equal(fields, test.expected) {
+				t.Fatalf("fields are %v, expected %v", fields, test.expected)
+			}
+		})
+	}
+}
+
+func TestValidationErrorIsInvalidMessage(t *testing.T) {
+	checker := NewChecker()
+
+	checker.Required("Code", "")
+	checker.Valid("Scope", testEnum(3))
+
+	err := checker.Err()
+
+	if !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("error is %v, expected %v", err, ErrInvalidMessage)
+	}
+
+	if expected := "Code is required\nScope is invalid: 3"; err.Error() != expected {
+		t.Fatalf("error is %q, expected %q", err.Error(), expected)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(struct{}{}); err != nil {
+		t.Fatalf("error of a message without Validate is %v, expected nil", err)
+	}
+
+	if err := Validate(&testMessage{}); err != nil {
+		t.Fatalf("error is %v, expected nil", err)
+	}
+
+	if err := Validate(&testMessage{err: ErrInvalidMessage}); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("error is %v, expected %v", err, ErrInvalidMessage)
+	}
+}